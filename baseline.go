@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"ghactionscheck/pkg/report"
+)
+
+type baselineCmd struct {
+	Paths  []string `arg:"" optional:"" name:"path" default:"." help:"Workflow files, glob patterns or repository directories to check"`
+	Output string   `name:"output" short:"o" type:"path" default:".ghactionscheck-baseline.json" help:"Baseline file to write"`
+	checkerFlags
+}
+
+// Run checks the files and records their findings, so that check
+// --baseline only reports the findings added since. The checks are selected
+// by the same flags as for check, which should match those of later runs.
+func (cmd *baselineCmd) Run() error {
+	files, err := expandPaths(cmd.Paths)
+	if err != nil {
+		return fmt.Errorf("finding workflow files: %v", err)
+	}
+	check := &checkCmd{Paths: cmd.Paths, checkFlags: checkFlags{checkerFlags: cmd.checkerFlags}}
+	results, err := check.check(files)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(cmd.Output)
+	if err != nil {
+		return fmt.Errorf("writing baseline: %v", err)
+	}
+	if err := report.WriteBaseline(out, results); err != nil {
+		out.Close()
+		return fmt.Errorf("writing baseline: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("writing baseline: %v", err)
+	}
+	fmt.Printf("Recorded %d findings in %s\n", len(results), cmd.Output)
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// diffOp is a line of a diff: ' ' for an unchanged line, '-' for a removed
+// line and '+' for an added line.
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// unifiedDiff returns the changes from a to b in unified diff format, or an
+// empty string when they are equal.
+func unifiedDiff(name string, a, b []byte) string {
+	ops := diffLines(splitLines(string(a)), splitLines(string(b)))
+
+	var out strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change and extend the hunk while changes are no more
+		// than twice the context apart.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for i := first; i < len(ops) && i-last <= 2*diffContext; i++ {
+			if ops[i].kind != ' ' {
+				last = i
+			}
+		}
+		from := max(first-diffContext, start)
+		to := min(last+diffContext+1, len(ops))
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", name, name)
+		}
+		aStart, bStart := 1, 1
+		for _, op := range ops[:from] {
+			if op.kind != '+' {
+				aStart++
+			}
+			if op.kind != '-' {
+				bStart++
+			}
+		}
+		aCount, bCount := 0, 0
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, op := range ops[from:to] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.text)
+			out.WriteByte('\n')
+		}
+		start = to
+	}
+	return out.String()
+}
+
+// hunkRange formats the line range of a hunk. An empty range refers to the
+// line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns the edit script turning a into b, based on their longest
+// common subsequence. Workflow files are small enough for the quadratic
+// table.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
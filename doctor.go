@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"ghactionscheck/pkg/checks"
+)
+
+type doctorCmd struct {
+	Path string `arg:"" optional:"" name:"path" default:"." help:"Directory whose checks config is diagnosed"`
+}
+
+// diagnosis is the outcome of one of the checks of doctor, with how to fix
+// it when it isn't ok.
+type diagnosis struct {
+	status  string
+	message string
+	fix     string
+}
+
+// The statuses of diagnoses.
+const (
+	diagnosisOK      = "ok"
+	diagnosisWarning = "warn"
+	diagnosisFailure = "fail"
+)
+
+// scopeRequirements lists the features needing a classic token scope, with
+// the scopes that grant it.
+var scopeRequirements = []struct {
+	feature string
+	scopes  []string
+}{
+	{"remote and org on private repositories", []string{"repo"}},
+	{"--pr review comments", []string{"repo", "public_repo"}},
+	{"--upload to code scanning", []string{"repo", "public_repo", "security_events"}},
+}
+
+// Run diagnoses the environment: the checks config found for the path, the
+// GitHub token and its scopes, the connection to the API and its rate limit,
+// and the cache directory. Each problem is printed with how to fix it, and
+// the exit status is 1 when something is broken.
+func (cmd *doctorCmd) Run() error {
+	var diagnoses []diagnosis
+	diagnoses = append(diagnoses, diagnoseConfig(cmd.Path))
+	diagnoses = append(diagnoses, diagnoseGitHub(cli.GitHubToken)...)
+	diagnoses = append(diagnoses, diagnoseCacheDir())
+
+	failures := 0
+	for _, d := range diagnoses {
+		fmt.Printf("%-6s %s\n", "["+d.status+"]", d.message)
+		if d.fix != "" {
+			fmt.Printf("%-6s %s\n", "", d.fix)
+		}
+		if d.status == diagnosisFailure {
+			failures++
+		}
+	}
+	switch {
+	case failures == 1:
+		return fmt.Errorf("1 problem found")
+	case failures > 1:
+		return fmt.Errorf("%d problems found", failures)
+	}
+	return nil
+}
+
+// diagnoseConfig reports the checks config used for path, and whether it
+// loads.
+func diagnoseConfig(path string) diagnosis {
+	configPath := cli.Config
+	if configPath == "" {
+		configPath = checks.FindConfigFile(path)
+	}
+	if configPath == "" {
+		return diagnosis{
+			status:  diagnosisOK,
+			message: fmt.Sprintf("No checks config found for %s, the built-in checks are used", checks.FindRepoRoot(path)),
+			fix:     "Run ghactionscheck init to write a config listing every check",
+		}
+	}
+	config, err := checks.LoadConfig(configPath)
+	if err != nil {
+		return diagnosis{
+			status:  diagnosisFailure,
+			message: fmt.Sprintf("Checks config %s doesn't load: %v", configPath, err),
+			fix:     "Fix the config, or compare it with the output of ghactionscheck init",
+		}
+	}
+	enabled := 0
+	for _, check := range config.Checks {
+		if check.Enabled == nil || *check.Enabled {
+			enabled++
+		}
+	}
+	return diagnosis{
+		status:  diagnosisOK,
+		message: fmt.Sprintf("Checks config %s loads, with %d of %d checks enabled", configPath, enabled, len(config.Checks)),
+	}
+}
+
+// diagnoseGitHub reports whether a token is set, whether the API can be
+// reached with it, its rate limit and the scopes of the token.
+func diagnoseGitHub(token string) []diagnosis {
+	var diagnoses []diagnosis
+	if token == "" {
+		diagnoses = append(diagnoses, diagnosis{
+			status:  diagnosisWarning,
+			message: "No GitHub token set, so API requests are anonymous and limited to 60 an hour",
+			fix:     "Set $GITHUB_TOKEN or $GH_TOKEN, or pass --github-token, for --online, remote and org",
+		})
+	} else {
+		diagnoses = append(diagnoses, diagnosis{status: diagnosisOK, message: "GitHub token set"})
+	}
+
+	github := githubClient()
+	status, err := github.Status()
+	if err != nil {
+		fix := "Check the network connection, $HTTPS_PROXY when behind a proxy, and --github-api-url for GitHub Enterprise Server"
+		if errors.Is(err, checks.ErrBadToken) {
+			fix = "The token is invalid or expired; create a new one"
+		}
+		return append(diagnoses, diagnosis{
+			status:  diagnosisFailure,
+			message: fmt.Sprintf("Can't query the GitHub API at %s: %v", github.APIURL(), err),
+			fix:     fix,
+		})
+	}
+
+	limit := status.RateLimit
+	d := diagnosis{
+		status: diagnosisOK,
+		message: fmt.Sprintf("GitHub API at %s reachable, %d of %d requests left until %s",
+			github.APIURL(), limit.Remaining, limit.Limit, limit.Reset.Local().Format(time.TimeOnly)),
+	}
+	switch {
+	case limit.Limit == 0:
+		d.message = fmt.Sprintf("GitHub API at %s reachable, without rate limit", github.APIURL())
+	case limit.Remaining == 0:
+		d.status = diagnosisFailure
+		d.fix = "Wait for the rate limit to reset, or use a token with a higher limit"
+	case limit.Remaining < limit.Limit/10:
+		d.status = diagnosisWarning
+		d.fix = "Few requests are left; a large scan may exceed the rate limit"
+	}
+	diagnoses = append(diagnoses, d)
+
+	if token == "" {
+		return diagnoses
+	}
+	if !status.ScopesReported {
+		return append(diagnoses, diagnosis{
+			status:  diagnosisOK,
+			message: "Token scopes aren't reported, as for fine-grained tokens and the GITHUB_TOKEN of Actions",
+			fix:     "Grant Contents: read, plus Pull requests: write for --pr and Code scanning alerts: write for --upload",
+		})
+	}
+	scopes := strings.Join(status.Scopes, ", ")
+	if scopes == "" {
+		scopes = "none"
+	}
+	diagnoses = append(diagnoses, diagnosis{status: diagnosisOK, message: "Token scopes: " + scopes})
+	for _, requirement := range scopeRequirements {
+		if !slices.ContainsFunc(requirement.scopes, func(scope string) bool { return slices.Contains(status.Scopes, scope) }) {
+			diagnoses = append(diagnoses, diagnosis{
+				status:  diagnosisWarning,
+				message: fmt.Sprintf("The token can't be used for %s", requirement.feature),
+				fix:     fmt.Sprintf("Add the %s scope to the token", strings.Join(requirement.scopes, " or ")),
+			})
+		}
+	}
+	return diagnoses
+}
+
+// diagnoseCacheDir reports whether files can be written to the cache
+// directory, creating it if needed.
+func diagnoseCacheDir() diagnosis {
+	dir, err := checks.CacheDir()
+	if err == nil {
+		err = os.MkdirAll(dir, 0o700)
+	}
+	if err == nil {
+		var f *os.File
+		if f, err = os.CreateTemp(dir, "doctor-*"); err == nil {
+			f.Close()
+			err = os.Remove(f.Name())
+		}
+	}
+	if err != nil {
+		return diagnosis{
+			status:  diagnosisFailure,
+			message: fmt.Sprintf("Cache directory isn't writable: %v", err),
+			fix:     "Set $XDG_CACHE_HOME to a writable directory",
+		}
+	}
+	return diagnosis{status: diagnosisOK, message: fmt.Sprintf("Cache directory %s is writable", dir)}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"ghactionscheck/pkg/checks"
+)
+
+type explainCmd struct {
+	ID   string `arg:"" name:"check" help:"Id of the check to explain"`
+	Path string `name:"path" default:"." help:"Directory whose checks config is used"`
+}
+
+// Run prints the explanation of a check: the risk of its findings, examples
+// of YAML it reports and of the fixed YAML, how to resolve its findings and
+// links to documentation. A check of the config without an explanation,
+// such as a built-in check copied from an older version of checks.yaml,
+// uses the built-in one.
+func (cmd *explainCmd) Run() error {
+	config, err := loadConfig(cmd.Path)
+	if err != nil {
+		return fmt.Errorf("loading checks config: %v", err)
+	}
+	defaults, err := checks.LoadConfig("")
+	if err != nil {
+		return err
+	}
+	id := checks.CanonicalID(cmd.ID)
+	check := configuredCheck(config, id)
+	builtin := configuredCheck(defaults, id)
+	if check == nil {
+		check = builtin
+	}
+	if check == nil {
+		return fmt.Errorf("unknown check %s, see ghactionscheck rules for the available checks", cmd.ID)
+	}
+	doc := check.Doc
+	if doc == nil && builtin != nil {
+		doc = builtin.Doc
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s)\n%s\n", check.ID, check.Severity, check.Description)
+	if doc != nil {
+		fmt.Fprintf(&b, "\nRisk:\n%s\n", indent(wrap(doc.Risk, 76)))
+		if doc.Bad != "" {
+			fmt.Fprintf(&b, "\nReported:\n%s\n", indent(doc.Bad))
+		}
+		if doc.Good != "" {
+			fmt.Fprintf(&b, "\nFixed:\n%s\n", indent(doc.Good))
+		}
+	}
+	fmt.Fprintf(&b, "\nHow to resolve:\n%s\n", indent(wrap(check.Detail, 76)))
+	var links []string
+	if check.URL != "" {
+		links = append(links, check.URL)
+	}
+	if doc != nil {
+		for _, link := range doc.Links {
+			if !slices.Contains(links, link) {
+				links = append(links, link)
+			}
+		}
+	}
+	if len(links) > 0 {
+		fmt.Fprintf(&b, "\nLinks:\n%s\n", indent(strings.Join(links, "\n")))
+	}
+	_, err = os.Stdout.WriteString(b.String())
+	return err
+}
+
+// configuredCheck returns the check with id in config, enabled or not, or
+// nil.
+func configuredCheck(config *checks.Config, id string) *checks.Check {
+	for i := range config.Checks {
+		if config.Checks[i].ID == id {
+			return &config.Checks[i]
+		}
+	}
+	return nil
+}
+
+// wrap breaks text into lines of at most width characters where possible.
+func wrap(text string, width int) string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	return strings.Join(append(lines, line), "\n")
+}
+
+// indent indents the lines of text by two spaces.
+func indent(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "  " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
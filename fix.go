@@ -0,0 +1,467 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"ghactionscheck/pkg/checks"
+	"ghactionscheck/pkg/report"
+	"ghactionscheck/pkg/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+type fixCmd struct {
+	Path   string `arg:"" optional:"" name:"path" default:"." help:"Path to a GitHub Actions workflow file or a repository directory"`
+	DryRun bool   `name:"dry-run" help:"Print the changes as a diff instead of writing them"`
+}
+
+func (cmd *fixCmd) Run() error {
+	checksConfig, err := loadConfig(cmd.Path)
+	if err != nil {
+		return fmt.Errorf("loading checks config: %v", err)
+	}
+
+	files, err := findWorkflowFiles(cmd.Path)
+	if err != nil {
+		return fmt.Errorf("finding workflow files: %v", err)
+	}
+
+	c, err := checks.New(checksConfig, checks.WithGitHubHost(githubHost()))
+	if err != nil {
+		return err
+	}
+	f := &fixer{
+		config:  checksConfig,
+		checker: c,
+		github:  githubClient(),
+		dryRun:  cmd.DryRun,
+	}
+	for _, file := range files {
+		if err := f.fixFile(file); err != nil {
+			return fmt.Errorf("fixing %s: %v", file, err)
+		}
+	}
+	return nil
+}
+
+// fixer rewrites workflow files to resolve findings. It edits the original
+// text at the positions of the affected nodes, so formatting and comments
+// are preserved.
+type fixer struct {
+	config *checks.Config
+	// checker finds the findings to fix, so disabled checks and suppressed
+	// findings are left alone. It runs offline checks only.
+	checker *checks.Checker
+	github  *checks.GitHubClient
+	dryRun  bool
+	// noPins leaves out pinning refs, which queries the GitHub API, for the
+	// code actions of the language server to stay fast.
+	noPins bool
+}
+
+// edit replaces the bytes from start to end of a file with text, to resolve
+// a finding. The note, if any, describes the fix and is printed when the
+// edit is written; a fix can include further edits without a note.
+type edit struct {
+	start, end int
+	text       string
+	note       string
+	finding    findingKey
+}
+
+// findings indexes the results of a file by check id and position.
+type findings map[findingKey]bool
+
+type findingKey struct {
+	checkID      string
+	line, column int
+}
+
+// has reports whether check id reported a finding at node.
+func (f findings) has(id string, node *yaml.Node) bool {
+	return node != nil && f[keyOf(id, node)]
+}
+
+func keyOf(id string, node *yaml.Node) findingKey {
+	return findingKey{id, node.Line, node.Column}
+}
+
+// fixes returns the edits fixing the findings of a workflow file whose
+// results are accepted by only, or all of them when only is nil.
+func (f *fixer) fixes(file string, data []byte, only func(report.Result) bool) ([]edit, error) {
+	w, err := workflow.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing YAML: %v", err)
+	}
+	results, err := f.checker.Check(file, data)
+	if err != nil {
+		return nil, err
+	}
+	flagged := make(findings)
+	for _, result := range results {
+		if only == nil || only(result) {
+			flagged[findingKey{result.CheckID, result.Line, result.Column}] = true
+		}
+	}
+
+	var edits []edit
+	if !f.noPins {
+		edits = append(edits, f.pinRefs(w, data, flagged)...)
+	}
+	edits = append(edits, f.addTimeouts(w, data, flagged)...)
+	edits = append(edits, f.addPermissions(w, data, flagged)...)
+	return edits, nil
+}
+
+func (f *fixer) fixFile(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	edits, err := f.fixes(file, data, nil)
+	if err != nil {
+		return err
+	}
+	if len(edits) == 0 {
+		return nil
+	}
+
+	fixed := applyEdits(data, edits)
+	if f.dryRun {
+		fmt.Print(unifiedDiff(file, data, fixed))
+		return nil
+	}
+
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+	for _, e := range edits {
+		if e.note != "" {
+			fmt.Printf("%s:%d: %s\n", file, lineOf(data, e.start), e.note)
+		}
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, fixed, info.Mode())
+}
+
+// pinRefs returns edits replacing the mutable refs of actions and reusable
+// workflows flagged by the action_ref and reusable_workflow_ref checks with
+// the commit hashes they currently point to, followed by a comment with the
+// version.
+func (f *fixer) pinRefs(w *workflow.Workflow, data []byte, flagged findings) []edit {
+	nodes := pinnableRefs(w, flagged)
+	var edits []edit
+	for _, node := range nodes.order {
+		repo, ref, _ := checks.ActionRepo(node.Value)
+		sha, err := f.github.CommitSHA(repo, ref)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not resolve %s: %v\n", node.Value, err)
+			continue
+		}
+		version := f.github.VersionOf(repo, ref, sha)
+
+		start, end, ok := scalarSpan(data, node)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Warning: could not locate %s at line %d\n", node.Value, node.Line)
+			continue
+		}
+		pinned := strings.TrimSuffix(node.Value, ref) + sha
+		finding := keyOf(nodes.checks[node], node)
+		edits = append(edits, edit{
+			start:   start,
+			end:     end,
+			text:    strings.Replace(string(data[start:end]), node.Value, pinned, 1),
+			note:    fmt.Sprintf("%s -> %s # %s", node.Value, pinned, version),
+			finding: finding,
+		})
+		if e, ok := commentEdit(data, node, version); ok {
+			e.finding = finding
+			edits = append(edits, e)
+		}
+	}
+	return edits
+}
+
+// refNodes are the uses nodes of a workflow whose refs can be pinned, in
+// order, with the check flagging each.
+type refNodes struct {
+	order  []*yaml.Node
+	checks map[*yaml.Node]string
+}
+
+// pinnableRefs returns the uses nodes of the actions and reusable workflows
+// flagged by the action_ref and reusable_workflow_ref checks whose refs can
+// be resolved to a commit hash.
+func pinnableRefs(w *workflow.Workflow, flagged findings) refNodes {
+	nodes := refNodes{checks: make(map[*yaml.Node]string)}
+	add := func(id string, uses *yaml.Node) {
+		// A step resolved from an alias is shared with its anchor, and
+		// pinned once.
+		if _, ok := nodes.checks[uses]; ok || !flagged.has(id, uses) {
+			return
+		}
+		_, ref, ok := checks.ActionRepo(uses.Value)
+		if !ok || checks.IsCommitHash(ref) || strings.Contains(uses.Value, "${{") {
+			return
+		}
+		nodes.checks[uses] = id
+		nodes.order = append(nodes.order, uses)
+	}
+	for _, jobName := range w.JobNames() {
+		job := w.Jobs[jobName]
+		_, uses := workflow.LookupKey(job.Node, "uses")
+		add("reusable_workflow_ref", uses)
+		for _, step := range job.Steps {
+			_, uses := workflow.LookupKey(step.Node, "uses")
+			add("action_ref", uses)
+		}
+	}
+	return nodes
+}
+
+// defaultTimeoutMinutes is the timeout added by addTimeouts unless the
+// timeout check sets the default_minutes option.
+const defaultTimeoutMinutes = 30
+
+// addTimeouts returns edits inserting timeout-minutes into the jobs flagged
+// by the timeout check, as the first key of the job.
+func (f *fixer) addTimeouts(w *workflow.Workflow, data []byte, flagged findings) []edit {
+	check := f.config.Check("timeout")
+	if check == nil {
+		return nil
+	}
+	minutes := check.IntOption("default_minutes", defaultTimeoutMinutes)
+
+	var edits []edit
+	for _, jobName := range w.JobNames() {
+		job := w.Jobs[jobName]
+		if !flagged.has("timeout", job.Key) {
+			continue
+		}
+		// Flow mappings and jobs on a single line have no line to insert
+		// the key on.
+		if job.Node.Kind != yaml.MappingNode || job.Node.Style&yaml.FlowStyle != 0 ||
+			len(job.Node.Content) == 0 || job.Node.Content[0].Line <= job.Key.Line {
+			fmt.Fprintf(os.Stderr, "Warning: could not add timeout-minutes to job %s at line %d\n", jobName, job.Key.Line)
+			continue
+		}
+
+		offset := lineStart(data, job.Key.Line+1)
+		indent := strings.Repeat(" ", job.Node.Content[0].Column-1)
+		edits = append(edits, edit{
+			start:   offset,
+			end:     offset,
+			text:    fmt.Sprintf("%stimeout-minutes: %d\n", indent, minutes),
+			note:    fmt.Sprintf("add timeout-minutes: %d to job %s", minutes, jobName),
+			finding: keyOf("timeout", job.Key),
+		})
+	}
+	return edits
+}
+
+// defaultPermissions are the permissions added by addPermissions unless the
+// permissions check sets the default_permissions option.
+var defaultPermissions = map[string]interface{}{"contents": "read"}
+
+// addPermissions returns edits declaring permissions where the permissions
+// checks fired. With the permissions check's fix_level option set to
+// "workflow" (the default), a single block is added above jobs; with "job",
+// each flagged job gets its own block.
+func (f *fixer) addPermissions(w *workflow.Workflow, data []byte, flagged findings) []edit {
+	check := f.config.Check("permissions")
+	if check == nil {
+		return nil
+	}
+	permissions := check.Options["default_permissions"]
+	if permissions == nil {
+		permissions = defaultPermissions
+	}
+
+	var jobs []string
+	for _, jobName := range w.JobNames() {
+		if flagged.has("permissions", w.Jobs[jobName].Key) {
+			jobs = append(jobs, jobName)
+		}
+	}
+
+	switch level := check.StringOption("fix_level", "workflow"); level {
+	case "workflow":
+		finding := keyOf("workflow_permissions", w.Node)
+		if !flagged[finding] {
+			if len(jobs) == 0 {
+				return nil
+			}
+			finding = keyOf("permissions", w.Jobs[jobs[0]].Key)
+		}
+		jobsKey, jobsValue := workflow.LookupKey(w.Node, "jobs")
+		if jobsKey == nil {
+			return nil
+		}
+
+		// Keep the comments above jobs attached to it.
+		line := jobsKey.Line
+		for line > 1 && strings.HasPrefix(strings.TrimSpace(lineText(data, line-1)), "#") {
+			line--
+		}
+		text := permissionsBlock(strings.Repeat(" ", jobsKey.Column-1), indentUnit(jobsKey, jobsValue), permissions)
+		if line > 1 && strings.TrimSpace(lineText(data, line-1)) == "" {
+			text += "\n"
+		}
+		offset := lineStart(data, line)
+		return []edit{{start: offset, end: offset, text: text, note: "add workflow permissions", finding: finding}}
+
+	case "job":
+		var edits []edit
+		for _, jobName := range jobs {
+			job := w.Jobs[jobName]
+			if job.Node.Kind != yaml.MappingNode || job.Node.Style&yaml.FlowStyle != 0 ||
+				len(job.Node.Content) == 0 || job.Node.Content[0].Line <= job.Key.Line {
+				fmt.Fprintf(os.Stderr, "Warning: could not add permissions to job %s at line %d\n", jobName, job.Key.Line)
+				continue
+			}
+			offset := lineStart(data, job.Key.Line+1)
+			indent := strings.Repeat(" ", job.Node.Content[0].Column-1)
+			edits = append(edits, edit{
+				start:   offset,
+				end:     offset,
+				text:    permissionsBlock(indent, indentUnit(job.Key, job.Node), permissions),
+				note:    "add permissions to job " + jobName,
+				finding: keyOf("permissions", job.Key),
+			})
+		}
+		return edits
+
+	default:
+		fmt.Fprintf(os.Stderr, "Warning: unknown fix_level %q for the permissions check\n", level)
+		return nil
+	}
+}
+
+// permissionsBlock renders a permissions key at indent, with scopes nested
+// by unit.
+func permissionsBlock(indent, unit string, permissions interface{}) string {
+	scopes, ok := permissions.(map[string]interface{})
+	if !ok {
+		return fmt.Sprintf("%spermissions: %v\n", indent, permissions)
+	}
+	if len(scopes) == 0 {
+		return indent + "permissions: {}\n"
+	}
+
+	names := make([]string, 0, len(scopes))
+	for name := range scopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	text := indent + "permissions:\n"
+	for _, name := range names {
+		text += fmt.Sprintf("%s%s%s: %v\n", indent, unit, name, scopes[name])
+	}
+	return text
+}
+
+// indentUnit returns the indentation the file uses for the mapping value of
+// key, defaulting to two spaces.
+func indentUnit(key, value *yaml.Node) string {
+	if value != nil && value.Kind == yaml.MappingNode && len(value.Content) > 0 {
+		if width := value.Content[0].Column - key.Column; width > 0 && value.Content[0].Line > key.Line {
+			return strings.Repeat(" ", width)
+		}
+	}
+	return "  "
+}
+
+// lineStart returns the byte offset of the start of a 1-based line.
+func lineStart(data []byte, line int) int {
+	offset := 0
+	for i := 1; i < line; i++ {
+		next := strings.IndexByte(string(data[offset:]), '\n')
+		if next < 0 {
+			return len(data)
+		}
+		offset += next + 1
+	}
+	return offset
+}
+
+// lineOf returns the 1-based line containing the byte at offset.
+func lineOf(data []byte, offset int) int {
+	return strings.Count(string(data[:offset]), "\n") + 1
+}
+
+// lineText returns the text of a 1-based line, without its newline.
+func lineText(data []byte, line int) string {
+	start := lineStart(data, line)
+	return string(data[start:lineEnd(data, start)])
+}
+
+// lineEnd returns the byte offset of the newline ending the line starting at
+// offset, or the end of data.
+func lineEnd(data []byte, offset int) int {
+	if end := strings.IndexByte(string(data[offset:]), '\n'); end >= 0 {
+		return offset + end
+	}
+	return len(data)
+}
+
+// scalarSpan returns the byte offsets of a single-line scalar node in data,
+// including its quotes.
+func scalarSpan(data []byte, node *yaml.Node) (start, end int, ok bool) {
+	line := lineStart(data, node.Line)
+	text := string(data[line:lineEnd(data, line)])
+
+	// Columns count characters, not bytes.
+	column := len(string([]rune(text)[:min(node.Column-1, len([]rune(text)))]))
+	index := strings.Index(text[column:], node.Value)
+	if index < 0 {
+		return 0, 0, false
+	}
+	start = line + column
+	end = line + column + index + len(node.Value)
+	if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 && end < line+len(text) {
+		end++
+	}
+	return start, end, true
+}
+
+// versionCommentPattern matches comments holding a version, as written by
+// pinRefs.
+var versionCommentPattern = regexp.MustCompile(`^#\s*v?\d+(\.\d+)*\s*$`)
+
+// commentEdit returns an edit setting the comment at the end of node's line
+// to comment. An existing version comment is replaced, while other comments
+// are kept and no edit is returned.
+func commentEdit(data []byte, node *yaml.Node, comment string) (edit, bool) {
+	start := lineStart(data, node.Line)
+	end := lineEnd(data, start)
+	text := string(data[start:end])
+	if node.LineComment != "" {
+		index := strings.LastIndex(text, node.LineComment)
+		if index < 0 || !versionCommentPattern.MatchString(node.LineComment) {
+			return edit{}, false
+		}
+		return edit{start: start + index, end: end, text: "# " + comment}, true
+	}
+	trimmed := strings.TrimRight(text, " \t\r")
+	return edit{start: start + len(trimmed), end: start + len(trimmed), text: " # " + comment}, true
+}
+
+// applyEdits applies non-overlapping edits to data.
+func applyEdits(data []byte, edits []edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	out := string(data)
+	for _, e := range edits {
+		out = out[:e.start] + e.text + out[e.end:]
+	}
+	return []byte(out)
+}
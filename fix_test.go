@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ghactionscheck/pkg/checks"
+	"ghactionscheck/pkg/workflow"
+)
+
+// testSHA is the commit every ref resolves to in newTestFixer.
+const testSHA = "0123456789abcdef0123456789abcdef01234567"
+
+// newTestFixer returns a fixer with the default config, whose GitHub client
+// resolves every ref to testSHA, tagged v4.1.0.
+func newTestFixer(t *testing.T) *fixer {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body interface{} = map[string]string{}
+		switch {
+		case strings.Contains(r.URL.Path, "/commits/"):
+			body = map[string]string{"sha": testSHA}
+		case strings.HasSuffix(r.URL.Path, "/tags"):
+			body = []map[string]interface{}{{"name": "v4.1.0", "commit": map[string]string{"sha": testSHA}}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+
+	config, err := checks.LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := checks.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	github := checks.NewGitHubClient("")
+	github.SetAPIURL(server.URL)
+	return &fixer{config: config, checker: c, github: github}
+}
+
+// fix returns data with the fixes of the findings accepted by only applied.
+func fix(t *testing.T, f *fixer, data string, only func(id string) bool) string {
+	t.Helper()
+	edits, err := f.fixes("ci.yml", []byte(data), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []edit
+	for _, e := range edits {
+		if only(e.finding.checkID) {
+			kept = append(kept, e)
+		}
+	}
+	return string(applyEdits([]byte(data), kept))
+}
+
+func TestFixPinsRefs(t *testing.T) {
+	tests := []struct {
+		name       string
+		data, want string
+	}{
+		{
+			name: "step",
+			data: "on: push\njobs:\n  build:\n    steps:\n      - uses: actions/checkout@v4\n",
+			want: "on: push\njobs:\n  build:\n    steps:\n      - uses: actions/checkout@" + testSHA + " # v4.1.0\n",
+		},
+		{
+			name: "version comment replaced",
+			data: "on: push\njobs:\n  build:\n    steps:\n      - uses: actions/checkout@v4 # v4\n",
+			want: "on: push\njobs:\n  build:\n    steps:\n      - uses: actions/checkout@" + testSHA + " # v4.1.0\n",
+		},
+		{
+			name: "other comment kept",
+			data: "on: push\njobs:\n  build:\n    steps:\n      - uses: actions/checkout@v4 # keep\n",
+			want: "on: push\njobs:\n  build:\n    steps:\n      - uses: actions/checkout@" + testSHA + " # keep\n",
+		},
+		{
+			name: "already pinned",
+			data: "on: push\njobs:\n  build:\n    steps:\n      - uses: actions/checkout@" + testSHA + "\n",
+			want: "on: push\njobs:\n  build:\n    steps:\n      - uses: actions/checkout@" + testSHA + "\n",
+		},
+		{
+			// The aliased step shares the anchored node, which must be
+			// edited once.
+			name: "aliased step",
+			data: "on: push\njobs:\n  build:\n    steps:\n      - &checkout\n        uses: actions/checkout@v4\n  test:\n    steps:\n      - *checkout\n",
+			want: "on: push\njobs:\n  build:\n    steps:\n      - &checkout\n        uses: actions/checkout@" + testSHA + " # v4.1.0\n  test:\n    steps:\n      - *checkout\n",
+		},
+	}
+	f := newTestFixer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fix(t, f, tt.data, func(id string) bool { return id == "action_ref" })
+			if got != tt.want {
+				t.Errorf("fixed:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFixAddsTimeouts(t *testing.T) {
+	data := "on: push\njobs:\n  build:\n    runs-on: ubuntu-22.04\n    steps:\n      - run: make\n  test:\n    timeout-minutes: 5\n    runs-on: ubuntu-22.04\n"
+	want := "on: push\njobs:\n  build:\n    timeout-minutes: 30\n    runs-on: ubuntu-22.04\n    steps:\n      - run: make\n  test:\n    timeout-minutes: 5\n    runs-on: ubuntu-22.04\n"
+	got := fix(t, newTestFixer(t), data, func(id string) bool { return id == "timeout" })
+	if got != want {
+		t.Errorf("fixed:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPinnableRefsSharedNode(t *testing.T) {
+	f := newTestFixer(t)
+	data := []byte("on: push\njobs:\n  build:\n    steps:\n      - &checkout\n        uses: actions/checkout@v4\n  test:\n    steps:\n      - *checkout\n      - uses: actions/setup-go@v5\n")
+	w, err := workflow.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := f.checker.Check("ci.yml", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flagged := make(findings)
+	for _, result := range results {
+		flagged[findingKey{result.CheckID, result.Line, result.Column}] = true
+	}
+
+	var uses []string
+	for _, node := range pinnableRefs(w, flagged).order {
+		uses = append(uses, node.Value)
+	}
+	if want := "actions/checkout@v4 actions/setup-go@v5"; strings.Join(uses, " ") != want {
+		t.Errorf("pinnable refs = %q, want %q", uses, want)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"ghactionscheck/pkg/report"
+)
+
+type actionCmd struct{}
+
+// actionInput returns the value of an input of the action, which the runner
+// passes as $INPUT_<NAME>, upper-cased with its hyphens kept.
+func actionInput(name string) string {
+	return strings.TrimSpace(os.Getenv("INPUT_" + strings.ToUpper(strings.ReplaceAll(name, " ", "_"))))
+}
+
+// Run checks the workflows as the ghactionscheck action, taking the flags
+// from the inputs of the action declared in action.yml. The report is
+// written to the log in the format input, the findings are annotated on
+// their lines and appended to the job summary, and their numbers are set as
+// the outputs of the step.
+func (cmd *actionCmd) Run() error {
+	paths := strings.Fields(actionInput("paths"))
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	check := &checkCmd{Paths: paths, checkFlags: checkFlags{
+		Format:      cmp.Or(actionInput("format"), "table"),
+		FailOn:      cmp.Or(actionInput("fail-on"), report.SeverityWarning),
+		MinSeverity: cmp.Or(actionInput("min-severity"), report.SeverityNotice),
+	}}
+	if !slices.Contains([]string{"error", "warning", "notice", "none"}, check.FailOn) {
+		return fmt.Errorf("the fail-on input must be one of error, warning, notice or none")
+	}
+	if report.SeverityRank(check.MinSeverity) == 0 {
+		return fmt.Errorf("the min-severity input must be one of error, warning or notice")
+	}
+	if online := actionInput("online"); online != "" {
+		var err error
+		if check.Online, err = strconv.ParseBool(online); err != nil {
+			return fmt.Errorf("the online input must be true or false")
+		}
+	}
+	if minScore := actionInput("min-score"); minScore != "" {
+		var err error
+		if check.MinScore, err = strconv.Atoi(minScore); err != nil || check.MinScore < 0 || check.MinScore > 100 {
+			return fmt.Errorf("the min-score input must be a number between 0 and 100")
+		}
+	}
+	cli.action = true
+	if allow := actionInput("allow-plugins"); allow != "" {
+		var err error
+		if cli.AllowPlugins, err = strconv.ParseBool(allow); err != nil {
+			return fmt.Errorf("the allow-plugins input must be true or false")
+		}
+	}
+	if config := actionInput("config"); config != "" {
+		cli.Config = config
+	}
+	if lang := actionInput("lang"); lang != "" {
+		cli.Lang = lang
+	}
+	if token := actionInput("github-token"); token != "" {
+		cli.GitHubToken = token
+	}
+
+	files, err := expandPaths(check.Paths)
+	if err != nil {
+		return fmt.Errorf("finding workflow files: %v", err)
+	}
+	results, err := check.check(files)
+	if err != nil {
+		return err
+	}
+	if err := report.Write(os.Stdout, check.Format, files, results, check.reportOptions(files)); err != nil {
+		return fmt.Errorf("writing results: %v", err)
+	}
+	if check.Format != "github" {
+		if err := report.WriteGitHubAnnotations(os.Stdout, results); err != nil {
+			return fmt.Errorf("writing annotations: %v", err)
+		}
+	}
+	check.writeStepSummary(files, results)
+	if err := setActionOutputs(results); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not set the outputs of the step: %v\n", err)
+	}
+
+	if check.shouldFail(files, results) {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// setActionOutputs sets the number of findings, in total and of each
+// severity, as outputs of the step in $GITHUB_OUTPUT.
+func setActionOutputs(results []report.Result) error {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Severity]++
+	}
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "findings-count=%d\nerror-count=%d\nwarning-count=%d\nnotice-count=%d\n",
+		len(results), counts[report.SeverityError], counts[report.SeverityWarning], counts[report.SeverityNotice])
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
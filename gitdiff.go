@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"ghactionscheck/pkg/checks"
+	"ghactionscheck/pkg/report"
+)
+
+// hunkHeaderPattern matches the header of a hunk of a unified diff,
+// capturing the start and length of its lines in the new file.
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -\S+ \+(\d+)(?:,(\d+))? @@`)
+
+// changedLines holds the lines of files changed since a base ref, by
+// absolute path. A nil set means the whole file is new.
+type changedLines map[string]map[int]bool
+
+// gitChangedLines returns the lines of files changed since base, as by git
+// diff against the merge base of base and HEAD, so the changes made on the
+// base branch since are left out. Uncommitted changes are included, and
+// untracked files are new. The files are grouped by repository, so they
+// can span several.
+func gitChangedLines(base string, files []string) (changedLines, error) {
+	roots := make(map[string][]string)
+	for _, file := range files {
+		root := checks.FindRepoRoot(file)
+		roots[root] = append(roots[root], absPath(file))
+	}
+
+	changed := make(changedLines)
+	for root, paths := range roots {
+		mergeBase, err := git(root, "merge-base", base, "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		diff, err := git(root, append([]string{"diff", "--unified=0", "--no-color", "--no-ext-diff", strings.TrimSpace(mergeBase), "--"}, paths...)...)
+		if err != nil {
+			return nil, err
+		}
+		parseDiff(changed, root, diff)
+
+		untracked, err := git(root, append([]string{"ls-files", "--others", "--exclude-standard", "--"}, paths...)...)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range strings.Split(strings.TrimSpace(untracked), "\n") {
+			if file == "" {
+				continue
+			}
+			changed[filepath.Join(root, file)] = nil
+		}
+	}
+	return changed, nil
+}
+
+// parseDiff adds the lines added or changed by a unified diff of the files
+// under root.
+func parseDiff(changed changedLines, root, diff string) {
+	var lines map[int]bool
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if file, ok := strings.CutPrefix(line, "+++ "); ok {
+			lines = nil
+			if file, ok := strings.CutPrefix(file, "b/"); ok {
+				lines = make(map[int]bool)
+				changed[filepath.Join(root, file)] = lines
+			}
+			continue
+		}
+		match := hunkHeaderPattern.FindStringSubmatch(line)
+		if match == nil || lines == nil {
+			continue
+		}
+		start, _ := strconv.Atoi(match[1])
+		count := 1
+		if match[2] != "" {
+			count, _ = strconv.Atoi(match[2])
+		}
+		for i := start; i < start+count; i++ {
+			lines[i] = true
+		}
+	}
+}
+
+// filter returns the results on changed lines. The results of files
+// without changes are left out.
+func (changed changedLines) filter(results []report.Result) []report.Result {
+	var filtered []report.Result
+	for _, result := range results {
+		if lines, ok := changed[absPath(result.File)]; ok && (lines == nil || lines[result.Line]) {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// git runs a git command in dir and returns its output.
+func git(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return stdout.String(), nil
+}
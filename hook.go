@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"ghactionscheck/pkg/checks"
+	"ghactionscheck/pkg/workflow"
+)
+
+// hookFiles returns the files among paths, as given by pre-commit, that are
+// workflow files under .github/workflows or action metadata files. The other
+// files are skipped without being read, to keep the hook fast.
+func hookFiles(paths []string) []string {
+	var files []string
+	for _, path := range paths {
+		if isWorkflowFile(path) || workflow.IsActionFile(path) {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// isWorkflowFile reports whether path is a YAML file in a .github/workflows
+// directory.
+func isWorkflowFile(path string) bool {
+	dir := filepath.Dir(path)
+	ext := strings.ToLower(filepath.Ext(path))
+	return filepath.Base(dir) == "workflows" && filepath.Base(filepath.Dir(dir)) == ".github" && (ext == ".yml" || ext == ".yaml")
+}
+
+// stagedContent returns the contents of file staged in the index of its git
+// repository, as shown by git show :path. Untracked files aren't staged.
+func stagedContent(file string) ([]byte, error) {
+	root := checks.FindRepoRoot(file)
+	rel, err := filepath.Rel(root, absPath(file))
+	if err != nil {
+		return nil, err
+	}
+	staged, err := git(root, "ls-files", "--cached", "--", filepath.ToSlash(rel))
+	if err != nil {
+		return nil, err
+	}
+	if staged == "" {
+		return nil, fmt.Errorf("not in the git index")
+	}
+	content, err := git(root, "show", ":"+filepath.ToSlash(rel))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
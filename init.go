@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ghactionscheck/pkg/checks"
+	"ghactionscheck/pkg/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+type initCmd struct {
+	Output string `name:"output" short:"o" type:"path" help:"Config file to write (default: .ghactionscheck.yaml in the repository root)"`
+	Force  bool   `name:"force" help:"Overwrite an existing config file"`
+	Yes    bool   `name:"yes" short:"y" help:"Don't ask questions, keeping the built-in settings"`
+}
+
+// initHeader is the comment heading the config written by init.
+const initHeader = `# ghactionscheck checks config, written by ghactionscheck init.
+#
+# This file replaces the built-in defaults, so a check missing from it doesn't
+# run. Set the severity (error, warning or notice) of each check, or disable
+# it with enabled: false; ghactionscheck explain <id> describes what a check
+# reports, and ghactionscheck rules lists the state of every check.
+#
+# Findings can also be suppressed in workflow files with comments, for the
+# whole file when at its top followed by a blank line, or for a key and its
+# value:
+#
+#   # ghactionscheck:disable=default_shell
+#
+#   jobs:
+#     # ghactionscheck:disable=timeout
+#     build:
+#       runs-on: ubuntu-latest # ghactionscheck:disable=runner_version
+#       steps:
+#         - uses: actions/checkout@v4 # ghactionscheck:disable=action_ref`
+
+// initQuestion is a question asked by init, and the changes to the checks
+// made by answering yes, or no.
+type initQuestion struct {
+	prompt    string
+	answer    bool
+	yes, no   []initTuning
+	rationale string
+}
+
+// initTuning sets the severity of a check, when not empty, and whether it
+// is enabled.
+type initTuning struct {
+	id       string
+	severity string
+	enabled  bool
+}
+
+// initQuestions are asked in order.
+var initQuestions = []initQuestion{
+	{
+		prompt:    "Is the repository public, accepting pull requests from forks?",
+		yes:       []initTuning{{"action_ref", "error", true}, {"persist_credentials", "error", true}},
+		no:        []initTuning{{"self_hosted_runner", "notice", true}},
+		rationale: "public repository",
+	},
+	{
+		prompt:    "Does it deploy to AWS, Azure or Google Cloud?",
+		answer:    true,
+		no:        []initTuning{{"cloud_credentials", "", false}},
+		rationale: "no cloud deployments",
+	},
+	{
+		prompt:    "Does it use self-hosted runners?",
+		yes:       []initTuning{{"remote_script", "error", true}, {"persist_credentials", "error", true}},
+		rationale: "self-hosted runners keep state between jobs",
+	},
+}
+
+// Run writes a config file listing every built-in check with its default
+// settings and options, tuned by the answers to a few questions about the
+// repository. The questions are skipped with --yes, or when stdin isn't a
+// terminal.
+func (cmd *initCmd) Run() error {
+	path := cmd.Output
+	if path == "" {
+		path = filepath.Join(checks.FindRepoRoot("."), checks.ConfigFileName)
+	}
+	if _, err := os.Stat(path); err == nil && !cmd.Force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(checks.DefaultConfig(), &doc); err != nil {
+		return err
+	}
+	root := doc.Content[0]
+	root.HeadComment = initHeader
+	_, checkList := workflow.LookupKey(root, "checks")
+	for _, check := range checkList.Content {
+		removeKey(check, "doc")
+	}
+
+	if !cmd.Yes && isTerminal(os.Stdin) {
+		in := bufio.NewReader(os.Stdin)
+		for _, question := range initQuestions {
+			answer, err := ask(in, question.prompt, question.answer)
+			if err != nil {
+				return err
+			}
+			tunings := question.no
+			if answer {
+				tunings = question.yes
+			}
+			for _, tuning := range tunings {
+				tune(checkList, tuning, question.rationale)
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	data := []byte(separateBlocks(b.String()))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing config: %v", err)
+	}
+	fmt.Printf("Wrote %s\n", path)
+	return nil
+}
+
+// ask asks a yes or no question on stdout and reads the answer from in,
+// returning def for an empty answer.
+func ask(in *bufio.Reader, prompt string, def bool) (bool, error) {
+	choices := "[y/N]"
+	if def {
+		choices = "[Y/n]"
+	}
+	for {
+		fmt.Printf("%s %s ", prompt, choices)
+		line, err := in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				fmt.Println()
+				return def, nil
+			}
+			return false, err
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+// tune applies tuning to its check in checkList, noting the rationale in a
+// comment on it.
+func tune(checkList *yaml.Node, tuning initTuning, rationale string) {
+	for _, check := range checkList.Content {
+		_, id := workflow.LookupKey(check, "id")
+		if id == nil || id.Value != tuning.id {
+			continue
+		}
+		if id.LineComment == "" {
+			id.LineComment = "Tuned by ghactionscheck init: " + rationale
+		} else if !strings.HasSuffix(id.LineComment, rationale) {
+			id.LineComment += ", " + rationale
+		}
+		if tuning.severity != "" {
+			if _, severity := workflow.LookupKey(check, "severity"); severity != nil {
+				severity.Value = tuning.severity
+			}
+		}
+		if _, enabled := workflow.LookupKey(check, "enabled"); enabled != nil {
+			enabled.Value = fmt.Sprint(tuning.enabled)
+		}
+	}
+}
+
+// separateBlocks adds the blank lines that the YAML encoder drops between
+// the checks, and before the top-level keys following them.
+func separateBlocks(text string) string {
+	lines := strings.Split(text, "\n")
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			item := strings.HasPrefix(line, "  - id: ") && prev != "checks:"
+			topLevel := line != "" && line[0] != ' ' && strings.HasPrefix(prev, " ")
+			if item || topLevel {
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// removeKey removes key and its value from a mapping node.
+func removeKey(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
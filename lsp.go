@@ -0,0 +1,694 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"ghactionscheck/pkg/checks"
+	"ghactionscheck/pkg/report"
+	"ghactionscheck/pkg/workflow"
+)
+
+type lspCmd struct{}
+
+// Run serves the Language Server Protocol over stdin and stdout. Open
+// workflow files are checked whenever they change, and fixes are offered as
+// code actions. Requests are handled one at a time.
+func (cmd *lspCmd) Run() error {
+	s := &lspServer{
+		in:        bufio.NewReader(os.Stdin),
+		out:       os.Stdout,
+		documents: make(map[string][]byte),
+		configs:   make(map[string]*lspConfig),
+		github:    githubClient(),
+	}
+	return s.serve()
+}
+
+// lspServer is a language server for the workflow files open in an editor.
+type lspServer struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	// documents holds the contents of the open workflow files by URI.
+	documents map[string][]byte
+	// configs holds the loaded config of each workspace root, so it is
+	// only read, and its extends fetched, again when a config changes.
+	configs map[string]*lspConfig
+	// github resolves action refs for the pinning fixes, and is shared by
+	// all files so its cache lasts for the session.
+	github *checks.GitHubClient
+	// resolvePins is set when the editor resolves the edits of code
+	// actions, so pinning refs is offered without querying the API for
+	// every codeAction request.
+	resolvePins bool
+	// watchConfigs is set when the editor lets the server register for
+	// changes to config files made outside of it.
+	watchConfigs bool
+	shutdown     bool
+}
+
+// lspConfig is the config of a workspace root and the checker built from it.
+type lspConfig struct {
+	config  *checks.Config
+	checker *checks.Checker
+}
+
+// rpcMessage is a JSON-RPC request, notification or response.
+type rpcMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC error codes.
+const (
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+const (
+	lspSourceName = "ghactionscheck"
+	lspQuickFix   = "quickfix"
+	// lspSyncFull makes the editor send the whole document on each change.
+	lspSyncFull = 1
+)
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspCodeAction struct {
+	Title       string             `json:"title"`
+	Kind        string             `json:"kind"`
+	Diagnostics []lspDiagnostic    `json:"diagnostics,omitempty"`
+	Edit        *lspWorkspaceEdit  `json:"edit,omitempty"`
+	Data        *lspCodeActionData `json:"data,omitempty"`
+}
+
+type lspWorkspaceEdit struct {
+	Changes map[string][]lspTextEdit `json:"changes"`
+}
+
+// lspCodeActionData identifies the finding of a code action whose edit is
+// computed by codeAction/resolve.
+type lspCodeActionData struct {
+	URI     string `json:"uri"`
+	CheckID string `json:"checkId"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+}
+
+type lspInitializeParams struct {
+	Capabilities struct {
+		Workspace struct {
+			DidChangeWatchedFiles struct {
+				DynamicRegistration bool `json:"dynamicRegistration"`
+			} `json:"didChangeWatchedFiles"`
+		} `json:"workspace"`
+		TextDocument struct {
+			CodeAction struct {
+				ResolveSupport struct {
+					Properties []string `json:"properties"`
+				} `json:"resolveSupport"`
+			} `json:"codeAction"`
+		} `json:"textDocument"`
+	} `json:"capabilities"`
+}
+
+type lspDocumentParams struct {
+	TextDocument struct {
+		URI  string `json:"uri"`
+		Text string `json:"text"`
+	} `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+	Text *string `json:"text"`
+}
+
+type lspDidChangeWatchedFilesParams struct {
+	Changes []struct {
+		URI string `json:"uri"`
+	} `json:"changes"`
+}
+
+type lspCodeActionParams struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Range lspRange `json:"range"`
+}
+
+func (s *lspServer) serve() error {
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "" {
+			// A response to a request of the server, such as
+			// client/registerCapability, needs no handling.
+			continue
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				os.Exit(1)
+			}
+			return nil
+		}
+
+		result, rpcErr := s.handle(msg)
+		if msg.ID == nil {
+			if rpcErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", msg.Method, rpcErr.Message)
+			}
+			continue
+		}
+		response := rpcMessage{JSONRPC: "2.0", ID: msg.ID, Error: rpcErr}
+		if rpcErr == nil {
+			// A null result must still be sent, which omitempty would drop.
+			response.Result = result
+			if result == nil {
+				response.Result = json.RawMessage("null")
+			}
+		}
+		if err := s.write(response); err != nil {
+			return err
+		}
+	}
+}
+
+// handle handles a request or notification, returning the result for a
+// request.
+func (s *lspServer) handle(msg *rpcMessage) (interface{}, *rpcError) {
+	if s.shutdown && msg.ID != nil {
+		return nil, &rpcError{rpcInvalidRequest, "server is shut down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		var params lspInitializeParams
+		if len(msg.Params) > 0 {
+			if err := json.Unmarshal(msg.Params, &params); err != nil {
+				return nil, &rpcError{rpcInvalidParams, err.Error()}
+			}
+		}
+		s.resolvePins = slices.Contains(params.Capabilities.TextDocument.CodeAction.ResolveSupport.Properties, "edit")
+		s.watchConfigs = params.Capabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": map[string]interface{}{
+					"openClose": true,
+					"change":    lspSyncFull,
+					"save":      map[string]bool{"includeText": true},
+				},
+				"codeActionProvider": map[string]interface{}{
+					"codeActionKinds": []string{lspQuickFix},
+					"resolveProvider": true,
+				},
+			},
+			"serverInfo": map[string]string{"name": lspSourceName},
+		}, nil
+	case "initialized":
+		return nil, s.watchConfigFiles()
+	case "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+		return nil, nil
+	case "workspace/didChangeWatchedFiles":
+		var params lspDidChangeWatchedFilesParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+		for _, change := range params.Changes {
+			s.configChanged(change.URI)
+		}
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen", "textDocument/didChange", "textDocument/didSave":
+		var params lspDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+		uri := params.TextDocument.URI
+		if msg.Method == "textDocument/didSave" {
+			s.configChanged(uri)
+		}
+		if lspFile(uri) == "" {
+			return nil, nil
+		}
+		switch {
+		case msg.Method == "textDocument/didOpen":
+			s.documents[uri] = []byte(params.TextDocument.Text)
+		case len(params.ContentChanges) > 0:
+			// Full sync sends the whole document as the last change.
+			s.documents[uri] = []byte(params.ContentChanges[len(params.ContentChanges)-1].Text)
+		case params.Text != nil:
+			s.documents[uri] = []byte(*params.Text)
+		}
+		return nil, s.publish(uri)
+	case "textDocument/didClose":
+		var params lspDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+		uri := params.TextDocument.URI
+		if _, ok := s.documents[uri]; !ok {
+			return nil, nil
+		}
+		delete(s.documents, uri)
+		return nil, s.notify(uri, []lspDiagnostic{})
+	case "textDocument/codeAction":
+		var params lspCodeActionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+		actions, err := s.codeActions(params.TextDocument.URI, params.Range)
+		if err != nil {
+			return nil, &rpcError{rpcInternalError, err.Error()}
+		}
+		return actions, nil
+	case "codeAction/resolve":
+		var action lspCodeAction
+		if err := json.Unmarshal(msg.Params, &action); err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+		if err := s.resolveCodeAction(&action); err != nil {
+			return nil, &rpcError{rpcInternalError, err.Error()}
+		}
+		return action, nil
+	}
+	if msg.ID == nil || strings.HasPrefix(msg.Method, "$/") {
+		return nil, nil
+	}
+	return nil, &rpcError{rpcMethodNotFound, "method not supported: " + msg.Method}
+}
+
+// publish checks an open document and publishes its findings.
+func (s *lspServer) publish(uri string) *rpcError {
+	data := s.documents[uri]
+	var diagnostics []lspDiagnostic
+	results, err := s.check(uri, data)
+	if err != nil {
+		// Report errors such as invalid YAML at the start of the file, as
+		// the file can't be checked until they are resolved.
+		diagnostics = append(diagnostics, lspDiagnostic{
+			Severity: 1,
+			Source:   lspSourceName,
+			Message:  err.Error(),
+		})
+	}
+	for _, result := range results {
+		diagnostics = append(diagnostics, resultDiagnostic(data, result))
+	}
+	if diagnostics == nil {
+		diagnostics = []lspDiagnostic{}
+	}
+	return s.notify(uri, diagnostics)
+}
+
+func (s *lspServer) notify(uri string, diagnostics []lspDiagnostic) *rpcError {
+	params, err := json.Marshal(map[string]interface{}{"uri": uri, "diagnostics": diagnostics})
+	if err != nil {
+		return &rpcError{rpcInternalError, err.Error()}
+	}
+	msg := rpcMessage{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics", Params: params}
+	if err := s.write(msg); err != nil {
+		return &rpcError{rpcInternalError, err.Error()}
+	}
+	return nil
+}
+
+// fixer returns a fixer for a document, using the config that applies to
+// its file. The config is loaded once for each workspace root, and again
+// after a config file is saved or changed, so that edits to it take effect
+// without restarting the server.
+func (s *lspServer) fixer(uri string) (*fixer, error) {
+	file := lspFile(uri)
+	root := checks.FindRepoRoot(file)
+	loaded, ok := s.configs[root]
+	if !ok {
+		checksConfig, err := loadConfig(file)
+		if err != nil {
+			return nil, fmt.Errorf("loading checks config: %v", err)
+		}
+		c, err := checks.New(checksConfig, checks.WithGitHubHost(githubHost()))
+		if err != nil {
+			return nil, err
+		}
+		loaded = &lspConfig{config: checksConfig, checker: c}
+		s.configs[root] = loaded
+	}
+	return &fixer{config: loaded.config, checker: loaded.checker, github: s.github}, nil
+}
+
+// configChanged forgets the loaded configs when the file at uri may be one
+// of them: a YAML file other than a workflow or action, such as a checks
+// config or a config it extends. The open documents are checked again with
+// the reloaded configs.
+func (s *lspServer) configChanged(uri string) {
+	if lspYAMLFile(uri) == "" || lspFile(uri) != "" || len(s.configs) == 0 {
+		return
+	}
+	clear(s.configs)
+	for open := range s.documents {
+		if rpcErr := s.publish(open); rpcErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", open, rpcErr.Message)
+		}
+	}
+}
+
+// watchConfigFiles asks the editor to report changes to the checks config
+// files made outside of it, such as by checking out a branch, when it
+// supports registering for them.
+func (s *lspServer) watchConfigFiles() *rpcError {
+	if !s.watchConfigs {
+		return nil
+	}
+	params, err := json.Marshal(map[string]interface{}{
+		"registrations": []map[string]interface{}{{
+			"id":     "configs",
+			"method": "workspace/didChangeWatchedFiles",
+			"registerOptions": map[string]interface{}{
+				"watchers": []map[string]string{{"globPattern": "**/" + checks.ConfigFileName}},
+			},
+		}},
+	})
+	if err != nil {
+		return &rpcError{rpcInternalError, err.Error()}
+	}
+	id := json.RawMessage(`"register-configs"`)
+	if err := s.write(rpcMessage{JSONRPC: "2.0", ID: &id, Method: "client/registerCapability", Params: params}); err != nil {
+		return &rpcError{rpcInternalError, err.Error()}
+	}
+	return nil
+}
+
+func (s *lspServer) check(uri string, data []byte) ([]report.Result, error) {
+	f, err := s.fixer(uri)
+	if err != nil {
+		return nil, err
+	}
+	return f.checker.Check(lspFile(uri), data)
+}
+
+// codeActions returns a quick fix for each fixable finding in rng. Pinning
+// refs, which queries the GitHub API, is offered without its edit, which
+// codeAction/resolve computes when the action is chosen, and only to editors
+// resolving code actions.
+func (s *lspServer) codeActions(uri string, rng lspRange) ([]lspCodeAction, error) {
+	actions := []lspCodeAction{}
+	data, ok := s.documents[uri]
+	if !ok {
+		return actions, nil
+	}
+	f, err := s.fixer(uri)
+	if err != nil {
+		return nil, err
+	}
+	f.noPins = true
+
+	diagnostics := make(map[findingKey]lspDiagnostic)
+	edits, err := f.fixes(lspFile(uri), data, func(result report.Result) bool {
+		diagnostic := resultDiagnostic(data, result)
+		if diagnostic.Range.Start.Line < rng.Start.Line || diagnostic.Range.Start.Line > rng.End.Line {
+			return false
+		}
+		diagnostics[findingKey{result.CheckID, result.Line, result.Column}] = diagnostic
+		return true
+	})
+	if err != nil {
+		// The document is invalid, so there is nothing to fix yet.
+		return actions, nil
+	}
+
+	index := make(map[findingKey]int)
+	for _, e := range edits {
+		i, ok := index[e.finding]
+		if !ok {
+			i = len(actions)
+			index[e.finding] = i
+			action := lspCodeAction{Kind: lspQuickFix}
+			if diagnostic, ok := diagnostics[e.finding]; ok {
+				action.Diagnostics = []lspDiagnostic{diagnostic}
+			}
+			action.Edit = &lspWorkspaceEdit{Changes: map[string][]lspTextEdit{uri: nil}}
+			actions = append(actions, action)
+		}
+		if e.note != "" {
+			actions[i].Title = "Fix: " + e.note
+		}
+		actions[i].Edit.Changes[uri] = append(actions[i].Edit.Changes[uri], lspTextEdit{
+			Range:   lspRange{offsetPosition(data, e.start), offsetPosition(data, e.end)},
+			NewText: e.text,
+		})
+	}
+
+	if !s.resolvePins {
+		return actions, nil
+	}
+	w, err := workflow.Parse(data)
+	if err != nil {
+		return actions, nil
+	}
+	flagged := make(findings)
+	for key := range diagnostics {
+		flagged[key] = true
+	}
+	refs := pinnableRefs(w, flagged)
+	for _, node := range refs.order {
+		key := keyOf(refs.checks[node], node)
+		actions = append(actions, lspCodeAction{
+			Title:       fmt.Sprintf("Fix: pin %s to a commit hash", node.Value),
+			Kind:        lspQuickFix,
+			Diagnostics: []lspDiagnostic{diagnostics[key]},
+			Data:        &lspCodeActionData{URI: uri, CheckID: key.checkID, Line: key.line, Column: key.column},
+		})
+	}
+	return actions, nil
+}
+
+// resolveCodeAction sets the edit of a code action pinning a ref, resolving
+// the ref with the GitHub API.
+func (s *lspServer) resolveCodeAction(action *lspCodeAction) error {
+	if action.Data == nil || action.Edit != nil {
+		return nil
+	}
+	uri := action.Data.URI
+	data, ok := s.documents[uri]
+	if !ok {
+		return nil
+	}
+	f, err := s.fixer(uri)
+	if err != nil {
+		return err
+	}
+	key := findingKey{action.Data.CheckID, action.Data.Line, action.Data.Column}
+	edits, err := f.fixes(lspFile(uri), data, func(result report.Result) bool {
+		return findingKey{result.CheckID, result.Line, result.Column} == key
+	})
+	if err != nil {
+		return nil
+	}
+	action.Edit = &lspWorkspaceEdit{Changes: map[string][]lspTextEdit{uri: {}}}
+	for _, e := range edits {
+		if e.finding != key {
+			continue
+		}
+		action.Edit.Changes[uri] = append(action.Edit.Changes[uri], lspTextEdit{
+			Range:   lspRange{offsetPosition(data, e.start), offsetPosition(data, e.end)},
+			NewText: e.text,
+		})
+	}
+	return nil
+}
+
+// resultDiagnostic converts a finding to a diagnostic spanning the rest of
+// its line.
+func resultDiagnostic(data []byte, result report.Result) lspDiagnostic {
+	severity := 2
+	switch result.Severity {
+	case report.SeverityError:
+		severity = 1
+	case report.SeverityNotice:
+		severity = 3
+	}
+	message := result.Message
+	if result.Description != "" {
+		message += "\n" + result.Description
+	}
+
+	line := max(result.Line-1, 0)
+	text := strings.TrimRight(lineText(data, line+1), " \t\r")
+	start := utf16Length(runePrefix(text, max(result.Column-1, 0)))
+	return lspDiagnostic{
+		Range: lspRange{
+			Start: lspPosition{line, start},
+			End:   lspPosition{line, max(utf16Length(text), start)},
+		},
+		Severity: severity,
+		Code:     result.CheckID,
+		Source:   lspSourceName,
+		Message:  message,
+	}
+}
+
+// offsetPosition converts a byte offset in data to an LSP position, whose
+// character counts UTF-16 code units.
+func offsetPosition(data []byte, offset int) lspPosition {
+	prefix := data[:offset]
+	line := bytes.Count(prefix, []byte("\n"))
+	column := prefix[bytes.LastIndexByte(prefix, '\n')+1:]
+	return lspPosition{line, utf16Length(string(column))}
+}
+
+// runePrefix returns the first n runes of text, as yaml.v3 columns count
+// runes.
+func runePrefix(text string, n int) string {
+	for i := range text {
+		if n == 0 {
+			return text[:i]
+		}
+		n--
+	}
+	return text
+}
+
+// utf16Length returns the number of UTF-16 code units encoding text: runes
+// outside the Basic Multilingual Plane take a surrogate pair.
+func utf16Length(text string) int {
+	n := 0
+	for _, r := range text {
+		if r > 0xffff {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// lspFile returns the path of a file URI if it is a workflow or action
+// metadata file, or an empty string for other documents.
+func lspFile(uri string) string {
+	file := lspYAMLFile(uri)
+	if file == "" {
+		return ""
+	}
+	if workflow.IsActionFile(file) {
+		return file
+	}
+	if filepath.Base(filepath.Dir(file)) != "workflows" || filepath.Base(filepath.Dir(filepath.Dir(file))) != ".github" {
+		return ""
+	}
+	return file
+}
+
+// lspYAMLFile returns the path of a file URI if it is a YAML file, or an
+// empty string for other documents.
+func lspYAMLFile(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	file := filepath.FromSlash(u.Path)
+	if ext := filepath.Ext(file); ext != ".yml" && ext != ".yaml" {
+		return ""
+	}
+	return file
+}
+
+func (s *lspServer) read() (*rpcMessage, error) {
+	length := -1
+	for {
+		header, err := s.in.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && header == "" {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("reading message header: %v", err)
+		}
+		header = strings.TrimRight(header, "\r\n")
+		if header == "" {
+			break
+		}
+		name, value, ok := strings.Cut(header, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %v", err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message without Content-Length")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, fmt.Errorf("reading message: %v", err)
+	}
+	var msg rpcMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		// Answer with an error and carry on with an empty notification,
+		// rather than stopping the server.
+		nullID := json.RawMessage("null")
+		return &rpcMessage{}, s.write(rpcMessage{
+			JSONRPC: "2.0",
+			ID:      &nullID,
+			Error:   &rpcError{rpcInvalidRequest, err.Error()},
+		})
+	}
+	return &msg, nil
+}
+
+func (s *lspServer) write(msg rpcMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
@@ -1,258 +1,633 @@
 package main
 
 import (
+	"cmp"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
-	"regexp"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"ghactionscheck/pkg/checks"
+	"ghactionscheck/pkg/report"
+	"ghactionscheck/pkg/workflow"
 	"github.com/alecthomas/kong"
-	"github.com/olekukonko/tablewriter"
-	"gopkg.in/yaml.v3"
 )
 
 var cli struct {
-	File string `arg:"" name:"file" help:"Path to GitHub Actions workflow file"`
+	Config       string           `name:"config" type:"path" help:"Path to a checks config file (overrides config discovery)"`
+	AllowPlugins bool             `name:"allow-plugins" help:"Run the command plugins of a checks config found in the repository"`
+	GitHubToken  string           `name:"github-token" env:"GITHUB_TOKEN,GH_TOKEN" help:"GitHub token for API requests"`
+	GitHubAPIURL string           `name:"github-api-url" env:"GITHUB_API_URL" placeholder:"URL" help:"URL of the GitHub API, such as https://HOST/api/v3 for GitHub Enterprise Server (default: https://api.github.com)"`
+	NoCache      bool             `name:"no-cache" help:"Don't cache GitHub API lookups in the cache directory"`
+	Lang         string           `name:"lang" env:"LANG" placeholder:"LANG" help:"Language of the messages of findings, such as en or ja"`
+	VersionFlag  kong.VersionFlag `name:"version" help:"Print the version and build metadata, and exit"`
+
+	// action is set when running as the GitHub Action, whose config input
+	// is a file of the checked repository like a discovered config.
+	action bool
+
+	Check    checkCmd    `cmd:"" default:"withargs" help:"Check workflow files (default)"`
+	Fix      fixCmd      `cmd:"" help:"Fix findings in workflow files"`
+	Lsp      lspCmd      `cmd:"" name:"lsp" help:"Run a language server for editors"`
+	Remote   remoteCmd   `cmd:"" help:"Check the workflows of a GitHub repository without cloning it"`
+	Org      orgCmd      `cmd:"" help:"Check the workflows of all repositories in a GitHub organization"`
+	Baseline baselineCmd `cmd:"" help:"Record the current findings in a baseline file"`
+	Rules    rulesCmd    `cmd:"" help:"List the available checks and their state in the checks config"`
+	Explain  explainCmd  `cmd:"" help:"Explain a check, with examples and how to resolve its findings"`
+	Init     initCmd     `cmd:"" help:"Write a checks config file listing every check"`
+	Doctor   doctorCmd   `cmd:"" help:"Diagnose the config, GitHub token, API access and cache directory"`
+	Action   actionCmd   `cmd:"" name:"github-action" help:"Check workflows as a GitHub Action, with the inputs of the action from $INPUT_* variables"`
+	Version  versionCmd  `cmd:"" help:"Print the version and build metadata"`
 }
 
-type Workflow struct {
-	Jobs        map[string]Job `yaml:"jobs"`
-	Defaults    *Defaults      `yaml:"defaults"`
-	Concurrency interface{}    `yaml:"concurrency"`
+type checkCmd struct {
+	Paths    []string `arg:"" optional:"" name:"path" default:"." help:"Workflow files, glob patterns or repository directories to check, or - to read a workflow from stdin"`
+	Filename string   `name:"filename" help:"File name to report for a workflow read from stdin"`
+	Watch    bool     `name:"watch" help:"Check the workflow files again whenever they change"`
+	DiffBase string   `name:"diff-base" help:"Only report findings on lines changed since this git ref, such as origin/main"`
+	PR       string   `name:"pr" help:"Post the findings on the lines a pull request adds as review comments, for a checkout of its head (owner/repo#number)"`
+	Upload   bool     `name:"upload" help:"Upload the findings as SARIF to GitHub code scanning, for the current repository and commit"`
+	Hook     bool     `name:"hook" help:"Run as a pre-commit hook: only check the given workflow and action files, in the compact format"`
+	Staged   bool     `name:"staged" help:"Check the contents of the files staged in the git index rather than in the working tree"`
+	checkFlags
 }
 
-type Defaults struct {
-	Run *RunDefaults `yaml:"run"`
+// checkFlags are the flags of the commands checking and reporting on
+// workflows.
+type checkFlags struct {
+	Format        string   `name:"format" enum:"table,json,rdjson,rdjsonl,junit,checkstyle,markdown,html,csv,tap,sarif,github,compact" default:"table" help:"Output format (table, json, rdjson, rdjsonl, junit, checkstyle, markdown, html, csv, tap, sarif, github, compact)"`
+	Output        string   `name:"output" short:"o" type:"path" help:"Write the report to this file instead of stdout"`
+	FailOn        string   `name:"fail-on" enum:"error,warning,notice,none" default:"warning" help:"Exit with status 1 when a finding has at least this severity (error, warning, notice, none)"`
+	Quiet         bool     `name:"quiet" short:"q" help:"Don't write the report, only set the exit status"`
+	Summary       bool     `name:"summary" help:"Only show the number of findings of each check and severity"`
+	MaxFindings   int      `name:"max-findings" help:"Show at most this many findings"`
+	GroupBy       string   `name:"group-by" placeholder:"job|check|file" help:"Show a table of findings for each job, check or file"`
+	Only          []string `name:"only" placeholder:"CHECK" help:"Only report the findings of these checks"`
+	Exclude       []string `name:"exclude" placeholder:"CHECK" help:"Don't report the findings of these checks"`
+	MinSeverity   string   `name:"min-severity" enum:"error,warning,notice" default:"notice" help:"Only report findings with at least this severity (error, warning, notice)"`
+	MinScore      int      `name:"min-score" placeholder:"SCORE" help:"Exit with status 1 when a file scores below this (0-100)"`
+	Baseline      string   `name:"baseline" type:"path" help:"Only report the findings not recorded in this baseline file"`
+	NoColor       bool     `name:"no-color" help:"Don't color the table output, as with $NO_COLOR"`
+	NoStepSummary bool     `name:"no-step-summary" help:"Don't append the findings to $GITHUB_STEP_SUMMARY in GitHub Actions"`
+	NoStats       bool     `name:"no-stats" help:"Don't append the files and jobs checked, the findings by severity and check, and the time taken to the report"`
+	checkerFlags
+
+	// run records the start of the run and the jobs checked, for the
+	// statistics of the report.
+	run struct {
+		start time.Time
+		jobs  atomic.Int64
+		// checks are the ids of the checks that ran, for the reports
+		// listing those that passed.
+		checks atomic.Pointer[[]string]
+	}
 }
 
-type RunDefaults struct {
-	Shell string `yaml:"shell"`
+// checkerFlags are the flags selecting the checks that run.
+type checkerFlags struct {
+	Online  bool     `name:"online" help:"Enable checks that query the GitHub API"`
+	Policy  []string `name:"policy" type:"path" help:"Evaluate the Rego policies in these files or directories"`
+	Schema  bool     `name:"schema" help:"Also validate files against the SchemaStore workflow and action schemas"`
+	Jobs    int      `name:"jobs" short:"j" help:"Number of files checked at once (default: the number of CPUs)"`
+	Enable  []string `name:"enable" placeholder:"CHECK" help:"Enable these checks for this run, whatever the config"`
+	Disable []string `name:"disable" placeholder:"CHECK" help:"Disable these checks for this run, whatever the config"`
 }
 
-type Job struct {
-	TimeoutMinutes *int                     `yaml:"timeout-minutes"`
-	Permissions    *map[string]string       `yaml:"permissions"`
-	Steps          []map[string]interface{} `yaml:"steps"`
-	RunsOn         interface{}              `yaml:"runs-on"`
+// checker returns a Checker running the checks of config as set by the
+// flags, with the additional options. The online checks query the API with
+// github, or with the shared client when it is nil.
+func (flags *checkerFlags) checker(config *checks.Config, github *checks.GitHubClient, options ...checks.Option) (*checks.Checker, error) {
+	if len(flags.Enable) > 0 || len(flags.Disable) > 0 {
+		var err error
+		if config, err = config.Override(flags.Enable, flags.Disable); err != nil {
+			return nil, err
+		}
+	}
+	if flags.Online {
+		if github == nil {
+			github = githubClient()
+		}
+		options = append(options, checks.WithGitHub(github))
+	}
+	if len(flags.Policy) > 0 {
+		options = append(options, checks.WithRegoPolicies(flags.Policy...))
+	}
+	if flags.Schema {
+		options = append(options, checks.WithSchema())
+	}
+	options = append(options, checks.WithGitHubHost(githubHost()))
+	return checks.New(config, options...)
 }
 
-type Check struct {
-	ID          string `yaml:"id"`
-	Description string `yaml:"description"`
-	Message     string `yaml:"message"`
-	Detail      string `yaml:"detail"`
-	Enabled     *bool  `yaml:"enabled,omitempty"`
+// githubHost returns the host of the repositories served by the API of
+// --github-api-url.
+func githubHost() string {
+	return checks.WebHost(cli.GitHubAPIURL)
 }
 
-type ChecksConfig struct {
-	Checks []Check `yaml:"checks"`
-}
+// githubClient returns the client shared by the API requests of the run,
+// so that its caches and the state of the rate limit last for the whole
+// run. It authenticates with --github-token to the API of --github-api-url,
+// and caches its lookups in the cache directory unless --no-cache is set.
+var githubClient = sync.OnceValue(func() *checks.GitHubClient {
+	github := checks.NewGitHubClient(cli.GitHubToken)
+	if cli.GitHubAPIURL != "" {
+		github.SetAPIURL(cli.GitHubAPIURL)
+	}
+	if !cli.NoCache {
+		if dir, err := checks.CacheDir(); err == nil {
+			github.SetCacheDir(filepath.Join(dir, "api"))
+		}
+	}
+	return github
+})
 
-type CheckResult struct {
-	JobName     string
-	Message     string
-	Description string
+func main() {
+	ctx := kong.Parse(&cli, kong.Vars{"version": versionInfo()})
+	if err := ctx.Run(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 }
 
-var commitHashPattern = regexp.MustCompile(`^[0-9a-f]{40}([0-9a-f]{24})?$`)
-
-func loadChecksConfig() (*ChecksConfig, error) {
-	data, err := os.ReadFile("checks.yaml")
+// loadConfig loads the checks config given by --config, or discovered for
+// path, with the messages in the language of --lang. The command plugins of
+// a config found in the repository are left out without --allow-plugins, as
+// the repository may not be trusted to run commands, such as a clone of a
+// third-party repository or a pull request editing the config.
+func loadConfig(path string) (*checks.Config, error) {
+	configPath := cli.Config
+	if configPath == "" {
+		configPath = checks.FindConfigFile(path)
+	}
+	config, err := checks.LoadConfig(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("error reading checks config: %v", err)
+		return nil, err
 	}
-
-	var config ChecksConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("error parsing checks config: %v", err)
+	trusted := cli.AllowPlugins || (cli.Config != "" && !cli.action) || configPath == checks.UserConfigFile()
+	if !trusted {
+		if names := config.RemoveCommandPlugins(); len(names) > 0 {
+			allow := "pass --allow-plugins"
+			if cli.action {
+				allow = "set the allow-plugins input"
+			}
+			fmt.Fprintf(os.Stderr, "Warning: not running the command plugins %s of %s, found in the repository; %s to run them\n",
+				strings.Join(names, ", "), configPath, allow)
+		}
 	}
-
-	return &config, nil
+	if err := config.Localize(cli.Lang); err != nil {
+		return nil, err
+	}
+	return config, nil
 }
 
-func findCheck(checks []Check, id string) *Check {
-	for _, check := range checks {
-		if check.ID == id {
-			if check.Enabled == nil || *check.Enabled {
-				return &check
-			}
+func (cmd *checkCmd) Run() error {
+	if cmd.Hook && cmd.Watch {
+		return fmt.Errorf("--hook can't be used with --watch")
+	}
+	var files []string
+	if cmd.Hook {
+		files = hookFiles(cmd.Paths)
+		if len(files) == 0 {
 			return nil
 		}
+		if cmd.Format == "table" {
+			cmd.Format = "compact"
+		}
+		// A hook passing on every commit should stay quiet.
+		cmd.NoStats = true
+	} else {
+		var err error
+		if files, err = expandPaths(cmd.Paths); err != nil {
+			return fmt.Errorf("finding workflow files: %v", err)
+		}
+	}
+
+	if cmd.Watch && slices.Contains(files, stdinPath) {
+		return fmt.Errorf("--watch can't be used with a workflow read from stdin")
+	}
+	if cmd.Watch && (cmd.Output != "" || cmd.Quiet) {
+		return fmt.Errorf("--watch can't be used with --output or --quiet, as it prints the changes as they happen")
+	}
+	if err := cmd.validateOutput(); err != nil {
+		return err
+	}
+	if cmd.DiffBase != "" && slices.Contains(files, stdinPath) {
+		return fmt.Errorf("--diff-base can't be used with a workflow read from stdin")
+	}
+	if cmd.PR != "" && (cmd.Watch || slices.Contains(files, stdinPath)) {
+		return fmt.Errorf("--pr can't be used with --watch or a workflow read from stdin")
+	}
+	if cmd.Upload && (cmd.Watch || slices.Contains(files, stdinPath)) {
+		return fmt.Errorf("--upload can't be used with --watch or a workflow read from stdin")
+	}
+	if cmd.Staged && (cmd.Watch || slices.Contains(files, stdinPath)) {
+		return fmt.Errorf("--staged can't be used with --watch or a workflow read from stdin")
+	}
+
+	results, err := cmd.check(files)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = cmd.name(file)
+	}
+	err = cmd.writeReport(func(out io.Writer) error {
+		return report.Write(out, cmd.Format, names, results, cmd.reportOptions(names))
+	})
+	if err != nil {
+		return fmt.Errorf("writing results: %v", err)
+	}
+	cmd.writeStepSummary(names, results)
+	if cmd.PR != "" {
+		if err := postReview(cmd.PR, results); err != nil {
+			return fmt.Errorf("posting review comments: %v", err)
+		}
+	}
+	if cmd.Upload {
+		if err := uploadSARIF(files, results); err != nil {
+			return fmt.Errorf("uploading to code scanning: %v", err)
+		}
+	}
+
+	if cmd.Watch {
+		return cmd.watch(files)
+	}
+	if cmd.shouldFail(names, results) {
+		os.Exit(1)
 	}
 	return nil
 }
 
-func main() {
-	ctx := kong.Parse(&cli)
-	if ctx.Error != nil {
-		fmt.Printf("Error parsing arguments: %v\n", ctx.Error)
-		os.Exit(1)
+// check checks files with the config for the first of them, which is loaded
+// each time so that watch mode picks up changes to it. The files are checked
+// in parallel by --jobs goroutines, and the results kept in their order.
+func (cmd *checkCmd) check(files []string) ([]report.Result, error) {
+	cmd.startRun()
+	base := files[0]
+	if base == stdinPath {
+		base = cmp.Or(cmd.Filename, ".")
+	}
+	checksConfig, err := loadConfig(base)
+	if err != nil {
+		return nil, fmt.Errorf("loading checks config: %v", err)
 	}
 
-	checksConfig, err := loadChecksConfig()
+	c, err := cmd.checker(checksConfig, nil)
 	if err != nil {
-		fmt.Printf("Error loading checks config: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
 
-	data, err := os.ReadFile(cli.File)
+	fileResults, err := parallel(len(files), cmd.Jobs, func(i int) ([]report.Result, error) {
+		file := files[i]
+		var results []report.Result
+		var err error
+		if file == stdinPath {
+			var data []byte
+			data, err = io.ReadAll(os.Stdin)
+			if err == nil {
+				results, err = c.Check(cmd.name(file), data)
+			}
+		} else if cmd.Staged {
+			var data []byte
+			data, err = stagedContent(file)
+			if err == nil {
+				results, err = c.Check(file, data)
+			}
+		} else {
+			results, err = c.CheckFile(file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("checking %s: %v", cmd.name(file), err)
+		}
+		return results, nil
+	})
 	if err != nil {
-		fmt.Printf("Error reading file: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
+	cmd.recordChecks(c)
+	results := slices.Concat(fileResults...)
+	if cmd.DiffBase != "" {
+		changed, err := gitChangedLines(cmd.DiffBase, files)
+		if err != nil {
+			return nil, fmt.Errorf("finding changed lines: %v", err)
+		}
+		results = changed.filter(results)
+	}
+	return cmd.newFindings(results)
+}
 
-	var workflow Workflow
-	err = yaml.Unmarshal(data, &workflow)
+// writeReport calls write with the --output file, or with stdout without
+// one. Nothing is written to stdout with --quiet.
+func (flags *checkFlags) writeReport(write func(io.Writer) error) error {
+	if flags.Output == "" && flags.Quiet {
+		return nil
+	}
+	if flags.Output == "" {
+		return write(os.Stdout)
+	}
+	out, err := os.Create(flags.Output)
 	if err != nil {
-		fmt.Printf("Error parsing YAML: %v\n", err)
-		os.Exit(1)
+		return err
+	}
+	if err := write(out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// startRun starts recording the statistics of a run.
+func (flags *checkFlags) startRun() {
+	flags.run.start = time.Now()
+	flags.run.jobs.Store(0)
+	flags.run.checks.Store(nil)
+}
+
+// recordChecks records the jobs checked by c and the checks it ran that
+// --only, --exclude and --min-severity select.
+func (flags *checkFlags) recordChecks(c *checks.Checker) {
+	flags.run.jobs.Add(int64(c.Jobs()))
+	var ids []string
+	for _, check := range c.EnabledChecks() {
+		if flags.selects(check.ID, check.Severity) {
+			ids = append(ids, check.ID)
+		}
 	}
+	flags.run.checks.Store(&ids)
+}
 
-	results := checkWorkflow(workflow, checksConfig.Checks)
-	outputResults(results)
+// reportOptions returns the options of the report on files. The table is
+// colored when written to a terminal, unless --no-color or $NO_COLOR is set,
+// and the statistics of the run are included unless --no-stats is set.
+func (flags *checkFlags) reportOptions(files []string) report.Options {
+	color := !flags.NoColor && os.Getenv("NO_COLOR") == "" && flags.Output == "" && isTerminal(os.Stdout)
+	options := report.Options{Color: color, Summary: flags.Summary, MaxFindings: flags.MaxFindings, GroupBy: flags.GroupBy}
+	if ids := flags.run.checks.Load(); ids != nil {
+		options.Checks = *ids
+	}
+	if !flags.NoStats {
+		options.Stats = &report.Stats{Files: len(files), Jobs: int(flags.run.jobs.Load()), Elapsed: time.Since(flags.run.start)}
+	}
+	return options
 }
 
-func checkWorkflow(workflow Workflow, checks []Check) []CheckResult {
-	var results []CheckResult
-
-	if workflow.Concurrency == nil {
-		check := findCheck(checks, "concurrency")
-		if check != nil {
-			results = append(results, CheckResult{
-				JobName:     "workflow",
-				Message:     check.Message,
-				Description: check.Detail,
-			})
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// shouldFail reports whether the run fails, when a finding has at least the
+// --fail-on severity or one of files scores below --min-score.
+func (flags *checkFlags) shouldFail(files []string, results []report.Result) bool {
+	if report.ShouldFail(results, flags.FailOn) {
+		return true
+	}
+	if flags.MinScore > 0 {
+		for _, score := range report.Scores(files, results) {
+			if score < flags.MinScore {
+				return true
+			}
 		}
 	}
+	return false
+}
+
+// validateOutput checks that the output flags can be used together.
+func (flags *checkFlags) validateOutput() error {
+	switch {
+	case flags.Summary && flags.Format != "table":
+		return fmt.Errorf("--summary can only be used with the table format")
+	case flags.MinScore < 0 || flags.MinScore > 100:
+		return fmt.Errorf("--min-score must be between 0 and 100")
+	case !slices.Contains([]string{"", "job", "check", "file"}, flags.GroupBy):
+		return fmt.Errorf("--group-by must be one of job, check or file")
+	case flags.GroupBy != "" && (flags.Format != "table" || flags.Summary):
+		return fmt.Errorf("--group-by can only be used with the table format, without --summary")
+	case flags.MaxFindings < 0:
+		return fmt.Errorf("--max-findings must not be negative")
+	}
+	return nil
+}
+
+// newFindings returns the results selected by --only, --exclude and
+// --min-severity that are not recorded in the --baseline file.
+func (flags *checkFlags) newFindings(results []report.Result) ([]report.Result, error) {
+	results = flags.selected(results)
+	if flags.Baseline == "" {
+		return results, nil
+	}
+	baseline, err := report.ReadBaseline(flags.Baseline)
+	if err != nil {
+		return nil, fmt.Errorf("reading baseline: %v", err)
+	}
+	return baseline.Filter(results), nil
+}
 
-	if workflow.Defaults == nil || workflow.Defaults.Run == nil || workflow.Defaults.Run.Shell == "" {
-		check := findCheck(checks, "default_shell")
-		if check != nil {
-			results = append(results, CheckResult{
-				JobName:     "workflow",
-				Message:     check.Message,
-				Description: check.Detail,
-			})
+// selected returns the results of the checks selected by --only and
+// --exclude, with at least the --min-severity.
+func (flags *checkFlags) selected(results []report.Result) []report.Result {
+	var filtered []report.Result
+	for _, result := range results {
+		if flags.selects(result.CheckID, result.Severity) {
+			filtered = append(filtered, result)
 		}
 	}
+	return filtered
+}
 
-	for jobName, job := range workflow.Jobs {
-		if runsOn, ok := job.RunsOn.(string); ok {
-			if strings.Contains(runsOn, "latest") {
-				check := findCheck(checks, "runner_version")
-				results = append(results, CheckResult{
-					JobName:     jobName,
-					Message:     fmt.Sprintf(check.Message, runsOn),
-					Description: check.Detail,
-				})
+// selects reports whether --only and --exclude select the check checkID,
+// and its severity is at least the --min-severity, if it has one. Check ids
+// are compared by their current id, so renamed checks can be given by their
+// former one.
+func (flags *checkFlags) selects(checkID, severity string) bool {
+	matches := func(ids []string) bool {
+		return slices.ContainsFunc(ids, func(id string) bool {
+			return checks.CanonicalID(strings.TrimSpace(id)) == checkID
+		})
+	}
+	if len(flags.Only) > 0 && !matches(flags.Only) || matches(flags.Exclude) {
+		return false
+	}
+	return severity == "" || report.SeverityRank(severity) >= report.SeverityRank(flags.MinSeverity)
+}
+
+// stdinPath is the path argument reading a workflow from stdin.
+const stdinPath = "-"
+
+// name returns the name to report for file: the --filename of a workflow
+// read from stdin, or the path itself.
+func (cmd *checkCmd) name(file string) string {
+	if file == stdinPath {
+		return cmp.Or(cmd.Filename, "<stdin>")
+	}
+	return file
+}
+
+// expandPaths returns the workflow files to check for each of paths, which
+// may be glob patterns, in order and without duplicates, followed by the
+// local reusable workflows they call.
+func expandPaths(paths []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if path == stdinPath {
+			if !seen[path] {
+				seen[path] = true
+				files = append(files, path)
 			}
-		} else if runsOnList, ok := job.RunsOn.([]interface{}); ok {
-			for _, runner := range runsOnList {
-				if runnerStr, ok := runner.(string); ok {
-					if strings.Contains(runnerStr, "latest") {
-						check := findCheck(checks, "runner_version")
-						results = append(results, CheckResult{
-							JobName:     jobName,
-							Message:     fmt.Sprintf(check.Message, runnerStr),
-							Description: check.Detail,
-						})
-					}
-				}
+			continue
+		}
+		matches := []string{path}
+		if isGlob(path) {
+			var err error
+			matches, err = filepath.Glob(path)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %v", path, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %s", path)
 			}
 		}
-
-		if job.TimeoutMinutes == nil {
-			hasStepTimeout := false
-			for _, step := range job.Steps {
-				if _, ok := step["timeout-minutes"]; ok {
-					hasStepTimeout = true
-					break
-				}
+		for _, match := range matches {
+			found, err := findWorkflowFiles(match)
+			if err != nil {
+				return nil, err
 			}
-
-			if !hasStepTimeout {
-				check := findCheck(checks, "timeout")
-				results = append(results, CheckResult{
-					JobName:     jobName,
-					Message:     check.Message,
-					Description: check.Detail,
-				})
+			for _, file := range found {
+				if !seen[absPath(file)] {
+					seen[absPath(file)] = true
+					files = append(files, file)
+				}
 			}
 		}
+	}
 
-		if job.Permissions == nil {
-			check := findCheck(checks, "permissions")
-			results = append(results, CheckResult{
-				JobName:     jobName,
-				Message:     check.Message,
-				Description: check.Detail,
-			})
-		} else {
-			perms := *job.Permissions
-			if perms["contents"] == "write-all" {
-				check := findCheck(checks, "unrestricted_permissions")
-				results = append(results, CheckResult{
-					JobName:     jobName,
-					Message:     check.Message,
-					Description: check.Detail,
-				})
+	for i := 0; i < len(files); i++ {
+		for _, called := range calledWorkflows(files[i]) {
+			if !seen[absPath(called)] {
+				seen[absPath(called)] = true
+				files = append(files, called)
 			}
 		}
+	}
+	return files, nil
+}
 
-		for _, step := range job.Steps {
-			if uses, ok := step["uses"].(string); ok {
-				parts := strings.Split(uses, "@")
-				if len(parts) == 2 {
-					ref := parts[1]
-					if !commitHashPattern.MatchString(ref) {
-						check := findCheck(checks, "action_ref")
-						results = append(results, CheckResult{
-							JobName:     jobName,
-							Message:     fmt.Sprintf(check.Message, uses),
-							Description: check.Detail,
-						})
-					}
-				}
+// calledWorkflows returns the existing local reusable workflows called by
+// the jobs of a workflow file. Files that can't be read or parsed have none,
+// leaving the error to be reported when they are checked.
+func calledWorkflows(file string) []string {
+	if file == stdinPath || workflow.IsActionFile(file) {
+		return nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	w, err := workflow.Parse(data)
+	if err != nil {
+		return nil
+	}
 
-				if uses == "aws-actions/configure-aws-credentials" || strings.HasPrefix(uses, "aws-actions/configure-aws-credentials@") {
-					if with, ok := step["with"].(map[string]interface{}); ok {
-						if _, hasAccessKeyID := with["aws-access-key-id"]; hasAccessKeyID {
-							check := findCheck(checks, "aws_credentials")
-							results = append(results, CheckResult{
-								JobName:     jobName,
-								Message:     check.Message,
-								Description: check.Detail,
-							})
-						}
-					}
-				}
-			}
+	// Paths are shown relative to the working directory when they are in it.
+	root := checks.FindRepoRoot(file)
+	wd, _ := os.Getwd()
+	var called []string
+	for _, jobName := range w.JobNames() {
+		uses := w.Jobs[jobName].Uses
+		if !strings.HasPrefix(uses, "./") {
+			continue
+		}
+		path := filepath.Join(root, uses)
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			called = append(called, path)
 		}
 	}
+	return called
+}
 
-	return results
+// isGlob reports whether path is a pattern rather than a file name.
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
 }
 
-func outputResults(results []CheckResult) {
-	if len(results) == 0 {
-		fmt.Println("No issues found!")
-		return
+// findWorkflowFiles returns the workflow files to check for path. A file is
+// returned as is; for a directory, the files under its .github/workflows
+// (or in it, if it is a .github/workflows directory) are returned, followed
+// by the action metadata files found anywhere in the directory. Other YAML
+// files, such as the checks config, are not workflows and are left out.
+func findWorkflowFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
 	}
 
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Job", "Message", "Description"})
-	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
-	table.SetCenterSeparator("|")
-	table.SetRowLine(true)
+	dir := filepath.Join(path, ".github", "workflows")
+	if abs, err := filepath.Abs(path); err == nil && filepath.Base(abs) == "workflows" && filepath.Base(filepath.Dir(abs)) == ".github" {
+		dir = path
+	}
 
-	for _, result := range results {
-		table.Append([]string{
-			result.JobName,
-			result.Message,
-			result.Description,
-		})
+	var files []string
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
 	}
+	sort.Strings(files)
 
-	table.Render()
+	actions, err := findActionFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, action := range actions {
+		if !slices.Contains(files, action) {
+			files = append(files, action)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no workflow files found in %s", dir)
+	}
+
+	return files, nil
+}
+
+// findActionFiles returns the action metadata files under dir, skipping
+// hidden directories other than .github, and node_modules.
+func findActionFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if path != dir && (strings.HasPrefix(name, ".") && name != ".github" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if workflow.IsActionFile(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeFiles creates files with empty contents under dir.
+func writeFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindWorkflowFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		// path is checked, relative to the directory of files.
+		path string
+		want []string
+		err  bool
+	}{
+		{
+			name:  "workflows and actions",
+			files: []string{".github/workflows/ci.yml", ".github/workflows/deploy.yaml", ".github/workflows/notes.txt", "action.yml", "actions/setup/action.yaml"},
+			path:  ".",
+			want:  []string{".github/workflows/ci.yml", ".github/workflows/deploy.yaml", "action.yml", "actions/setup/action.yaml"},
+		},
+		{
+			name:  "other YAML files",
+			files: []string{".github/workflows/ci.yml", ".goreleaser.yml", ".ghactionscheck.yaml", "docker-compose.yaml", "config/settings.yml"},
+			path:  ".",
+			want:  []string{".github/workflows/ci.yml"},
+		},
+		{
+			name:  "actions only",
+			files: []string{"action.yml", ".goreleaser.yml", ".ghactionscheck.yaml"},
+			path:  ".",
+			want:  []string{"action.yml"},
+		},
+		{
+			name:  "skipped directories",
+			files: []string{".github/workflows/ci.yml", ".hidden/action.yml", "node_modules/tool/action.yml", ".github/actions/lint/action.yml"},
+			path:  ".",
+			want:  []string{".github/workflows/ci.yml", ".github/actions/lint/action.yml"},
+		},
+		{
+			name:  "workflows directory",
+			files: []string{".github/workflows/ci.yml", ".github/workflows/deploy.yaml"},
+			path:  ".github/workflows",
+			want:  []string{".github/workflows/ci.yml", ".github/workflows/deploy.yaml"},
+		},
+		{
+			name:  "file",
+			files: []string{"ci.yml"},
+			path:  "ci.yml",
+			want:  []string{"ci.yml"},
+		},
+		{
+			name:  "no workflows",
+			files: []string{".goreleaser.yml", ".ghactionscheck.yaml"},
+			path:  ".",
+			err:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files...)
+
+			files, err := findWorkflowFiles(filepath.Join(dir, tt.path))
+			if tt.err {
+				if err == nil {
+					t.Errorf("findWorkflowFiles = %q, want an error", files)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, file := range files {
+				rel, err := filepath.Rel(dir, file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("findWorkflowFiles = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"ghactionscheck/pkg/checks"
+	"ghactionscheck/pkg/report"
+	"github.com/olekukonko/tablewriter"
+)
+
+type orgCmd struct {
+	Organization    string `arg:"" name:"organization" help:"GitHub organization to audit"`
+	Concurrency     int    `name:"concurrency" default:"8" help:"Number of repositories checked at once"`
+	IncludeArchived bool   `name:"include-archived" help:"Also check archived repositories"`
+	IncludeForks    bool   `name:"include-forks" help:"Also check forked repositories"`
+	checkFlags
+}
+
+// orgRepository is the outcome of checking a repository of an organization.
+type orgRepository struct {
+	Repository string `json:"repository"`
+	Workflows  int    `json:"workflows"`
+	Findings   int    `json:"findings"`
+	Errors     int    `json:"errors"`
+	Warnings   int    `json:"warnings"`
+	Notices    int    `json:"notices"`
+
+	files   []string
+	results []report.Result
+}
+
+// Run checks the workflows of every repository in the organization at its
+// default branch, and reports the repositories ranked by finding count. As
+// with remote, the checks config is the one for the current directory.
+func (cmd *orgCmd) Run() error {
+	if err := cmd.validateOutput(); err != nil {
+		return err
+	}
+	cmd.startRun()
+	checksConfig, err := loadConfig(".")
+	if err != nil {
+		return fmt.Errorf("loading checks config: %v", err)
+	}
+
+	github := githubClient()
+	repos, err := github.OrganizationRepositories(cmd.Organization)
+	if err != nil {
+		return fmt.Errorf("listing repositories: %v", err)
+	}
+	var selected []checks.Repository
+	for _, repo := range repos {
+		if (repo.Archived && !cmd.IncludeArchived) || (repo.Fork && !cmd.IncludeForks) {
+			continue
+		}
+		selected = append(selected, repo)
+	}
+
+	checked, _ := parallel(len(selected), max(cmd.Concurrency, 1), func(i int) (*orgRepository, error) {
+		repo := selected[i]
+		files, results, err := cmd.checkRepository(checksConfig, github, repo.FullName, repo.DefaultBranch)
+		if errors.Is(err, checks.ErrNoWorkflowFiles) {
+			return nil, nil
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not check %s: %v\n", repo.FullName, err)
+			return nil, nil
+		}
+		return newOrgRepository(repo.FullName, files, results), nil
+	})
+
+	var ranked []*orgRepository
+	for _, repo := range checked {
+		if repo != nil {
+			ranked = append(ranked, repo)
+		}
+	}
+	if len(ranked) == 0 {
+		return fmt.Errorf("no workflow files found in %s", cmd.Organization)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Findings != ranked[j].Findings {
+			return ranked[i].Findings > ranked[j].Findings
+		}
+		return ranked[i].Repository < ranked[j].Repository
+	})
+
+	var files []string
+	var results []report.Result
+	for _, repo := range ranked {
+		files = append(files, repo.files...)
+		results = append(results, repo.results...)
+	}
+	err = cmd.writeReport(func(out io.Writer) error {
+		return writeOrgReport(out, cmd.Format, cmd.reportOptions(files), ranked, files, results)
+	})
+	if err != nil {
+		return fmt.Errorf("writing results: %v", err)
+	}
+	cmd.writeStepSummary(files, results)
+	if cmd.shouldFail(files, results) {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func newOrgRepository(name string, files []string, results []report.Result) *orgRepository {
+	repo := &orgRepository{
+		Repository: name,
+		Workflows:  len(files),
+		Findings:   len(results),
+		files:      files,
+		results:    results,
+	}
+	for _, result := range results {
+		switch result.Severity {
+		case report.SeverityError:
+			repo.Errors++
+		case report.SeverityWarning:
+			repo.Warnings++
+		default:
+			repo.Notices++
+		}
+	}
+	return repo
+}
+
+// writeOrgReport writes the findings of all repositories followed by their
+// ranking as a table, or both in one JSON object. The other formats hold the
+// findings only. The statistics of the run, if any, end the report.
+func writeOrgReport(out io.Writer, format string, options report.Options, ranked []*orgRepository, files []string, results []report.Result) error {
+	stats := options.Stats
+	if format == "json" || format == "table" {
+		// Written after the repositories.
+		options.Stats = nil
+	}
+	if format == "json" {
+		if results == nil {
+			results = []report.Result{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(struct {
+			Repositories []*orgRepository   `json:"repositories"`
+			Findings     []report.Result    `json:"findings"`
+			Stats        *report.Statistics `json:"stats,omitempty"`
+		}{ranked, results, report.NewStatistics(stats, results)})
+	}
+
+	if err := report.Write(out, format, files, results, options); err != nil {
+		return err
+	}
+	if format != "table" {
+		return nil
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Repositories by finding count")
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"Repository", "Workflows", "Findings", "Errors", "Warnings", "Notices"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetCenterSeparator("|")
+	for _, repo := range ranked {
+		table.Append([]string{
+			repo.Repository,
+			strconv.Itoa(repo.Workflows),
+			strconv.Itoa(repo.Findings),
+			strconv.Itoa(repo.Errors),
+			strconv.Itoa(repo.Warnings),
+			strconv.Itoa(repo.Notices),
+		})
+	}
+	table.Render()
+	report.WriteStats(out, stats, results)
+	return nil
+}
@@ -0,0 +1,118 @@
+package checks
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"ghactionscheck/pkg/report"
+	"ghactionscheck/pkg/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+// actionJobName is the job reported for findings in action metadata files.
+const actionJobName = "action"
+
+// checkAction checks the metadata file of an action: its runtime, the
+// descriptions of its inputs and, for a composite action, the checks of
+// workflow steps that apply to its steps.
+func (c *Checker) checkAction(file string, data []byte) ([]report.Result, error) {
+	action, err := workflow.ParseAction(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing YAML: %v", err)
+	}
+	r := &reporter{Checker: c}
+
+	_, runs := workflow.LookupKey(action.Node, "runs")
+	if _, using := workflow.LookupKey(runs, "using"); using != nil && deprecatedRuntimes[action.Runs.Using] {
+		r.report("deprecated_runtime", actionJobName, using, field{"Uses", file}, field{"Runtime", action.Runs.Using})
+	}
+
+	_, inputs := workflow.LookupKey(action.Node, "inputs")
+	names := make([]string, 0, len(action.Inputs))
+	for name := range action.Inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if action.Inputs[name].Description == "" {
+			key, _ := workflow.LookupKey(inputs, name)
+			r.report("action_input_description", actionJobName, key, field{"Input", name})
+		}
+	}
+
+	if action.IsComposite() {
+		checkComposite(r, action)
+	}
+	checkActionKeys(r, action)
+	checkActionSchema(r, action)
+	checkActionExpressions(r, action)
+
+	for _, step := range action.Runs.Steps {
+		checkActionRef(r, actionJobName, step)
+		checkScriptInjection(r, actionJobName, step)
+		checkDeprecatedCommands(r, actionJobName, step)
+		checkRemoteScripts(r, actionJobName, step)
+		checkDeprecatedRuntime(r, file, actionJobName, step)
+		if step.Uses != "" {
+			_, uses := workflow.LookupKey(step.Node, "uses")
+			checkActionPolicy(r, actionJobName, step.Uses, uses)
+		}
+		if r.github != nil {
+			checkOutdatedAction(r, actionJobName, step)
+			checkActionRepository(r, actionJobName, step)
+		}
+	}
+
+	setSteps(r.results, actionJobName, action.Runs.Steps)
+	r.formatMessages(file)
+	return filterSuppressed(r.results, findSuppressions(action.Document)), nil
+}
+
+// stepOutputPattern matches references to the steps context, capturing the
+// step id.
+var stepOutputPattern = regexp.MustCompile(`\bsteps\.([\w-]+)`)
+
+// checkComposite runs the checks specific to composite actions: branding
+// for the Marketplace, outputs set from steps that don't exist, and run
+// steps without the shell GitHub requires in composite actions.
+func checkComposite(r *reporter, action *workflow.Action) {
+	if action.Branding == nil {
+		r.report("composite_branding", actionJobName, action.Node)
+	}
+
+	ids := make(map[string]bool)
+	for _, step := range action.Runs.Steps {
+		if step.ID != "" {
+			ids[step.ID] = true
+		}
+	}
+	_, outputs := workflow.LookupKey(action.Node, "outputs")
+	for i := 0; outputs != nil && outputs.Kind == yaml.MappingNode && i+1 < len(outputs.Content); i += 2 {
+		name := outputs.Content[i].Value
+		_, value := workflow.LookupKey(outputs.Content[i+1], "value")
+		if value == nil {
+			continue
+		}
+		for _, expr := range expressionPattern.FindAllStringSubmatch(value.Value, -1) {
+			for _, ref := range stepOutputPattern.FindAllStringSubmatch(expr[1], -1) {
+				if !ids[ref[1]] {
+					r.report("composite_output_step", actionJobName, value, field{"Output", name}, field{"Step", ref[1]})
+				}
+			}
+		}
+	}
+
+	for _, step := range action.Runs.Steps {
+		key, script := workflow.LookupKey(step.Node, "run")
+		if _, shell := workflow.LookupKey(step.Node, "shell"); script == nil || shell != nil {
+			continue
+		}
+		label := step.Name
+		if label == "" {
+			label, _, _ = strings.Cut(strings.TrimSpace(script.Value), "\n")
+		}
+		r.report("composite_shell", actionJobName, key, field{"Label", label})
+	}
+}
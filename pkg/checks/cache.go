@@ -0,0 +1,137 @@
+package checks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The times API responses are used from the cache before being revalidated.
+// Responses for a commit hash can't change, so they are kept much longer.
+const (
+	cacheTTL          = time.Hour
+	immutableCacheTTL = 30 * 24 * time.Hour
+)
+
+// CacheDir returns the directory for the files ghactionscheck caches
+// between runs: ghactionscheck in $XDG_CACHE_HOME (or ~/.cache), or the
+// platform's equivalent. It isn't created.
+func CacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ghactionscheck"), nil
+}
+
+// SetCacheDir caches the responses of the lookups of the online checks in
+// dir, so that later runs make fewer requests: tags and their commits,
+// releases, repositories and action metadata. A cached response is used
+// for a while, then revalidated with its ETag, which doesn't count against
+// the rate limit when it hasn't changed. The workflow files of remote
+// repositories are revalidated on each run. The responses are cached by
+// token, as they depend on what it can see.
+func (c *GitHubClient) SetCacheDir(dir string) {
+	c.cacheDir = dir
+}
+
+// cacheEntry is a cached API response. NotFound records a 404 response,
+// which has no body.
+type cacheEntry struct {
+	ETag     string          `json:"etag,omitempty"`
+	Fetched  time.Time       `json:"fetched"`
+	NotFound bool            `json:"not_found,omitempty"`
+	Body     json.RawMessage `json:"body,omitempty"`
+}
+
+// cachedGet is get for responses that can be cached for ttl, or only
+// revalidated when it is zero. Failing to read or write the cache only falls
+// back to requesting the API.
+func (c *GitHubClient) cachedGet(path string, ttl time.Duration, v interface{}) error {
+	if c.cacheDir == "" {
+		return c.get(path, v)
+	}
+	sum := sha256.Sum256([]byte(c.token + "\x00" + c.baseURL + path))
+	file := filepath.Join(c.cacheDir, hex.EncodeToString(sum[:])+".json")
+
+	var cached cacheEntry
+	if data, err := os.ReadFile(file); err != nil || json.Unmarshal(data, &cached) != nil {
+		cached = cacheEntry{}
+	}
+	entry := cached
+	if cached.Fetched.IsZero() || time.Since(cached.Fetched) >= ttl {
+		var err error
+		if entry, err = c.revalidate(path, cached); err != nil {
+			return err
+		}
+		writeCacheEntry(file, entry)
+	}
+
+	if entry.NotFound {
+		return errNotFound
+	}
+	return json.Unmarshal(entry.Body, v)
+}
+
+// revalidate requests path, with the ETag of cached so that an unchanged
+// response isn't sent again, and returns the entry to cache.
+func (c *GitHubClient) revalidate(path string, cached cacheEntry) (cacheEntry, error) {
+	req, err := c.newRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	if cached.ETag != "" && !cached.NotFound {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached.ETag != "":
+		cached.Fetched = time.Now()
+		return cached, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return cacheEntry{Fetched: time.Now(), NotFound: true}, nil
+	case resp.StatusCode != http.StatusOK:
+		return cacheEntry{}, httpError(http.MethodGet, path, resp)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	return cacheEntry{ETag: resp.Header.Get("ETag"), Fetched: time.Now(), Body: body}, nil
+}
+
+// writeCacheEntry writes entry to file through a temporary file, so that
+// concurrent runs never read a partial entry.
+func writeCacheEntry(file string, entry cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
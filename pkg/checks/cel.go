@@ -0,0 +1,168 @@
+package checks
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"ghactionscheck/pkg/workflow"
+	"github.com/google/cel-go/cel"
+	"gopkg.in/yaml.v3"
+)
+
+// celScope is what a CEL rule is evaluated for: once per workflow, job or
+// step.
+type celScope int
+
+const (
+	celWorkflowScope celScope = iota
+	celJobScope
+	celStepScope
+)
+
+// celKeys are the keys of workflows, jobs and steps, which are null in CEL
+// rules when unset so that conditions like "job.timeout_minutes == null"
+// work without has().
+var celKeys = map[celScope][]string{
+	celWorkflowScope: {"name", "run-name", "on", "permissions", "env", "defaults", "concurrency", "jobs"},
+	celJobScope: {"name", "needs", "permissions", "if", "runs-on", "environment", "concurrency", "outputs",
+		"env", "defaults", "steps", "timeout-minutes", "strategy", "continue-on-error", "container",
+		"services", "uses", "with", "secrets"},
+	celStepScope: {"id", "if", "name", "uses", "run", "working-directory", "shell", "with", "env",
+		"continue-on-error", "timeout-minutes"},
+}
+
+var celEnv *cel.Env
+
+func init() {
+	var err error
+	celEnv, err = cel.NewEnv(
+		cel.Variable("workflow", cel.DynType),
+		cel.Variable("job", cel.DynType),
+		cel.Variable("step", cel.DynType),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// compileExpr compiles the CEL condition of a rule, which is evaluated for
+// each step when it refers to step, for each job when it refers to job, and
+// once per workflow otherwise.
+func (r *Rule) compileExpr() error {
+	ast, issues := celEnv.Compile(r.Expr)
+	if issues.Err() != nil {
+		return fmt.Errorf("invalid rule expr: %v", issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return fmt.Errorf("rule expr must be a bool, not %v", ast.OutputType())
+	}
+	program, err := celEnv.Program(ast)
+	if err != nil {
+		return fmt.Errorf("invalid rule expr: %v", err)
+	}
+	r.program = program
+
+	for _, ref := range ast.NativeRep().ReferenceMap() {
+		switch ref.Name {
+		case "step":
+			r.scope = celStepScope
+		case "job":
+			r.scope = max(r.scope, celJobScope)
+		}
+	}
+	return nil
+}
+
+// checkExprRule reports the workflows, jobs or steps for which the CEL
+// condition of check is true.
+func checkExprRule(r *reporter, w *workflow.Workflow, check Check) {
+	vars := map[string]interface{}{
+		"workflow": celObject(w.Node, celWorkflowScope),
+		"job":      nil,
+		"step":     nil,
+	}
+	if check.Rule.scope == celWorkflowScope {
+		evalExprRule(r, check, vars, "workflow", w.Node)
+		return
+	}
+
+	for _, jobName := range w.JobNames() {
+		job := w.Jobs[jobName]
+		vars["job"] = celObject(job.Node, celJobScope)
+		if check.Rule.scope == celJobScope {
+			evalExprRule(r, check, vars, jobName, job.Key)
+			continue
+		}
+		for _, step := range job.Steps {
+			vars["step"] = celObject(step.Node, celStepScope)
+			evalExprRule(r, check, vars, jobName, step.Node)
+		}
+	}
+}
+
+func evalExprRule(r *reporter, check Check, vars map[string]interface{}, jobName string, node *yaml.Node) {
+	out, _, err := check.Rule.program.Eval(vars)
+	if err != nil {
+		r.warnOnce(check.ID, fmt.Sprintf("Warning: evaluating rule %s at line %d: %v", check.ID, node.Line, err))
+		return
+	}
+	if matched, ok := out.Value().(bool); ok && matched {
+		r.report(check.ID, jobName, node)
+	}
+}
+
+// celObject converts a mapping of a workflow to a CEL value, with the keys
+// of scope set to null when unset. Dashes in keys are replaced with
+// underscores, so keys can be selected as fields.
+func celObject(node *yaml.Node, scope celScope) map[string]interface{} {
+	var value interface{}
+	if node != nil {
+		if err := node.Decode(&value); err != nil {
+			value = nil
+		}
+	}
+	object, _ := celValue(value).(map[string]interface{})
+	if object == nil {
+		object = make(map[string]interface{})
+	}
+	for _, key := range celKeys[scope] {
+		key = strings.ReplaceAll(key, "-", "_")
+		if _, ok := object[key]; !ok {
+			object[key] = nil
+		}
+	}
+	return object
+}
+
+func celValue(value interface{}) interface{} {
+	switch value := jsonValue(value).(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, v := range value {
+			converted[strings.ReplaceAll(key, "-", "_")] = celValue(v)
+		}
+		return converted
+	case []interface{}:
+		for i, v := range value {
+			value[i] = celValue(v)
+		}
+		return value
+	case int:
+		return int64(value)
+	default:
+		return value
+	}
+}
+
+// warnOnce prints a warning to stderr unless one was already printed for
+// the key.
+func (r *reporter) warnOnce(key, warning string) {
+	if r.warned == nil {
+		r.warned = make(map[string]bool)
+	}
+	if !r.warned[key] {
+		r.warned[key] = true
+		fmt.Fprintln(os.Stderr, warning)
+	}
+}
@@ -0,0 +1,206 @@
+// Package checks checks GitHub Actions workflows against the checks of a
+// config, along with its custom rules, plugins and Rego policies.
+package checks
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"ghactionscheck/pkg/report"
+	"ghactionscheck/pkg/workflow"
+)
+
+// Checker runs the enabled checks against workflow files. A Checker can be
+// used from several goroutines.
+type Checker struct {
+	checks  []Check
+	policy  Policy
+	plugins []Plugin
+	ignore  []Ignore
+
+	// github is used by online checks, and is nil when running offline.
+	github *GitHubClient
+	// wasm runs WASM plugins, and is created when the first one runs. The
+	// runs are serialized by wasmMu, as its compiled modules are cached.
+	wasm   *wasmRuntime
+	wasmMu sync.Mutex
+	// rego holds the Rego policies, if any.
+	rego *regoPolicy
+	// remote is the repository of the workflows when they are checked
+	// without a clone, and is nil for local files.
+	remote *remoteRepository
+	// schemas validates the documents when enabled, and is nil otherwise.
+	schemas *schemas
+	// host is the host of the repositories, github.com when empty.
+	host string
+	// jobs counts the jobs of the workflows checked.
+	jobs atomic.Int64
+}
+
+// remoteRepository is a repository whose workflows are fetched with github,
+// along with the local actions they use.
+type remoteRepository struct {
+	github    *GitHubClient
+	repo, ref string
+}
+
+// Option configures a Checker.
+type Option func(*Checker) error
+
+// WithGitHub enables the online checks, which query the GitHub API with
+// client.
+func WithGitHub(client *GitHubClient) Option {
+	return func(c *Checker) error {
+		c.github = client
+		return nil
+	}
+}
+
+// WithRegoPolicies evaluates the Rego policies in the files and directories
+// at paths against each workflow.
+func WithRegoPolicies(paths ...string) Option {
+	return func(c *Checker) error {
+		policy, err := loadRegoPolicy(paths)
+		if err != nil {
+			return fmt.Errorf("loading policies: %v", err)
+		}
+		c.rego = policy
+		return nil
+	}
+}
+
+// WithRemoteRepository checks workflows fetched from repo ("owner/name") at
+// ref, resolving the local actions they use with client instead of reading
+// them from disk.
+func WithRemoteRepository(client *GitHubClient, repo, ref string) Option {
+	return func(c *Checker) error {
+		c.remote = &remoteRepository{github: client, repo: repo, ref: ref}
+		return nil
+	}
+}
+
+// New returns a Checker running the checks of config.
+func New(config *Config, options ...Option) (*Checker, error) {
+	c := &Checker{checks: config.Checks, policy: config.Policy, plugins: config.Plugins, ignore: config.Ignore}
+	for _, option := range options {
+		if err := option(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Jobs returns the number of jobs of the workflows checked so far.
+func (c *Checker) Jobs() int {
+	return int(c.jobs.Load())
+}
+
+// EnabledChecks returns the checks that run, in the order of the config,
+// followed by the plugins as checks with their names and severities, for the
+// reports listing the checks that pass.
+func (c *Checker) EnabledChecks() []Check {
+	var enabled []Check
+	for _, check := range c.checks {
+		if check.Enabled == nil || *check.Enabled {
+			enabled = append(enabled, check)
+		}
+	}
+	for _, plugin := range c.plugins {
+		if plugin.Enabled == nil || *plugin.Enabled {
+			enabled = append(enabled, Check{ID: plugin.Name, Severity: plugin.Severity})
+		}
+	}
+	return enabled
+}
+
+// CheckFile checks a workflow file.
+func (c *Checker) CheckFile(file string) ([]report.Result, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+	return c.Check(file, data)
+}
+
+// Check checks the contents of a workflow file, or of an action metadata
+// file when file is named like one. The results are sorted by position, and
+// exclude the findings suppressed by comments or ignored by the config.
+func (c *Checker) Check(file string, data []byte) ([]report.Result, error) {
+	if workflow.IsActionFile(file) {
+		results, err := c.checkAction(file, data)
+		if err != nil {
+			return nil, err
+		}
+		return finishResults(file, c.filterIgnored(file, results)), nil
+	}
+
+	w, err := workflow.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing YAML: %v", err)
+	}
+	w.File = file
+	c.jobs.Add(int64(len(w.Jobs)))
+
+	pluginResults, err := c.runPlugins(w, data)
+	if err != nil {
+		return nil, err
+	}
+	results := append(checkWorkflow(w, c), pluginResults...)
+	if c.rego != nil {
+		policyResults, err := c.rego.evaluate(w)
+		if err != nil {
+			return nil, fmt.Errorf("evaluating policies: %v", err)
+		}
+		results = append(results, policyResults...)
+	}
+	results = c.filterIgnored(file, filterSuppressed(results, findSuppressions(w.Document)))
+	for _, jobName := range w.JobNames() {
+		setSteps(results, jobName, w.Jobs[jobName].Steps)
+	}
+	return finishResults(file, results), nil
+}
+
+// setSteps sets the step of the results of job jobName positioned in one of
+// its steps.
+func setSteps(results []report.Result, jobName string, steps []workflow.Step) {
+	for i := range results {
+		if results[i].JobName != jobName {
+			continue
+		}
+		for j, step := range steps {
+			if step.Node != nil && step.Node.Line <= results[i].Line && results[i].Line <= lastLine(step.Node) {
+				results[i].Step = stepLabel(step, j)
+				break
+			}
+		}
+	}
+}
+
+// stepLabel names the step at index i by its name or id, or by its index
+// when it has neither.
+func stepLabel(step workflow.Step, i int) string {
+	switch {
+	case step.Name != "":
+		return step.Name
+	case step.ID != "":
+		return step.ID
+	}
+	return fmt.Sprintf("steps[%d]", i)
+}
+
+// finishResults sets the file of results and sorts them by position.
+func finishResults(file string, results []report.Result) []report.Result {
+	for i := range results {
+		results[i].File = file
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Line != results[j].Line {
+			return results[i].Line < results[j].Line
+		}
+		return results[i].Column < results[j].Column
+	})
+	return results
+}
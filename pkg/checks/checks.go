@@ -0,0 +1,1184 @@
+package checks
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ghactionscheck/pkg/report"
+	"ghactionscheck/pkg/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+var commitHashPattern = regexp.MustCompile(`^[0-9a-f]{40}([0-9a-f]{24})?$`)
+
+// IsCommitHash reports whether ref is a full commit hash.
+func IsCommitHash(ref string) bool {
+	return commitHashPattern.MatchString(ref)
+}
+
+// expressionPattern matches ${{ }} expressions.
+var expressionPattern = regexp.MustCompile(`\$\{\{(.*?)\}\}`)
+
+// untrustedContextPattern matches contexts an attacker can control through
+// issues, pull requests, comments or commits.
+var untrustedContextPattern = regexp.MustCompile(`github\.head_ref|github\.event\.(` +
+	`(issue|pull_request|discussion)\.(title|body)|` +
+	`(comment|review|review_comment)\.body|` +
+	`pages(\.\*|\[\d+\])\.page_name|` +
+	`(commits(\.\*|\[\d+\])|head_commit)\.(message|author\.(email|name))|` +
+	`pull_request\.head\.(ref|label|repo\.default_branch)|` +
+	`workflow_run\.(head_branch|head_commit\.(message|author\.(email|name))))`)
+
+// pullRequestHeadPattern matches contexts referring to the head of a pull
+// request, i.e. code controlled by the pull request author.
+var pullRequestHeadPattern = regexp.MustCompile(`github\.event\.pull_request\.head\.(sha|ref)|github\.head_ref`)
+
+// deprecatedCommandPattern matches the workflow commands GitHub disabled in
+// favor of environment files.
+var deprecatedCommandPattern = regexp.MustCompile(`::(set-output|save-state|set-env|add-path)\b`)
+
+// deprecatedCommandReplacements maps each deprecated workflow command to the
+// environment file replacing it.
+var deprecatedCommandReplacements = map[string]string{
+	"set-output": "$GITHUB_OUTPUT",
+	"save-state": "$GITHUB_STATE",
+	"set-env":    "$GITHUB_ENV",
+	"add-path":   "$GITHUB_PATH",
+}
+
+// secretsPattern matches references to the secrets context.
+var secretsPattern = regexp.MustCompile(`\bsecrets(\.[\w-]+|\[)`)
+
+// gitPushPattern matches run scripts pushing to a git remote.
+var gitPushPattern = regexp.MustCompile(`\bgit\s+push\b`)
+
+// pushActions are actions that push with the credentials persisted by
+// actions/checkout.
+var pushActions = []string{
+	"stefanzweifel/git-auto-commit-action",
+	"peter-evans/create-pull-request",
+	"ad-m/github-push-action",
+	"EndBug/add-and-commit",
+}
+
+// remoteScriptPatterns match run scripts executing a downloaded script
+// directly, e.g. "curl ... | bash" or "bash <(curl ...)".
+var remoteScriptPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(curl|wget)\b[^|;&\n]*\|\s*(sudo\s+(-\S+\s+)*)?(ba|z|k|da)?sh\b`),
+	regexp.MustCompile(`\b(ba|z|k|da)?sh\s+(-\S+\s+)*<\(\s*(curl|wget)\b[^)\n]*\)`),
+	regexp.MustCompile(`\b(ba|z|k|da)?sh\s+-c\s+["']?\$\(\s*(curl|wget)\b[^)\n]*\)`),
+}
+
+// dynamicCacheKeyPattern matches cache key expressions that change with the
+// cached content or the run.
+var dynamicCacheKeyPattern = regexp.MustCompile(`hashFiles\(|github\.(sha|run_id|run_number)`)
+
+// cloudCredentialInputs lists the inputs of cloud login actions that take
+// long-lived credentials, with the OIDC alternative of each provider.
+var cloudCredentialInputs = []struct {
+	action, input, provider, alternative string
+}{
+	{"aws-actions/configure-aws-credentials", "aws-access-key-id", "AWS", "role-to-assume with OIDC"},
+	{"azure/login", "creds", "Azure", "OIDC with client-id, tenant-id and subscription-id"},
+	{"google-github-actions/auth", "credentials_json", "Google Cloud", "workload_identity_provider"},
+}
+
+// reporter collects findings for the enabled checks.
+type reporter struct {
+	*Checker
+	results []report.Result
+	// messages holds the checks and fields of the results, to format their
+	// messages with.
+	messages []pendingMessage
+
+	regexps map[string][]namedRegexp
+	warned  map[string]bool
+	// unknownKeys holds the keys reported by the unknown_key check.
+	unknownKeys map[*yaml.Node]bool
+	// reported holds the findings reported at each node, as nodes resolved
+	// from an alias are shared and visited once for each use.
+	reported map[reportedKey]bool
+}
+
+// reportedKey identifies a finding of a check at a node with its fields.
+type reportedKey struct {
+	id     string
+	node   *yaml.Node
+	fields string
+}
+
+// namedRegexp is a compiled pattern of a check option.
+type namedRegexp struct {
+	name string
+	*regexp.Regexp
+}
+
+// regexpsOption returns the compiled regular expressions of a check's
+// option, which is either a list of expressions or a mapping of names to
+// expressions, caching them for the workflow. Expressions in a list are
+// named after themselves. Invalid expressions are reported to stderr and
+// skipped.
+func (r *reporter) regexpsOption(check *Check, name string) []namedRegexp {
+	key := check.ID + "." + name
+	if patterns, ok := r.regexps[key]; ok {
+		return patterns
+	}
+
+	exprs := make(map[string]string)
+	var names []string
+	if mapping, ok := check.Options[name].(map[string]interface{}); ok {
+		for patternName, expr := range mapping {
+			if s, ok := expr.(string); ok {
+				exprs[patternName] = s
+				names = append(names, patternName)
+			}
+		}
+		sort.Strings(names)
+	} else {
+		for _, expr := range check.StringsOption(name) {
+			exprs[expr] = expr
+			names = append(names, expr)
+		}
+	}
+
+	var patterns []namedRegexp
+	for _, patternName := range names {
+		pattern, err := regexp.Compile(exprs[patternName])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: invalid %s pattern in check %s: %v\n", name, check.ID, err)
+			continue
+		}
+		patterns = append(patterns, namedRegexp{patternName, pattern})
+	}
+	if r.regexps == nil {
+		r.regexps = make(map[string][]namedRegexp)
+	}
+	r.regexps[key] = patterns
+	return patterns
+}
+
+// check returns the enabled check with id, or nil.
+func (r *reporter) check(id string) *Check {
+	return findCheck(r.checks, id)
+}
+
+// report adds a finding for check id at node, with the fields its message
+// can refer to. A finding already reported at node is skipped, so a shared
+// node is reported once, for the job it is first found in.
+func (r *reporter) report(id, jobName string, node *yaml.Node, fields ...field) {
+	check := findCheck(r.checks, id)
+	if check == nil {
+		return
+	}
+	if node != nil {
+		key := reportedKey{id, node, fmt.Sprint(fields)}
+		if r.reported[key] {
+			return
+		}
+		if r.reported == nil {
+			r.reported = make(map[reportedKey]bool)
+		}
+		r.reported[key] = true
+	}
+	result := report.Result{
+		CheckID:     check.ID,
+		JobName:     jobName,
+		Description: check.Detail,
+		URL:         check.URL,
+		Severity:    check.Severity,
+		Category:    check.Category,
+	}
+	if node != nil {
+		result.Line, result.Column = node.Line, node.Column
+	}
+	r.results = append(r.results, result)
+	r.messages = append(r.messages, pendingMessage{check, fields})
+}
+
+func checkWorkflow(w *workflow.Workflow, c *Checker) []report.Result {
+	r := &reporter{Checker: c}
+
+	if w.Concurrency == nil {
+		r.report("concurrency", "workflow", w.Node)
+	} else {
+		checkCancelInProgress(r, w)
+	}
+
+	if w.Defaults == nil || w.Defaults.Run == nil || w.Defaults.Run.Shell == "" {
+		r.report("default_shell", "workflow", w.Node)
+	}
+
+	if w.Permissions == nil {
+		r.report("workflow_permissions", "workflow", w.Node)
+	} else {
+		for _, node := range w.Permissions.WriteAll() {
+			r.report("unrestricted_permissions", "workflow", node)
+		}
+	}
+
+	_, jobs := workflow.LookupKey(w.Node, "jobs")
+	for _, duplicate := range w.Duplicates {
+		jobName := "workflow"
+		if duplicate.Parent == jobs {
+			jobName = duplicate.Key.Value
+		}
+		r.report("duplicate_key", jobName, duplicate.Key, field{"Key", duplicate.Key.Value}, field{"FirstLine", duplicate.First.Line})
+	}
+
+	_, env := workflow.LookupKey(w.Node, "env")
+	checkSecretsInEnv(r, "workflow", "workflow", env)
+	checkHardcodedCredentials(r, w)
+	checkSchedules(r, w)
+	checkDispatchInputs(r, w)
+	checkNeeds(r, w)
+	checkOutputs(r, w)
+	checkInputs(r, w)
+	checkUndefinedSecrets(r, w)
+	checkUndefinedEnv(r, w)
+	checkUnknownKeys(r, w)
+	checkWorkflowSchema(r, w)
+	checkWorkflowExpressions(r, w)
+
+	for _, jobName := range w.JobNames() {
+		job := w.Jobs[jobName]
+
+		_, env := workflow.LookupKey(job.Node, "env")
+		checkSecretsInEnv(r, jobName, "job", env)
+
+		_, runsOn := workflow.LookupKey(job.Node, "runs-on")
+		labels := runnerLabels(job, runsOn)
+		for _, label := range labels {
+			if strings.Contains(label.value, "latest") && !c.enterpriseServer() {
+				r.report("runner_version", jobName, label.node, field{"Label", label.value})
+			}
+		}
+		checkSelfHostedRunner(r, w, jobName, labels)
+
+		if job.Uses != "" {
+			checkReusableWorkflowRef(r, jobName, job)
+			checkReusableWorkflowCall(r, w, jobName, job)
+			if _, secrets := workflow.LookupKey(job.Node, "secrets"); secrets != nil && secrets.Value == "inherit" {
+				r.report("secrets_inherit", jobName, secrets, field{"Uses", job.Uses})
+			}
+			_, uses := workflow.LookupKey(job.Node, "uses")
+			checkActionPolicy(r, jobName, job.Uses, uses)
+		}
+
+		// Jobs calling reusable workflows can't set timeout-minutes.
+		if job.TimeoutMinutes == nil && job.Uses == "" {
+			hasStepTimeout := false
+			for _, step := range job.Steps {
+				if step.TimeoutMinutes != nil {
+					hasStepTimeout = true
+					break
+				}
+			}
+
+			if !hasStepTimeout {
+				r.report("timeout", jobName, job.Key)
+			}
+		}
+
+		if job.Permissions == nil {
+			if w.Permissions == nil {
+				r.report("permissions", jobName, job.Key)
+			}
+		} else {
+			for _, node := range job.Permissions.WriteAll() {
+				r.report("unrestricted_permissions", jobName, node)
+			}
+		}
+
+		checkPersistedCredentials(r, jobName, job)
+		checkContinueOnError(r, jobName, job)
+		checkImageDigests(r, jobName, job)
+		checkMatrix(r, jobName, job)
+		checkDuplicateStepIDs(r, jobName, job)
+		checkDeploymentEnvironment(r, jobName, job)
+
+		for _, step := range job.Steps {
+			checkActionRef(r, jobName, step)
+			checkCloudCredentials(r, jobName, step)
+
+			checkScriptInjection(r, jobName, step)
+			checkDeprecatedCommands(r, jobName, step)
+			checkRemoteScripts(r, jobName, step)
+			checkCache(r, w, jobName, step)
+			checkArtifactRetention(r, jobName, step)
+			checkStepName(r, jobName, step)
+			checkAlwaysOnDeploy(r, jobName, step)
+			checkDeprecatedRuntime(r, w.File, jobName, step)
+			if step.Uses != "" {
+				_, uses := workflow.LookupKey(step.Node, "uses")
+				checkActionPolicy(r, jobName, step.Uses, uses)
+			}
+			if r.github != nil {
+				checkOutdatedAction(r, jobName, step)
+				checkActionRepository(r, jobName, step)
+			}
+			if w.On.Has("pull_request_target") {
+				checkPullRequestTargetCheckout(r, jobName, step)
+			}
+		}
+	}
+
+	checkRules(r, w)
+
+	for _, jobName := range w.JobNames() {
+		setSteps(r.results, jobName, w.Jobs[jobName].Steps)
+	}
+	r.formatMessages(w.File)
+	return r.results
+}
+
+// checkActionRef reports steps using an action by a tag or branch instead
+// of a commit hash.
+func checkActionRef(r *reporter, jobName string, step workflow.Step) {
+	if step.Uses == "" || strings.HasPrefix(step.Uses, "docker://") {
+		return
+	}
+	_, uses := workflow.LookupKey(step.Node, "uses")
+	parts := strings.Split(step.Uses, "@")
+	if len(parts) == 2 {
+		ref := parts[1]
+		if !commitHashPattern.MatchString(ref) {
+			r.report("action_ref", jobName, uses, field{"Uses", step.Uses})
+		}
+	}
+}
+
+// checkCloudCredentials reports cloud login actions configured with
+// long-lived credentials instead of OIDC.
+func checkCloudCredentials(r *reporter, jobName string, step workflow.Step) {
+	for _, c := range cloudCredentialInputs {
+		if !usesAction(step.Uses, c.action) {
+			continue
+		}
+		if input, _ := step.Input(c.input); input != nil {
+			r.report("cloud_credentials", jobName, input, field{"Provider", c.provider}, field{"Alternative", c.alternative})
+		}
+	}
+}
+
+// checkScriptInjection reports untrusted contexts interpolated directly into
+// run scripts and actions/github-script scripts, where they are evaluated
+// before the script runs and can inject arbitrary code.
+func checkScriptInjection(r *reporter, jobName string, step workflow.Step) {
+	_, script := workflow.LookupKey(step.Node, "run")
+	if script == nil && usesAction(step.Uses, "actions/github-script") {
+		_, script = step.Input("script")
+	}
+	if script == nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, match := range expressionPattern.FindAllStringSubmatch(script.Value, -1) {
+		for _, context := range untrustedContextPattern.FindAllString(match[1], -1) {
+			if !seen[context] {
+				seen[context] = true
+				r.report("script_injection", jobName, script, field{"Expression", context})
+			}
+		}
+	}
+}
+
+// checkDeprecatedCommands reports deprecated workflow commands in run
+// scripts.
+func checkDeprecatedCommands(r *reporter, jobName string, step workflow.Step) {
+	_, script := workflow.LookupKey(step.Node, "run")
+	if script == nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, match := range deprecatedCommandPattern.FindAllStringSubmatch(script.Value, -1) {
+		command := match[1]
+		if !seen[command] {
+			seen[command] = true
+			r.report("deprecated_commands", jobName, script, field{"Command", command}, field{"Replacement", deprecatedCommandReplacements[command]})
+		}
+	}
+}
+
+// githubMinScheduleInterval is the shortest interval in minutes at which
+// GitHub runs scheduled workflows.
+const githubMinScheduleInterval = 5
+
+// checkSchedules validates the cron expressions of on.schedule and reports
+// schedules running more often than GitHub allows or than the
+// cron_frequency check's "min_interval" option in minutes.
+func checkSchedules(r *reporter, w *workflow.Workflow) {
+	schedules := w.On.Events["schedule"]
+	if schedules == nil || schedules.Kind != yaml.SequenceNode {
+		return
+	}
+
+	for _, entry := range schedules.Content {
+		_, cron := workflow.LookupKey(entry, "cron")
+		if cron == nil {
+			continue
+		}
+		schedule, err := parseCron(cron.Value)
+		if err != nil {
+			r.report("cron_syntax", "workflow", cron, field{"Schedule", cron.Value}, field{"Error", err})
+			continue
+		}
+
+		interval := schedule.minInterval()
+		if interval < githubMinScheduleInterval {
+			r.report("cron_interval", "workflow", cron, field{"Schedule", cron.Value})
+		} else if check := r.check("cron_frequency"); check != nil && interval < check.IntOption("min_interval", 15) {
+			r.report("cron_frequency", "workflow", cron, field{"Schedule", cron.Value}, field{"Minutes", interval})
+		}
+	}
+}
+
+// checkDispatchInputs reports workflow_dispatch inputs without a type or
+// description, optional inputs without a default, and choice inputs without
+// options or with a default that isn't one of them.
+func checkDispatchInputs(r *reporter, w *workflow.Workflow) {
+	_, inputs := workflow.LookupKey(w.On.Events["workflow_dispatch"], "inputs")
+	if inputs == nil || inputs.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(inputs.Content); i += 2 {
+		name, input := inputs.Content[i], inputs.Content[i+1]
+		report := func(problem string) {
+			r.report("dispatch_inputs", "workflow", name, field{"Input", name.Value}, field{"Problem", problem})
+		}
+
+		if _, description := workflow.LookupKey(input, "description"); description == nil || description.Value == "" {
+			report("no description")
+		}
+		_, inputType := workflow.LookupKey(input, "type")
+		if inputType == nil {
+			report("no type")
+		}
+		_, required := workflow.LookupKey(input, "required")
+		_, def := workflow.LookupKey(input, "default")
+		if def == nil && (required == nil || required.Value != "true") {
+			report("optional input without a default")
+		}
+
+		if inputType != nil && inputType.Value == "choice" {
+			_, options := workflow.LookupKey(input, "options")
+			if options == nil || len(options.Content) == 0 {
+				report("choice input without options")
+			} else if def != nil && !containsValue(options, def.Value) {
+				report(fmt.Sprintf("default %q is not one of the options", def.Value))
+			}
+		}
+	}
+}
+
+// containsValue reports whether a sequence node has a scalar item equal to
+// value.
+func containsValue(node *yaml.Node, value string) bool {
+	for _, item := range workflow.ScalarNodes(node) {
+		if item.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCancelInProgress reports CI workflows whose concurrency doesn't cancel
+// superseded runs and, inversely, deploy workflows (matching the check's
+// "deploy_workflows" patterns case-insensitively by name or file name) that
+// cancel in-progress deployments.
+func checkCancelInProgress(r *reporter, w *workflow.Workflow) {
+	check := r.check("cancel_in_progress")
+	if check == nil {
+		return
+	}
+	var deployPatterns []string
+	for _, pattern := range check.StringsOption("deploy_workflows") {
+		deployPatterns = append(deployPatterns, strings.ToLower(pattern))
+	}
+	deploy := matchesAny(deployPatterns, strings.ToLower(w.Name)) ||
+		matchesAny(deployPatterns, strings.ToLower(filepath.Base(w.File)))
+
+	concurrencyKey, concurrency := workflow.LookupKey(w.Node, "concurrency")
+	_, cancel := workflow.LookupKey(concurrency, "cancel-in-progress")
+	switch {
+	case deploy && cancel != nil && cancel.Value == "true":
+		r.report("cancel_in_progress", "workflow", cancel, field{"Setting", "false in deploy workflows"})
+	case !deploy && cancel == nil:
+		r.report("cancel_in_progress", "workflow", concurrencyKey, field{"Setting", "true"})
+	}
+}
+
+// checkSelfHostedRunner reports self-hosted runners in workflows triggered by
+// pull requests, which lets pull requests from forks run code on them.
+func checkSelfHostedRunner(r *reporter, w *workflow.Workflow, jobName string, labels []runnerLabel) {
+	var event string
+	for _, e := range []string{"pull_request", "pull_request_target"} {
+		if w.On.Has(e) {
+			event = e
+			break
+		}
+	}
+	if event == "" {
+		return
+	}
+
+	for _, label := range labels {
+		if label.value == "self-hosted" {
+			r.report("self_hosted_runner", jobName, label.node, field{"Event", event})
+			return
+		}
+	}
+}
+
+// runnerLabel is a runner label of a job, along with the node it comes
+// from: the label in runs-on, or the matrix value it expands to.
+type runnerLabel struct {
+	value string
+	node  *yaml.Node
+}
+
+// matrixValuePattern matches references to a matrix value, capturing its
+// key.
+var matrixValuePattern = regexp.MustCompile(`\$\{\{\s*matrix\.([\w-]+)\s*\}\}`)
+
+// runnerLabels returns the runner labels of runs-on, given as a label, a
+// list of labels or a mapping with labels. Labels referencing matrix values
+// are expanded with each value declared for them, in the matrix or its
+// include entries; they are skipped when a value is only known at run time.
+func runnerLabels(job workflow.Job, runsOn *yaml.Node) []runnerLabel {
+	nodes := workflow.ScalarNodes(runsOn)
+	if _, labels := workflow.LookupKey(runsOn, "labels"); labels != nil {
+		nodes = workflow.ScalarNodes(labels)
+	}
+
+	var labels []runnerLabel
+	for _, node := range nodes {
+		refs := matrixValuePattern.FindAllStringSubmatchIndex(node.Value, -1)
+		if len(refs) == 0 {
+			labels = append(labels, runnerLabel{node.Value, node})
+			continue
+		}
+
+		// Expand the references one at a time, from the last so the
+		// indexes of the others stay valid.
+		expanded := []runnerLabel{{node.Value, node}}
+		for i := len(refs) - 1; i >= 0; i-- {
+			ref := refs[i]
+			values := matrixValues(job, node.Value[ref[2]:ref[3]])
+			var next []runnerLabel
+			for _, label := range expanded {
+				for _, value := range values {
+					// A label from a single matrix value points at the value.
+					labelNode := node
+					if len(refs) == 1 {
+						labelNode = value
+					}
+					next = append(next, runnerLabel{label.value[:ref[0]] + value.Value + label.value[ref[1]:], labelNode})
+				}
+			}
+			expanded = next
+		}
+		labels = append(labels, expanded...)
+	}
+	return labels
+}
+
+// matrixValues returns the scalar values declared for the matrix key of
+// job, or nil when the matrix is computed or a value is an expression.
+func matrixValues(job workflow.Job, key string) []*yaml.Node {
+	_, strategy := workflow.LookupKey(job.Node, "strategy")
+	_, matrix := workflow.LookupKey(strategy, "matrix")
+	if matrix == nil || matrix.Kind != yaml.MappingNode || key == "include" || key == "exclude" {
+		return nil
+	}
+
+	var values []*yaml.Node
+	if _, dimension := workflow.LookupKey(matrix, key); dimension != nil {
+		if dimension.Kind != yaml.SequenceNode {
+			return nil
+		}
+		values = append(values, dimension.Content...)
+	}
+	_, include := workflow.LookupKey(matrix, "include")
+	if include != nil && include.Kind != yaml.SequenceNode {
+		return nil
+	}
+	if include != nil {
+		for _, entry := range include.Content {
+			if _, value := workflow.LookupKey(entry, key); value != nil {
+				values = append(values, value)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var scalars []*yaml.Node
+	for _, value := range values {
+		if value.Kind != yaml.ScalarNode || strings.Contains(value.Value, "${{") {
+			return nil
+		}
+		if !seen[value.Value] {
+			seen[value.Value] = true
+			scalars = append(scalars, value)
+		}
+	}
+	return scalars
+}
+
+// checkPersistedCredentials reports actions/checkout steps that leave the
+// token in the git config, in jobs that don't push back to the repository.
+func checkPersistedCredentials(r *reporter, jobName string, job workflow.Job) {
+	for _, step := range job.Steps {
+		if _, script := workflow.LookupKey(step.Node, "run"); script != nil && gitPushPattern.MatchString(script.Value) {
+			return
+		}
+		for _, action := range pushActions {
+			if usesAction(step.Uses, action) {
+				return
+			}
+		}
+	}
+
+	for _, step := range job.Steps {
+		if !usesAction(step.Uses, "actions/checkout") {
+			continue
+		}
+		if _, persist := step.Input("persist-credentials"); persist == nil || persist.Value != "false" {
+			_, uses := workflow.LookupKey(step.Node, "uses")
+			r.report("persist_credentials", jobName, uses)
+		}
+	}
+}
+
+// checkContinueOnError reports jobs and steps with continue-on-error: true,
+// except in jobs matching the check's "allow" patterns.
+func checkContinueOnError(r *reporter, jobName string, job workflow.Job) {
+	check := r.check("continue_on_error")
+	if check == nil || matchesAny(check.StringsOption("allow"), jobName) {
+		return
+	}
+
+	if _, value := workflow.LookupKey(job.Node, "continue-on-error"); value != nil && value.Value == "true" {
+		r.report("continue_on_error", jobName, value, field{"Level", "job"})
+	}
+	for _, step := range job.Steps {
+		if _, value := workflow.LookupKey(step.Node, "continue-on-error"); value != nil && value.Value == "true" {
+			r.report("continue_on_error", jobName, value, field{"Level", "step"})
+		}
+	}
+}
+
+// checkDuplicateStepIDs reports step ids used more than once in a job.
+func checkDuplicateStepIDs(r *reporter, jobName string, job workflow.Job) {
+	seen := make(map[string]*yaml.Node)
+	for _, step := range job.Steps {
+		if step.ID == "" {
+			continue
+		}
+		_, id := workflow.LookupKey(step.Node, "id")
+		if first, ok := seen[step.ID]; ok {
+			r.report("duplicate_step_id", jobName, id, field{"StepID", step.ID}, field{"FirstLine", first.Line})
+			continue
+		}
+		seen[step.ID] = id
+	}
+}
+
+// checkDeploymentEnvironment reports deployment jobs without an environment,
+// which bypass its protection rules and required reviewers. A job is a
+// deployment when its name matches one of the check's "job_patterns" globs,
+// or the name, action or script of one of its steps matches one of the
+// "step_patterns" regular expressions.
+func checkDeploymentEnvironment(r *reporter, jobName string, job workflow.Job) {
+	check := r.check("deployment_environment")
+	if check == nil || job.Environment != nil || job.Uses != "" {
+		return
+	}
+
+	deployment := matchesAny(check.StringsOption("job_patterns"), jobName)
+	patterns := r.regexpsOption(check, "step_patterns")
+	for _, step := range job.Steps {
+		if deployment {
+			break
+		}
+		_, script := workflow.LookupKey(step.Node, "run")
+		text := step.Name + "\n" + step.Uses
+		if script != nil {
+			text += "\n" + script.Value
+		}
+		for _, pattern := range patterns {
+			if pattern.MatchString(text) {
+				deployment = true
+				break
+			}
+		}
+	}
+
+	if deployment {
+		r.report("deployment_environment", jobName, job.Key)
+	}
+}
+
+// checkImageDigests reports container, service and docker:// step images
+// referenced by a mutable tag instead of a digest.
+func checkImageDigests(r *reporter, jobName string, job workflow.Job) {
+	var images []*yaml.Node
+	if job.Container != nil {
+		images = append(images, job.Container.ImageNode)
+	}
+	_, services := workflow.LookupKey(job.Node, "services")
+	if services != nil && services.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(services.Content); i += 2 {
+			images = append(images, job.Services[services.Content[i].Value].ImageNode)
+		}
+	}
+	for _, step := range job.Steps {
+		if strings.HasPrefix(step.Uses, "docker://") {
+			_, uses := workflow.LookupKey(step.Node, "uses")
+			images = append(images, uses)
+		}
+	}
+
+	for _, image := range images {
+		if image == nil || image.Value == "" || strings.Contains(image.Value, "${{") {
+			continue
+		}
+		if !strings.Contains(image.Value, "@sha256:") {
+			r.report("image_digest", jobName, image, field{"Image", strings.TrimPrefix(image.Value, "docker://")})
+		}
+	}
+}
+
+// checkMatrix reports matrices expanding to more jobs than the
+// matrix_max_parallel check's "max_size" option without max-parallel, and
+// matrix jobs matching the matrix_fail_fast check's "deploy_jobs" patterns
+// that rely on the default fail-fast, which cancels the other legs of a
+// partially applied deployment when one fails.
+func checkMatrix(r *reporter, jobName string, job workflow.Job) {
+	if job.Strategy == nil || job.Strategy.Matrix == nil {
+		return
+	}
+	_, strategy := workflow.LookupKey(job.Node, "strategy")
+	matrixKey, _ := workflow.LookupKey(strategy, "matrix")
+
+	if check := r.check("matrix_max_parallel"); check != nil && job.Strategy.MaxParallel == nil {
+		maxSize := check.IntOption("max_size", 10)
+		if size, ok := job.Strategy.Matrix.Size(); ok && size > maxSize {
+			r.report("matrix_max_parallel", jobName, matrixKey, field{"Size", size})
+		}
+	}
+
+	if check := r.check("matrix_fail_fast"); check != nil && job.Strategy.FailFast == nil {
+		if matchesAny(check.StringsOption("deploy_jobs"), jobName) {
+			r.report("matrix_fail_fast", jobName, matrixKey)
+		}
+	}
+}
+
+// matchesAny reports whether name matches any of the glob patterns.
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHardcodedCredentials reports values in the workflow, such as run
+// scripts, env and with: inputs, matching one of the check's "patterns",
+// which map credential kinds to regular expressions. The matched value is
+// left out of the finding so it isn't copied into reports.
+func checkHardcodedCredentials(r *reporter, w *workflow.Workflow) {
+	check := r.check("hardcoded_credentials")
+	if check == nil || w.Node == nil {
+		return
+	}
+	patterns := r.regexpsOption(check, "patterns")
+
+	var scan func(jobName string, node *yaml.Node)
+	scan = func(jobName string, node *yaml.Node) {
+		switch node.Kind {
+		case yaml.ScalarNode:
+			for _, pattern := range patterns {
+				if pattern.MatchString(node.Value) {
+					r.report("hardcoded_credentials", jobName, node, field{"Pattern", pattern.name})
+				}
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				scan(jobName, node.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				scan(jobName, item)
+			}
+		}
+	}
+
+	root := w.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "jobs" {
+			scan("workflow", root.Content[i+1])
+		}
+	}
+	for _, jobName := range w.JobNames() {
+		scan(jobName, w.Jobs[jobName].Node)
+	}
+}
+
+// checkSecretsInEnv reports secrets assigned in a workflow- or job-level env
+// mapping, which exposes them to every step including third-party actions.
+func checkSecretsInEnv(r *reporter, jobName, level string, env *yaml.Node) {
+	if env == nil || env.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(env.Content); i += 2 {
+		name, value := env.Content[i], env.Content[i+1]
+		if referencesContext(value.Value, secretsPattern) {
+			r.report("secrets_in_env", jobName, value, field{"Level", level}, field{"Name", name.Value})
+		}
+	}
+}
+
+// referencesContext reports whether any ${{ }} expression in s matches
+// pattern.
+func referencesContext(s string, pattern *regexp.Regexp) bool {
+	for _, match := range expressionPattern.FindAllStringSubmatch(s, -1) {
+		if pattern.MatchString(match[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRemoteScripts reports run scripts piping a downloaded script into a
+// shell, which runs whatever the remote server returns.
+func checkRemoteScripts(r *reporter, jobName string, step workflow.Step) {
+	_, script := workflow.LookupKey(step.Node, "run")
+	if script == nil {
+		return
+	}
+
+	for _, pattern := range remoteScriptPatterns {
+		for _, match := range pattern.FindAllString(script.Value, -1) {
+			r.report("remote_script", jobName, script, field{"Command", match})
+		}
+	}
+}
+
+// checkCache reviews actions/cache steps: keys that never change, restore
+// keys matching caches from any configuration, and cache writes from
+// pull_request_target workflows, which can poison caches used by the base
+// branch.
+func checkCache(r *reporter, w *workflow.Workflow, jobName string, step workflow.Step) {
+	writes := usesAction(step.Uses, "actions/cache") || usesAction(step.Uses, "actions/cache/save")
+	if !writes && !usesAction(step.Uses, "actions/cache/restore") {
+		return
+	}
+
+	if _, key := step.Input("key"); key != nil && !dynamicCacheKeyPattern.MatchString(key.Value) {
+		r.report("cache_key", jobName, key, field{"Key", key.Value})
+	}
+
+	if _, restoreKeys := step.Input("restore-keys"); restoreKeys != nil {
+		for _, restoreKey := range strings.Split(restoreKeys.Value, "\n") {
+			restoreKey = strings.TrimSpace(restoreKey)
+			if restoreKey != "" && !expressionPattern.MatchString(restoreKey) {
+				r.report("cache_restore_keys", jobName, restoreKeys, field{"RestoreKey", restoreKey})
+			}
+		}
+	}
+
+	if writes && w.On.Has("pull_request_target") {
+		_, uses := workflow.LookupKey(step.Node, "uses")
+		r.report("cache_poisoning", jobName, uses)
+	}
+}
+
+// checkArtifactRetention reports actions/upload-artifact steps relying on the
+// repository's default retention, or keeping artifacts longer than the
+// check's "max_days" option.
+func checkArtifactRetention(r *reporter, jobName string, step workflow.Step) {
+	check := r.check("artifact_retention")
+	if check == nil || !usesAction(step.Uses, "actions/upload-artifact") {
+		return
+	}
+	maxDays := check.IntOption("max_days", 30)
+
+	_, retention := step.Input("retention-days")
+	if retention == nil {
+		_, uses := workflow.LookupKey(step.Node, "uses")
+		r.report("artifact_retention", jobName, uses, field{"MaxDays", maxDays})
+		return
+	}
+	if days, err := strconv.Atoi(retention.Value); err == nil && days > maxDays {
+		r.report("artifact_retention", jobName, retention, field{"MaxDays", maxDays})
+	}
+}
+
+// deprecatedRuntimes are the action runtimes GitHub has deprecated.
+var deprecatedRuntimes = map[string]bool{
+	"node12": true,
+	"node16": true,
+}
+
+// checkDeprecatedRuntime reports actions running on a deprecated Node.js
+// runtime, used by a step of file. Local actions are read from the
+// repository; other actions are only resolved in online mode.
+func checkDeprecatedRuntime(r *reporter, file, jobName string, step workflow.Step) {
+	if r.check("deprecated_runtime") == nil || step.Uses == "" || strings.HasPrefix(step.Uses, "docker://") {
+		return
+	}
+
+	var action *workflow.Action
+	var err error
+	switch {
+	case strings.HasPrefix(step.Uses, "./") && r.remote != nil:
+		action, err = r.remote.github.action(path.Join(r.remote.repo, step.Uses) + "@" + r.remote.ref)
+	case strings.HasPrefix(step.Uses, "./"):
+		action, err = workflow.ReadAction(filepath.Join(FindRepoRoot(file), step.Uses))
+	case r.github != nil:
+		action, err = r.github.action(step.Uses)
+	default:
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not read action %s: %v\n", step.Uses, err)
+		return
+	}
+
+	if action != nil && deprecatedRuntimes[action.Runs.Using] {
+		_, uses := workflow.LookupKey(step.Node, "uses")
+		r.report("deprecated_runtime", jobName, uses, field{"Uses", step.Uses}, field{"Runtime", action.Runs.Using})
+	}
+}
+
+// checkActionPolicy reports actions and reusable workflows violating the
+// config's action policy.
+func checkActionPolicy(r *reporter, jobName, uses string, node *yaml.Node) {
+	if violation := r.policy.Actions.evaluate(uses); violation != "" {
+		r.report("action_policy", jobName, node, field{"Uses", uses}, field{"Violation", violation})
+	}
+}
+
+// checkReusableWorkflowRef reports calls to reusable workflows in other
+// repositories that aren't pinned to a commit hash. Local reusable workflows
+// always run at the caller's commit.
+func checkReusableWorkflowRef(r *reporter, jobName string, job workflow.Job) {
+	if strings.HasPrefix(job.Uses, "./") {
+		return
+	}
+	if _, ref, found := strings.Cut(job.Uses, "@"); !found || !commitHashPattern.MatchString(ref) {
+		_, uses := workflow.LookupKey(job.Node, "uses")
+		r.report("reusable_workflow_ref", jobName, uses, field{"Uses", job.Uses})
+	}
+}
+
+// checkReusableWorkflowCall reports calls to local reusable workflows whose
+// with: and secrets: don't match the inputs and secrets declared under the
+// called workflow's workflow_call trigger: undeclared ones, and required ones
+// that are missing.
+func checkReusableWorkflowCall(r *reporter, w *workflow.Workflow, jobName string, job workflow.Job) {
+	if r.check("reusable_workflow_call") == nil || !strings.HasPrefix(job.Uses, "./") {
+		return
+	}
+	_, uses := workflow.LookupKey(job.Node, "uses")
+	report := func(problem string) {
+		r.report("reusable_workflow_call", jobName, uses, field{"Uses", job.Uses}, field{"Problem", problem})
+	}
+
+	var data []byte
+	var err error
+	if r.remote != nil {
+		data, err = r.remote.github.fileContent(r.remote.repo, path.Clean(job.Uses), r.remote.ref, 0)
+	} else {
+		data, err = os.ReadFile(filepath.Join(FindRepoRoot(w.File), job.Uses))
+	}
+	if err == errNotFound || os.IsNotExist(err) {
+		report("the workflow file doesn't exist")
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not read workflow %s: %v\n", job.Uses, err)
+		return
+	}
+	called, err := workflow.Parse(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not parse workflow %s: %v\n", job.Uses, err)
+		return
+	}
+	if !called.On.Has("workflow_call") {
+		report("the workflow has no workflow_call trigger")
+		return
+	}
+
+	trigger := called.On.Events["workflow_call"]
+	for _, kind := range []string{"inputs", "secrets"} {
+		_, declared := workflow.LookupKey(trigger, kind)
+		key := "with"
+		if kind == "secrets" {
+			key = "secrets"
+		}
+		_, provided := workflow.LookupKey(job.Node, key)
+		if kind == "secrets" && provided != nil && provided.Value == "inherit" {
+			continue
+		}
+		singular := strings.TrimSuffix(kind, "s")
+
+		if provided != nil && provided.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(provided.Content); i += 2 {
+				name := provided.Content[i]
+				if declaredKey, _ := workflow.LookupKey(declared, name.Value); declaredKey == nil {
+					r.report("reusable_workflow_call", jobName, name, field{"Uses", job.Uses}, field{"Problem", fmt.Sprintf("%s %s is not declared", singular, name.Value)})
+				}
+			}
+		}
+		if declared == nil || declared.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(declared.Content); i += 2 {
+			name, parameter := declared.Content[i].Value, declared.Content[i+1]
+			_, required := workflow.LookupKey(parameter, "required")
+			_, def := workflow.LookupKey(parameter, "default")
+			if required == nil || required.Value != "true" || def != nil {
+				continue
+			}
+			if providedKey, _ := workflow.LookupKey(provided, name); providedKey == nil {
+				report(fmt.Sprintf("required %s %s is missing", singular, name))
+			}
+		}
+	}
+}
+
+// checkStepName reports steps without a name. When the check's
+// "min_run_lines" option is set, only run steps with more lines are
+// reported.
+func checkStepName(r *reporter, jobName string, step workflow.Step) {
+	check := r.check("step_name")
+	if check == nil || step.Name != "" {
+		return
+	}
+
+	_, script := workflow.LookupKey(step.Node, "run")
+	if minLines := check.IntOption("min_run_lines", 0); minLines > 0 {
+		if script == nil || len(strings.Split(strings.TrimSpace(script.Value), "\n")) <= minLines {
+			return
+		}
+	}
+
+	label := step.Uses
+	if script != nil {
+		label, _, _ = strings.Cut(strings.TrimSpace(script.Value), "\n")
+	}
+	r.report("step_name", jobName, step.Node, field{"Label", label})
+}
+
+// alwaysPattern matches the always() status check function.
+var alwaysPattern = regexp.MustCompile(`\balways\(\s*\)`)
+
+// checkAlwaysOnDeploy reports steps guarded by always() whose name, action or
+// script matches one of the check's "patterns", as they run even after
+// earlier steps failed or the run was cancelled.
+func checkAlwaysOnDeploy(r *reporter, jobName string, step workflow.Step) {
+	check := r.check("always_on_deploy")
+	if check == nil || !alwaysPattern.MatchString(step.If) {
+		return
+	}
+
+	_, script := workflow.LookupKey(step.Node, "run")
+	text := step.Name + "\n" + step.Uses
+	if script != nil {
+		text += "\n" + script.Value
+	}
+	for _, pattern := range r.regexpsOption(check, "patterns") {
+		if match := pattern.FindString(text); match != "" {
+			_, cond := workflow.LookupKey(step.Node, "if")
+			r.report("always_on_deploy", jobName, cond, field{"Match", match})
+			return
+		}
+	}
+}
+
+// checkOutdatedAction reports actions pinned to an older major version than
+// their latest release. For commit hash references, the version is read from
+// a trailing comment such as "# v4.1.1".
+func checkOutdatedAction(r *reporter, jobName string, step workflow.Step) {
+	if r.check("outdated_action") == nil {
+		return
+	}
+	repo, ref, ok := ActionRepo(step.Uses)
+	if !ok {
+		return
+	}
+	_, uses := workflow.LookupKey(step.Node, "uses")
+	if commitHashPattern.MatchString(ref) {
+		ref = strings.TrimSpace(strings.TrimPrefix(uses.LineComment, "#"))
+	}
+	current, ok := majorVersion(ref)
+	if !ok {
+		return
+	}
+
+	latestVersion, err := r.github.latestVersion(repo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not get latest version of %s: %v\n", repo, err)
+		return
+	}
+	if latest, ok := majorVersion(latestVersion); ok && latest > current {
+		r.report("outdated_action", jobName, uses, field{"Uses", step.Uses}, field{"Latest", latestVersion})
+	}
+}
+
+// checkActionRepository reports actions whose repository is archived, and so
+// no longer receives fixes, or no longer exists, so its name could be
+// claimed by someone else.
+func checkActionRepository(r *reporter, jobName string, step workflow.Step) {
+	if r.check("action_repository") == nil {
+		return
+	}
+	repo, _, ok := ActionRepo(step.Uses)
+	if !ok {
+		return
+	}
+
+	state, err := r.github.repository(repo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not get repository %s: %v\n", repo, err)
+		return
+	}
+	_, uses := workflow.LookupKey(step.Node, "uses")
+	switch {
+	case state == nil:
+	case state.Missing:
+		r.report("action_repository", jobName, uses, field{"Repository", repo}, field{"State", "deleted or private"})
+	case state.Archived:
+		r.report("action_repository", jobName, uses, field{"Repository", repo}, field{"State", "archived"})
+	}
+}
+
+// checkPullRequestTargetCheckout reports checkouts of the pull request head
+// in pull_request_target workflows, which run the pull request's code with
+// the base repository's token and secrets.
+func checkPullRequestTargetCheckout(r *reporter, jobName string, step workflow.Step) {
+	if !usesAction(step.Uses, "actions/checkout") {
+		return
+	}
+	if _, ref := step.Input("ref"); ref != nil && pullRequestHeadPattern.MatchString(ref.Value) {
+		r.report("pull_request_target_checkout", jobName, ref, field{"Ref", ref.Value})
+	}
+}
+
+// usesAction reports whether a uses reference points at action, with or
+// without a ref.
+func usesAction(uses, action string) bool {
+	return uses == action || strings.HasPrefix(uses, action+"@")
+}
@@ -0,0 +1,39 @@
+package checks
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+)
+
+// UploadSARIF uploads a SARIF log to the code scanning API of repo
+// ("owner/name") for commit and ref, such as refs/heads/main, returning the
+// ID of the upload. The log is gzipped and base64-encoded, as the API
+// requires.
+func (c *GitHubClient) UploadSARIF(repo, commit, ref string, sarif []byte) (string, error) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(sarif); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+
+	upload := struct {
+		CommitSHA string `json:"commit_sha"`
+		Ref       string `json:"ref"`
+		SARIF     string `json:"sarif"`
+		ToolName  string `json:"tool_name"`
+	}{commit, ref, base64.StdEncoding.EncodeToString(compressed.Bytes()), "ghactionscheck"}
+	var uploaded struct {
+		ID string `json:"id"`
+	}
+	err := c.do(http.MethodPost, "/repos/"+repo+"/code-scanning/sarifs", upload, &uploaded)
+	if err == errNotFound {
+		return "", fmt.Errorf("repository %s not found, or code scanning isn't available for it", repo)
+	}
+	return uploaded.ID, err
+}
@@ -0,0 +1,356 @@
+package checks
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/template"
+
+	"ghactionscheck/pkg/report"
+	"gopkg.in/yaml.v3"
+)
+
+// Check configures a check: its messages, the URL of documentation on
+// resolving its findings, the explanation shown by the explain command, its
+// category and severity, whether it is enabled and its check-specific
+// options.
+type Check struct {
+	ID          string    `yaml:"id"`
+	Description string    `yaml:"description"`
+	Message     string    `yaml:"message"`
+	Detail      string    `yaml:"detail"`
+	URL         string    `yaml:"url,omitempty"`
+	Doc         *CheckDoc `yaml:"doc,omitempty"`
+	Category    string    `yaml:"category,omitempty"`
+	Severity    string    `yaml:"severity,omitempty"`
+	Enabled     *bool     `yaml:"enabled,omitempty"`
+
+	// Locales holds the message and detail in other languages, by language
+	// code such as ja.
+	Locales map[string]Locale `yaml:"locales,omitempty"`
+	// Options holds check-specific settings.
+	Options map[string]interface{} `yaml:"options,omitempty"`
+	// Rule defines a custom check in the config.
+	Rule *Rule `yaml:"rule,omitempty"`
+
+	// template is the parsed Message, when it is a template.
+	template *template.Template
+	// sourceMessage and sourceTemplate are the message and template before
+	// Localize translated them, which identify findings in baselines and
+	// review comments whatever the language.
+	sourceMessage  string
+	sourceTemplate *template.Template
+}
+
+// CheckDoc explains a check: the risk of its findings, examples of YAML it
+// reports and of the fixed YAML, and links to further reading.
+type CheckDoc struct {
+	Risk  string   `yaml:"risk"`
+	Bad   string   `yaml:"bad,omitempty"`
+	Good  string   `yaml:"good,omitempty"`
+	Links []string `yaml:"links,omitempty"`
+}
+
+// IntOption returns the named option as an integer, or def when it is unset
+// or not an integer.
+func (c *Check) IntOption(name string, def int) int {
+	if value, ok := c.Options[name].(int); ok {
+		return value
+	}
+	return def
+}
+
+// StringOption returns the named option as a string, or def when it is unset
+// or not a string.
+func (c *Check) StringOption(name, def string) string {
+	if value, ok := c.Options[name].(string); ok {
+		return value
+	}
+	return def
+}
+
+// StringsOption returns the named option as a list of strings, accepting a
+// single string as a one-element list.
+func (c *Check) StringsOption(name string) []string {
+	switch value := c.Options[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Config is a checks config file.
+type Config struct {
+	Checks  []Check  `yaml:"checks"`
+	Policy  Policy   `yaml:"policy"`
+	Plugins []Plugin `yaml:"plugins"`
+	Ignore  []Ignore `yaml:"ignore"`
+
+	// lang is the language the messages were localized to.
+	lang string
+}
+
+// Check returns the check with id, or nil if it is not configured or is
+// disabled.
+func (c *Config) Check(id string) *Check {
+	return findCheck(c.Checks, id)
+}
+
+// Override returns a copy of the config with the checks of enable enabled
+// and those of disable disabled, for a run with a different set of checks
+// than the config's. A built-in check the config leaves out can be enabled
+// too.
+func (c *Config) Override(enable, disable []string) (*Config, error) {
+	config := *c
+	config.Checks = slices.Clone(c.Checks)
+	for _, id := range enable {
+		if err := config.setEnabled(CanonicalID(id), true); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range disable {
+		if slices.ContainsFunc(enable, func(e string) bool { return CanonicalID(e) == CanonicalID(id) }) {
+			return nil, fmt.Errorf("check %s is both enabled and disabled", id)
+		}
+		if err := config.setEnabled(CanonicalID(id), false); err != nil {
+			return nil, err
+		}
+	}
+	return &config, nil
+}
+
+func (c *Config) setEnabled(id string, enabled bool) error {
+	i := slices.IndexFunc(c.Checks, func(check Check) bool { return check.ID == id })
+	if i < 0 {
+		builtin, ok := builtinChecks()[id]
+		if !ok {
+			return fmt.Errorf("unknown check %s", id)
+		}
+		if !enabled {
+			return nil
+		}
+		if c.lang != "" {
+			translations, err := builtinLocale(c.lang)
+			if err != nil {
+				return err
+			}
+			if err := localize(&builtin, c.lang, translations); err != nil {
+				return err
+			}
+		}
+		c.Checks = append(c.Checks, builtin)
+		i = len(c.Checks) - 1
+	}
+	c.Checks[i].Enabled = &enabled
+	return nil
+}
+
+//go:embed checks.yaml
+var defaultConfig []byte
+
+// DefaultConfig returns the built-in checks config file.
+func DefaultConfig() []byte {
+	return bytes.Clone(defaultConfig)
+}
+
+// RuleSetVersion identifies the built-in checks config by the first 12 hex
+// digits of its SHA-256, so that reports of the same rule set can be told
+// apart from reports of another build.
+func RuleSetVersion() string {
+	sum := sha256.Sum256(defaultConfig)
+	return hex.EncodeToString(sum[:6])
+}
+
+// ConfigFileName is the name of the config file looked up in repositories.
+const ConfigFileName = ".ghactionscheck.yaml"
+
+// FindConfigFile returns the checks config to use for scanning path, or an
+// empty string when none is found. The locations are tried in order:
+//
+//  1. .ghactionscheck.yaml in the repository root
+//  2. .github/.ghactionscheck.yaml in the repository root
+//  3. ghactionscheck/config.yaml in $XDG_CONFIG_HOME (or ~/.config)
+//
+// The repository root is found with FindRepoRoot.
+func FindConfigFile(path string) string {
+	root := FindRepoRoot(path)
+	candidates := []string{
+		filepath.Join(root, ConfigFileName),
+		filepath.Join(root, ".github", ConfigFileName),
+	}
+	if user := UserConfigFile(); user != "" {
+		candidates = append(candidates, user)
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// UserConfigFile returns the path of the config of the user, in the user
+// config directory, or "" when it is unknown.
+func UserConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ghactionscheck", "config.yaml")
+}
+
+// FindRepoRoot returns the nearest ancestor of path containing .git, or the
+// directory containing .github/workflows (or path itself) when it is not in
+// a git repository.
+func FindRepoRoot(path string) string {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	start := dir
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	if filepath.Base(start) == "workflows" && filepath.Base(filepath.Dir(start)) == ".github" {
+		return filepath.Dir(filepath.Dir(start))
+	}
+	return start
+}
+
+// LoadConfig reads the checks config from path, or uses the built-in
+// default checks when path is empty.
+func LoadConfig(path string) (*Config, error) {
+	data := defaultConfig
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading checks config: %v", err)
+		}
+		if data, err = resolveExtends(data, path); err != nil {
+			return nil, err
+		}
+	}
+
+	config, err := parseConfig(data, path)
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		// Configs written before checks had categories keep them.
+		for i := range config.Checks {
+			if config.Checks[i].Category == "" {
+				config.Checks[i].Category = builtinChecks()[config.Checks[i].ID].Category
+			}
+		}
+	}
+	return config, nil
+}
+
+// parseConfig parses and validates the checks config in data, read from
+// path.
+func parseConfig(data []byte, path string) (*Config, error) {
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing checks config: %v", err)
+	}
+
+	for i := range config.Checks {
+		config.Checks[i].ID = CanonicalID(config.Checks[i].ID)
+		severity, err := report.NormalizeSeverity(config.Checks[i].Severity)
+		if err != nil {
+			return nil, fmt.Errorf("error in check %s: %v", config.Checks[i].ID, err)
+		}
+		config.Checks[i].Severity = severity
+		category, err := report.NormalizeCategory(config.Checks[i].Category)
+		if err != nil {
+			return nil, fmt.Errorf("error in check %s: %v", config.Checks[i].ID, err)
+		}
+		config.Checks[i].Category = category
+		if err := config.Checks[i].compileMessage(); err != nil {
+			return nil, fmt.Errorf("error in check %s: %v", config.Checks[i].ID, err)
+		}
+		if rule := config.Checks[i].Rule; rule != nil {
+			if err := rule.compile(); err != nil {
+				return nil, fmt.Errorf("error in check %s: %v", config.Checks[i].ID, err)
+			}
+		}
+	}
+
+	for i := range config.Ignore {
+		ignore := &config.Ignore[i]
+		ignore.Check = CanonicalID(ignore.Check)
+		if err := ignore.validate(); err != nil {
+			return nil, fmt.Errorf("error in ignore entry %d: %v", i+1, err)
+		}
+	}
+
+	for i := range config.Plugins {
+		plugin := &config.Plugins[i]
+		if plugin.Name == "" {
+			return nil, fmt.Errorf("error in plugin %d: no name", i+1)
+		}
+		severity, err := report.NormalizeSeverity(plugin.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("error in plugin %s: %v", plugin.Name, err)
+		}
+		plugin.Severity = severity
+		if path != "" {
+			plugin.dir = filepath.Dir(path)
+		}
+	}
+
+	return &config, nil
+}
+
+// renamedChecks maps former check ids to their current ids, so existing
+// configs and suppression comments keep working.
+var renamedChecks = map[string]string{
+	"aws_credentials": "cloud_credentials",
+}
+
+// CanonicalID returns the current id of a check that may have been renamed.
+func CanonicalID(id string) string {
+	if renamed, ok := renamedChecks[id]; ok {
+		return renamed
+	}
+	return id
+}
+
+func findCheck(checks []Check, id string) *Check {
+	for _, check := range checks {
+		if check.ID == id {
+			if check.Enabled == nil || *check.Enabled {
+				return &check
+			}
+			return nil
+		}
+	}
+	return nil
+}
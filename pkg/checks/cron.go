@@ -0,0 +1,135 @@
+package checks
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cronField describes the allowed values of a cron field.
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{name: "day of week", min: 0, max: 6, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+}
+
+// cronSchedule holds the values each field of a cron expression matches.
+type cronSchedule struct {
+	fields [5][]int
+}
+
+// parseCron parses a POSIX cron expression as supported by on.schedule.
+func parseCron(expr string) (*cronSchedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(parts))
+	}
+
+	var schedule cronSchedule
+	for i, part := range parts {
+		values, err := cronFields[i].parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", cronFields[i].name, err)
+		}
+		schedule.fields[i] = values
+	}
+	return &schedule, nil
+}
+
+// parse returns the sorted values matched by a comma-separated field.
+func (f cronField) parse(field string) ([]int, error) {
+	matched := make(map[int]bool)
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		var start, end int
+		switch {
+		case rangePart == "*":
+			start, end = f.min, f.max
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = f.value(from); err != nil {
+				return nil, err
+			}
+			if end, err = f.value(to); err != nil {
+				return nil, err
+			}
+			if start > end {
+				return nil, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			var err error
+			if start, err = f.value(rangePart); err != nil {
+				return nil, err
+			}
+			end = start
+			if hasStep {
+				end = f.max
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			matched[v] = true
+		}
+	}
+
+	values := make([]int, 0, len(matched))
+	for v := range matched {
+		values = append(values, v)
+	}
+	sort.Ints(values)
+	return values, nil
+}
+
+// value parses a single number or name of the field.
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+// minInterval returns the shortest interval in minutes between two runs on
+// the same or consecutive days, ignoring the day fields.
+func (s *cronSchedule) minInterval() int {
+	var times []int
+	for _, hour := range s.fields[1] {
+		for _, minute := range s.fields[0] {
+			times = append(times, hour*60+minute)
+		}
+	}
+	if len(times) == 1 {
+		return 24 * 60
+	}
+
+	interval := times[0] + 24*60 - times[len(times)-1]
+	for i := 1; i < len(times); i++ {
+		if d := times[i] - times[i-1]; d < interval {
+			interval = d
+		}
+	}
+	return interval
+}
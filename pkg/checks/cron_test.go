@@ -0,0 +1,114 @@
+package checks
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		// fields are the values of each field, or err the error.
+		fields [5][]int
+		err    string
+	}{
+		{
+			name:   "every minute",
+			expr:   "* * * * *",
+			fields: [5][]int{seq(0, 59, 1), seq(0, 23, 1), seq(1, 31, 1), seq(1, 12, 1), seq(0, 6, 1)},
+		},
+		{
+			name:   "values and lists",
+			expr:   "0,30 9 1 1 0",
+			fields: [5][]int{{0, 30}, {9}, {1}, {1}, {0}},
+		},
+		{
+			name:   "ranges",
+			expr:   "0 9-17 * * 1-5",
+			fields: [5][]int{{0}, seq(9, 17, 1), seq(1, 31, 1), seq(1, 12, 1), seq(1, 5, 1)},
+		},
+		{
+			name:   "steps",
+			expr:   "*/15 0-12/6 5/10 * *",
+			fields: [5][]int{{0, 15, 30, 45}, {0, 6, 12}, {5, 15, 25}, seq(1, 12, 1), seq(0, 6, 1)},
+		},
+		{
+			name:   "names",
+			expr:   "0 0 * jan,Jul-SEP mon-FRI",
+			fields: [5][]int{{0}, {0}, seq(1, 31, 1), {1, 7, 8, 9}, seq(1, 5, 1)},
+		},
+		{
+			name:   "overlapping items",
+			expr:   "0,0-2,*/2 0 * * *",
+			fields: [5][]int{append([]int{0, 1}, seq(2, 58, 2)...), {0}, seq(1, 31, 1), seq(1, 12, 1), seq(0, 6, 1)},
+		},
+		{name: "too few fields", expr: "* * * *", err: "expected 5 fields, got 4"},
+		{name: "too many fields", expr: "* * * * * *", err: "expected 5 fields, got 6"},
+		{name: "zero step", expr: "*/0 * * * *", err: `minute: invalid step "0"`},
+		{name: "negative step", expr: "* */-1 * * *", err: `hour: invalid step "-1"`},
+		{name: "missing step", expr: "* * */ * *", err: `day of month: invalid step ""`},
+		{name: "out of range", expr: "60 * * * *", err: `minute: invalid value "60"`},
+		{name: "below range", expr: "* * 0 * *", err: `day of month: invalid value "0"`},
+		{name: "reversed range", expr: "* 17-9 * * *", err: `hour: invalid range "17-9"`},
+		{name: "open range", expr: "* * * * 1-", err: `day of week: invalid value ""`},
+		{name: "unknown name", expr: "* * * JUNE *", err: `month: invalid value "JUNE"`},
+		{name: "name of another field", expr: "* * * MON *", err: `month: invalid value "MON"`},
+		{name: "empty item", expr: "1,,2 * * * *", err: `minute: invalid value ""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseCron(tt.expr)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("parseCron(%q) = %v, want %q", tt.expr, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCron(%q) = %v", tt.expr, err)
+			}
+			for i, values := range schedule.fields {
+				if !slices.Equal(values, tt.fields[i]) {
+					t.Errorf("parseCron(%q) %s = %v, want %v", tt.expr, cronFields[i].name, values, tt.fields[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCronMinInterval(t *testing.T) {
+	tests := []struct {
+		expr     string
+		interval int
+	}{
+		{expr: "* * * * *", interval: 1},
+		{expr: "*/5 * * * *", interval: 5},
+		{expr: "0 * * * *", interval: 60},
+		{expr: "0 0 * * *", interval: 24 * 60},
+		{expr: "0 0,12 * * *", interval: 12 * 60},
+		{expr: "0 1,23 * * *", interval: 2 * 60},
+		{expr: "50 9 * * *", interval: 24 * 60},
+		{expr: "0,50 9 * * 1", interval: 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCron(%q) = %v", tt.expr, err)
+			}
+			if interval := schedule.minInterval(); interval != tt.interval {
+				t.Errorf("minInterval(%q) = %d, want %d", tt.expr, interval, tt.interval)
+			}
+		})
+	}
+}
+
+// seq returns the values from start to end by step.
+func seq(start, end, step int) []int {
+	var values []int
+	for v := start; v <= end; v += step {
+		values = append(values, v)
+	}
+	return values
+}
@@ -0,0 +1,55 @@
+package checks
+
+import (
+	"net/url"
+	"strings"
+)
+
+// githubHost is the host of github.com repositories.
+const githubHost = "github.com"
+
+// WebHost returns the host of the repositories served by the API at apiURL:
+// github.com for https://api.github.com or an empty URL, the host of a
+// GitHub Enterprise Server, whose API is at https://HOST/api/v3, and
+// SUBDOMAIN.ghe.com for the API of GitHub Enterprise Cloud with data
+// residency at https://api.SUBDOMAIN.ghe.com.
+func WebHost(apiURL string) string {
+	u, err := url.Parse(apiURL)
+	if apiURL == "" || err != nil || u.Hostname() == "" || u.Hostname() == "api.github.com" {
+		return githubHost
+	}
+	if host, ok := strings.CutPrefix(u.Hostname(), "api."); ok && strings.HasSuffix(host, ".ghe.com") {
+		return host
+	}
+	return u.Hostname()
+}
+
+// SetAPIURL sends the requests of the client to the API at apiURL instead of
+// https://api.github.com, such as https://HOST/api/v3 for a GitHub
+// Enterprise Server.
+func (c *GitHubClient) SetAPIURL(apiURL string) {
+	c.baseURL = strings.TrimSuffix(apiURL, "/")
+}
+
+// APIURL returns the URL of the API the client sends its requests to.
+func (c *GitHubClient) APIURL() string {
+	return c.baseURL
+}
+
+// WithGitHubHost checks workflows of repositories on host, as returned by
+// WebHost, rather than on github.com. The origin remotes of local clones
+// are matched against it, and on a GitHub Enterprise Server, which has no
+// GitHub-hosted runners, labels such as ubuntu-latest name self-hosted
+// runners rather than moving images, so runner_version doesn't report them.
+func WithGitHubHost(host string) Option {
+	return func(c *Checker) error {
+		c.host = host
+		return nil
+	}
+}
+
+// enterpriseServer reports whether the workflows run on a GitHub Enterprise
+// Server.
+func (c *Checker) enterpriseServer() bool {
+	return c.host != "" && c.host != githubHost && !strings.HasSuffix(c.host, ".ghe.com")
+}
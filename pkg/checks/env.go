@@ -0,0 +1,266 @@
+package checks
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ghactionscheck/pkg/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+// envWritePattern matches the names set in run scripts writing to
+// $GITHUB_ENV, as NAME=value or NAME<<DELIMITER. The whole script is
+// searched, so writes grouped in a block redirected to the file are found
+// too.
+var envWritePattern = regexp.MustCompile(`\b([A-Za-z_]\w*)(?:=|<<)`)
+
+// shellAssignmentPattern matches the variables a shell script assigns, and
+// shellDeclarationPattern the lines of commands setting the variables named
+// by their arguments.
+var (
+	shellAssignmentPattern  = regexp.MustCompile(`\b([A-Z_][A-Z0-9_]*)(?:\[[^\]]*\])?\+?=`)
+	shellDeclarationPattern = regexp.MustCompile(`(?m)\b(?:export|read|local|declare|typeset|readonly|for|select|mapfile|readarray|getopts)\b(.*)$`)
+	shellNamePattern        = regexp.MustCompile(`\b[A-Z_][A-Z0-9_]*\b`)
+	// shellDynamicPattern matches scripts sourcing files or evaluating
+	// code, which can set any variable.
+	shellDynamicPattern = regexp.MustCompile(`(?m)\b(?:source|eval)\s|(?:^|[;&|]\s*)\.\s+\S|\bset\s+-a\b`)
+)
+
+// envScope holds the environment variables defined for a part of a
+// workflow. dynamic is set when an env: value is an expression, so any
+// variable may be defined.
+type envScope struct {
+	names   map[string]bool
+	dynamic bool
+}
+
+// with returns the scope extended with the env: mapping under parent.
+func (s envScope) with(parent *yaml.Node) envScope {
+	_, env := workflow.LookupKey(parent, "env")
+	if env == nil {
+		return s
+	}
+	if env.Kind != yaml.MappingNode {
+		return envScope{names: s.names, dynamic: true}
+	}
+	var names []string
+	for i := 0; i+1 < len(env.Content); i += 2 {
+		names = append(names, env.Content[i].Value)
+	}
+	return s.withNames(names)
+}
+
+// withNames returns the scope extended with names, leaving s unchanged.
+func (s envScope) withNames(names []string) envScope {
+	if len(names) == 0 {
+		return s
+	}
+	extended := make(map[string]bool, len(s.names)+len(names))
+	for name := range s.names {
+		extended[name] = true
+	}
+	for _, name := range names {
+		extended[name] = true
+	}
+	return envScope{names: extended, dynamic: s.dynamic}
+}
+
+// defines reports whether name is defined in the scope, compared
+// case-insensitively when ignoreCase is set, as in the env context.
+func (s envScope) defines(name string, ignoreCase bool) bool {
+	if s.dynamic || s.names[name] {
+		return true
+	}
+	if ignoreCase {
+		for defined := range s.names {
+			if strings.EqualFold(defined, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkUndefinedEnv reports the environment variables that expressions read
+// through env.<name>, and that run scripts read as $NAME, but that env: never
+// defines at the level of the workflow, the job or the step, and no script
+// of the job writes to $GITHUB_ENV. The variables set by the runner
+// and by actions can be listed in the check's "variables" option. Only upper
+// case names of scripts are checked, as lower case ones are usually shell
+// variables.
+func checkUndefinedEnv(r *reporter, w *workflow.Workflow) {
+	check := r.check("undefined_env")
+	if check == nil {
+		return
+	}
+	known := check.StringsOption("variables")
+	reported := make(map[string]bool)
+	report := func(jobName string, node *yaml.Node, name string, scope envScope) {
+		if key := fmt.Sprintf("%p %s", node, name); !reported[key] {
+			reported[key] = true
+			r.report("undefined_env", jobName, node, field{"Name", name}, field{"Suggestion", suggestion(name, keys(scope.names))})
+		}
+	}
+	reportExpressions := func(jobName string, node *yaml.Node, scope envScope) {
+		for _, ref := range expressionPaths(node) {
+			if len(ref.path) < 2 || !strings.EqualFold(ref.path[0], "env") {
+				continue
+			}
+			if name := ref.path[1]; !scope.defines(name, true) && !matchesAny(known, name) {
+				report(jobName, ref.node, name, scope)
+			}
+		}
+	}
+
+	workflowScope := envScope{}.with(w.Node)
+	for i := 0; i+1 < len(w.Node.Content); i += 2 {
+		if key := w.Node.Content[i].Value; key != "jobs" && key != "env" && key != "on" {
+			reportExpressions("workflow", w.Node.Content[i+1], workflowScope)
+		}
+	}
+
+	for _, jobName := range w.JobNames() {
+		job := w.Jobs[jobName]
+		_, container := workflow.LookupKey(job.Node, "container")
+		jobScope := workflowScope.with(job.Node).with(container)
+		jobScope = jobScope.withNames(writtenEnv(job.Node))
+
+		for i := 0; i+1 < len(job.Node.Content); i += 2 {
+			if key := job.Node.Content[i].Value; key != "steps" && key != "env" {
+				reportExpressions(jobName, job.Node.Content[i+1], jobScope)
+			}
+		}
+		for _, step := range job.Steps {
+			stepScope := jobScope.with(step.Node)
+			for i := 0; i+1 < len(step.Node.Content); i += 2 {
+				if step.Node.Content[i].Value != "env" {
+					reportExpressions(jobName, step.Node.Content[i+1], stepScope)
+				}
+			}
+			_, script := workflow.LookupKey(step.Node, "run")
+			if script != nil && posixShell(w, job, step) {
+				for _, name := range shellReferences(script.Value) {
+					if !stepScope.defines(name, false) && !matchesAny(known, name) {
+						report(jobName, script, name, stepScope)
+					}
+				}
+			}
+		}
+	}
+}
+
+// writtenEnv returns the variables that the values under node, such as run
+// scripts and the scripts of actions like azure/cli, write to $GITHUB_ENV.
+func writtenEnv(node *yaml.Node) []string {
+	var names []string
+	if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, "GITHUB_ENV") {
+		for _, match := range envWritePattern.FindAllStringSubmatch(node.Value, -1) {
+			names = append(names, match[1])
+		}
+	}
+	for _, child := range node.Content {
+		names = append(names, writtenEnv(child)...)
+	}
+	return names
+}
+
+// shellReferences returns the upper case variables a POSIX shell script
+// reads without assigning them or giving a default value, as in
+// ${NAME:-default}. Single-quoted strings and comments are skipped, and
+// scripts that source files or evaluate code are skipped entirely.
+func shellReferences(script string) []string {
+	if shellDynamicPattern.MatchString(script) {
+		return nil
+	}
+	assigned := make(map[string]bool)
+	for _, match := range shellAssignmentPattern.FindAllStringSubmatch(script, -1) {
+		assigned[match[1]] = true
+	}
+	for _, match := range shellDeclarationPattern.FindAllStringSubmatch(script, -1) {
+		for _, name := range shellNamePattern.FindAllString(match[1], -1) {
+			assigned[name] = true
+		}
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	inDouble := false
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == '\\':
+			i++
+		case c == '"':
+			inDouble = !inDouble
+		case c == '\'' && !inDouble:
+			end := strings.IndexByte(script[i+1:], '\'')
+			if end < 0 {
+				return names
+			}
+			i += end + 1
+		case c == '#' && !inDouble && (i == 0 || strings.ContainsRune(" \t\n;", rune(script[i-1]))):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				return names
+			}
+			i += end
+		case c == '$':
+			name, defaulted := shellVariable(script[i+1:])
+			if name != "" && name != "_" && !defaulted && !assigned[name] && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// shellVariable returns the upper case variable read by the text after a $,
+// as NAME or ${NAME}, and whether the expansion gives a default value for it.
+func shellVariable(text string) (name string, defaulted bool) {
+	braced := strings.HasPrefix(text, "{")
+	if braced {
+		text = text[1:]
+	}
+	end := 0
+	for end < len(text) && (text[end] == '_' || 'A' <= text[end] && text[end] <= 'Z' || end > 0 && '0' <= text[end] && text[end] <= '9') {
+		end++
+	}
+	// Names with lower case letters aren't checked.
+	if end == 0 || end < len(text) && ('a' <= text[end] && text[end] <= 'z') {
+		return "", false
+	}
+	rest := strings.TrimPrefix(text[end:], ":")
+	return text[:end], braced && rest != "" && strings.ContainsRune("-=+?", rune(rest[0]))
+}
+
+// posixShell reports whether the run script of step runs in bash or sh: the
+// shell of the step or of the defaults of its job or workflow, or the
+// default shell of the runner, which is pwsh on Windows.
+func posixShell(w *workflow.Workflow, job workflow.Job, step workflow.Step) bool {
+	shell := ""
+	for _, parent := range []*yaml.Node{step.Node, runDefaults(job.Node), runDefaults(w.Node)} {
+		if _, value := workflow.LookupKey(parent, "shell"); value != nil {
+			shell = value.Value
+			break
+		}
+	}
+	if shell == "" {
+		_, runsOn := workflow.LookupKey(job.Node, "runs-on")
+		for _, label := range runnerLabels(job, runsOn) {
+			if strings.Contains(strings.ToLower(label.value), "windows") {
+				return false
+			}
+		}
+		return true
+	}
+	command, _, _ := strings.Cut(strings.TrimSpace(shell), " ")
+	return command == "bash" || command == "sh"
+}
+
+// runDefaults returns the defaults.run mapping under parent, or nil.
+func runDefaults(parent *yaml.Node) *yaml.Node {
+	_, defaults := workflow.LookupKey(parent, "defaults")
+	_, run := workflow.LookupKey(defaults, "run")
+	return run
+}
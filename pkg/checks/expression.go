@@ -0,0 +1,517 @@
+package checks
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"ghactionscheck/pkg/expression"
+	"ghactionscheck/pkg/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+// exprType is the type of an expression's value, as far as it is known
+// before the workflow runs.
+type exprType int
+
+const (
+	typeAny exprType = iota
+	typeNull
+	typeBool
+	typeNumber
+	typeString
+	typeObject
+	typeArray
+)
+
+func (t exprType) String() string {
+	return [...]string{"any", "null", "boolean", "number", "string", "object", "array"}[t]
+}
+
+// contextProperties maps the contexts with a fixed set of properties to the
+// types of their properties. The other contexts have user-defined
+// properties: env, vars and secrets hold strings, inputs and matrix any
+// value, and steps, needs and jobs objects described by idProperties. All
+// names are lowercase, as GitHub compares them case-insensitively.
+var contextProperties = map[string]map[string]exprType{
+	"github": {
+		"action": typeString, "action_path": typeString, "action_ref": typeString,
+		"action_repository": typeString, "action_status": typeString, "actor": typeString,
+		"actor_id": typeString, "api_url": typeString, "base_ref": typeString,
+		"env": typeString, "event": typeObject, "event_name": typeString,
+		"event_path": typeString, "graphql_url": typeString, "head_ref": typeString,
+		"job": typeString, "output": typeString, "path": typeString, "ref": typeString,
+		"ref_name": typeString, "ref_protected": typeBool, "ref_type": typeString,
+		"repository": typeString, "repository_id": typeString, "repository_owner": typeString,
+		"repository_owner_id": typeString, "repositoryurl": typeString,
+		"retention_days": typeString, "run_attempt": typeString, "run_id": typeString,
+		"run_number": typeString, "secret_source": typeString, "server_url": typeString,
+		"sha": typeString, "state": typeString, "step_summary": typeString, "token": typeString,
+		"triggering_actor": typeString, "workflow": typeString, "workflow_ref": typeString,
+		"workflow_sha": typeString, "workspace": typeString,
+	},
+	"runner": {
+		"name": typeString, "os": typeString, "arch": typeString, "temp": typeString,
+		"tool_cache": typeString, "debug": typeString, "environment": typeString,
+	},
+	"job": {
+		"check_run_id": typeNumber, "container": typeObject, "services": typeObject,
+		"status": typeString,
+	},
+	"strategy": {
+		"fail-fast": typeBool, "job-index": typeNumber, "job-total": typeNumber,
+		"max-parallel": typeNumber,
+	},
+}
+
+// idProperties maps the contexts keyed by step or job id to the properties
+// of each step or job.
+var idProperties = map[string]map[string]exprType{
+	"steps": {"outputs": typeObject, "outcome": typeString, "conclusion": typeString},
+	"needs": {"outputs": typeObject, "result": typeString},
+	"jobs":  {"outputs": typeObject, "result": typeString},
+}
+
+// stringContexts and anyContexts are the contexts whose properties are
+// defined by the workflow.
+var (
+	stringContexts = map[string]bool{"env": true, "vars": true, "secrets": true}
+	anyContexts    = map[string]bool{"inputs": true, "matrix": true}
+)
+
+// exprFunction describes a function of expressions: its number of arguments
+// (max is -1 for any number) and the type it returns.
+type exprFunction struct {
+	name     string
+	min, max int
+	result   exprType
+}
+
+// exprFunctions lists the functions of expressions by lowercase name.
+var exprFunctions = map[string]exprFunction{
+	"contains":   {"contains", 2, 2, typeBool},
+	"startswith": {"startsWith", 2, 2, typeBool},
+	"endswith":   {"endsWith", 2, 2, typeBool},
+	"format":     {"format", 1, -1, typeString},
+	"join":       {"join", 1, 2, typeString},
+	"tojson":     {"toJSON", 1, 1, typeString},
+	"fromjson":   {"fromJSON", 1, 1, typeAny},
+	"hashfiles":  {"hashFiles", 1, -1, typeString},
+	"success":    {"success", 0, 0, typeBool},
+	"always":     {"always", 0, 0, typeBool},
+	"cancelled":  {"cancelled", 0, 0, typeBool},
+	"failure":    {"failure", 0, 0, typeBool},
+}
+
+// formatPlaceholderPattern matches the {N} placeholders of format strings,
+// along with the escaped braces {{ and }} so they can be skipped.
+var formatPlaceholderPattern = regexp.MustCompile(`\{\{|\}\}|\{(\d+)\}`)
+
+// checkWorkflowExpressions checks the expressions of every value of the
+// workflow, and the conditions of its jobs and steps.
+func checkWorkflowExpressions(r *reporter, w *workflow.Workflow) {
+	conditions := make(map[*yaml.Node]bool)
+	for _, job := range w.Jobs {
+		conditions[job.Node] = true
+		for _, step := range job.Steps {
+			conditions[step.Node] = true
+		}
+	}
+
+	_, jobs := workflow.LookupKey(w.Node, "jobs")
+	for i := 0; i+1 < len(w.Node.Content); i += 2 {
+		if value := w.Node.Content[i+1]; value != jobs {
+			checkExpressions(r, "workflow", value, conditions)
+		}
+	}
+	for _, jobName := range w.JobNames() {
+		checkExpressions(r, jobName, w.Jobs[jobName].Node, conditions)
+	}
+}
+
+// checkActionExpressions checks the expressions of every value of an
+// action, and the conditions of its steps.
+func checkActionExpressions(r *reporter, action *workflow.Action) {
+	conditions := make(map[*yaml.Node]bool)
+	for _, step := range action.Runs.Steps {
+		conditions[step.Node] = true
+	}
+	checkExpressions(r, actionJobName, action.Node, conditions)
+}
+
+// checkExpressions checks the expressions of the values under node. The if
+// keys of the mappings in conditions are checked as conditions.
+func checkExpressions(r *reporter, jobName string, node *yaml.Node, conditions map[*yaml.Node]bool) {
+	if node == nil {
+		return
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if conditions[node] && node.Content[i].Value == "if" {
+				checkCondition(r, jobName, node.Content[i+1])
+			} else {
+				checkExpressions(r, jobName, node.Content[i+1], conditions)
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			checkExpressions(r, jobName, item, conditions)
+		}
+	case yaml.ScalarNode:
+		checkPlaceholders(r, jobName, node)
+	}
+}
+
+// checkPlaceholders checks the ${{ }} placeholders of a value.
+func checkPlaceholders(r *reporter, jobName string, node *yaml.Node) {
+	placeholders, err := expression.Placeholders(node.Value)
+	for _, placeholder := range placeholders {
+		checkExpression(r, jobName, node, placeholder.Source, false)
+	}
+	if err, ok := err.(*expression.Error); ok {
+		text, _, _ := strings.Cut(node.Value[err.Offset:], "\n")
+		r.report("expression", jobName, node, field{"Expression", text}, field{"Problem", err.Message})
+	}
+}
+
+// checkCondition checks an if: condition, which is an expression with or
+// without the ${{ }} around it. Text around a placeholder makes the
+// condition a string, which is always true.
+func checkCondition(r *reporter, jobName string, node *yaml.Node) {
+	if node.Kind != yaml.ScalarNode {
+		return
+	}
+	switch value := strings.TrimSpace(node.Value); {
+	case !strings.Contains(value, "${{"):
+		checkExpression(r, jobName, node, value, true)
+	case expression.IsPlaceholder(value):
+		checkExpression(r, jobName, node, value[3:len(value)-2], true)
+	default:
+		checkPlaceholders(r, jobName, node)
+		r.report("condition_type", jobName, node, field{"Condition", value},
+			field{"Problem", "the text outside ${{ }} makes it a non-empty string, which is always true"})
+	}
+}
+
+// checkExpression reports the syntax errors of an expression and the
+// contexts, properties and functions it uses that don't exist. For a
+// condition, comparisons between incompatible types are reported too.
+func checkExpression(r *reporter, jobName string, node *yaml.Node, source string, condition bool) {
+	text, prefix := strings.TrimSpace(source), ""
+	if !condition {
+		prefix = "${{ "
+	}
+	tree, err := expression.Parse(text)
+	if err, ok := err.(*expression.Error); ok {
+		problem := err.Message
+		if text != "" {
+			// Point at the error in the expression as reported.
+			problem = fmt.Sprintf("%s at position %d", err.Message, len(prefix)+err.Offset+1)
+		}
+		if !condition {
+			text = prefix + text + " }}"
+		}
+		r.report("expression", jobName, node, field{"Expression", text}, field{"Problem", problem})
+		return
+	}
+	if !condition {
+		text = prefix + text + " }}"
+	}
+
+	for _, problem := range expressionProblems(tree) {
+		r.report("expression", jobName, node, field{"Expression", text}, field{"Problem", problem})
+	}
+	if condition {
+		for _, problem := range conditionProblems(tree) {
+			r.report("condition_type", jobName, node, field{"Condition", text}, field{"Problem", problem})
+		}
+	}
+}
+
+// expressionPath is a path of property accesses, such as
+// needs.build.outputs.version, used by an expression of node.
+type expressionPath struct {
+	path []string
+	node *yaml.Node
+}
+
+// expressionPaths returns the paths of property accesses used by the
+// expressions of the values under node, including the if: conditions of
+// mappings. Expressions with syntax errors are skipped, as the expression
+// check reports them.
+func expressionPaths(node *yaml.Node) []expressionPath {
+	var paths []expressionPath
+	var walk func(node *yaml.Node, condition bool)
+	walk = func(node *yaml.Node, condition bool) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				walk(node.Content[i+1], node.Content[i].Value == "if")
+			}
+			return
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				walk(item, false)
+			}
+			return
+		case yaml.ScalarNode:
+		default:
+			return
+		}
+
+		var sources []string
+		if condition && !strings.Contains(node.Value, "${{") {
+			sources = append(sources, node.Value)
+		}
+		placeholders, _ := expression.Placeholders(node.Value)
+		for _, placeholder := range placeholders {
+			sources = append(sources, placeholder.Source)
+		}
+		for _, source := range sources {
+			tree, err := expression.Parse(strings.TrimSpace(source))
+			if err != nil {
+				continue
+			}
+			expression.Walk(tree, func(expr expression.Node) bool {
+				if path := expression.Path(expr); path != nil {
+					paths = append(paths, expressionPath{path, node})
+					return false
+				}
+				return true
+			})
+		}
+	}
+	if node != nil {
+		walk(node, false)
+	}
+	return paths
+}
+
+// expressionProblems returns the unknown contexts, properties and functions
+// used in tree, and the calls with a wrong number of arguments.
+func expressionProblems(tree expression.Node) []string {
+	var problems []string
+	expression.Walk(tree, func(node expression.Node) bool {
+		if path := expression.Path(node); path != nil {
+			if problem := pathProblem(path); problem != "" {
+				problems = append(problems, problem)
+			}
+			return false
+		}
+
+		call, ok := node.(*expression.Call)
+		if !ok {
+			return true
+		}
+		function, ok := exprFunctions[strings.ToLower(call.Name)]
+		if !ok {
+			names := make([]string, 0, len(exprFunctions))
+			for _, f := range exprFunctions {
+				names = append(names, f.name)
+			}
+			problems = append(problems, fmt.Sprintf("unknown function %s%s", call.Name, suggestion(call.Name, names)))
+			return true
+		}
+		switch {
+		case len(call.Args) < function.min:
+			problems = append(problems, fmt.Sprintf("%s takes at least %d arguments, got %d", function.name, function.min, len(call.Args)))
+		case function.max >= 0 && len(call.Args) > function.max:
+			problems = append(problems, fmt.Sprintf("%s takes at most %d arguments, got %d", function.name, function.max, len(call.Args)))
+		case function.name == "format":
+			if problem := formatProblem(call); problem != "" {
+				problems = append(problems, problem)
+			}
+		}
+		return true
+	})
+	return problems
+}
+
+// pathProblem reports an unknown context or property in a path of property
+// accesses.
+func pathProblem(path []string) string {
+	context := strings.ToLower(path[0])
+	if properties, ok := contextProperties[context]; ok {
+		if len(path) > 1 {
+			if _, ok := properties[strings.ToLower(path[1])]; !ok {
+				return fmt.Sprintf("unknown property %s of the %s context%s", path[1], context, suggestion(path[1], keys(properties)))
+			}
+		}
+		return ""
+	}
+	if properties, ok := idProperties[context]; ok {
+		if len(path) > 2 {
+			if _, ok := properties[strings.ToLower(path[2])]; !ok {
+				return fmt.Sprintf("unknown property %s of %s.%s%s", path[2], path[0], path[1], suggestion(path[2], keys(properties)))
+			}
+		}
+		return ""
+	}
+	if stringContexts[context] || anyContexts[context] {
+		return ""
+	}
+
+	contexts := append(keys(contextProperties), keys(idProperties)...)
+	contexts = append(contexts, keys(stringContexts)...)
+	contexts = append(contexts, keys(anyContexts)...)
+	return fmt.Sprintf("unknown context %s%s", path[0], suggestion(path[0], contexts))
+}
+
+// formatProblem reports a format call whose string literal uses more
+// arguments than it is given.
+func formatProblem(call *expression.Call) string {
+	literal, ok := call.Args[0].(*expression.Literal)
+	if !ok || literal.Kind != expression.String {
+		return ""
+	}
+	for _, match := range formatPlaceholderPattern.FindAllStringSubmatch(literal.Value, -1) {
+		if match[1] == "" {
+			continue
+		}
+		if index, err := strconv.Atoi(match[1]); err == nil && index >= len(call.Args)-1 {
+			return fmt.Sprintf("the format string uses {%d}, but there is no argument for it", index)
+		}
+	}
+	return ""
+}
+
+// conditionProblems returns the comparisons of tree between an object or
+// array and a value of another type, whose result doesn't depend on the
+// values, and string literals used as the whole condition.
+func conditionProblems(tree expression.Node) []string {
+	if literal, ok := tree.(*expression.Literal); ok && literal.Kind == expression.String && literal.Value != "" {
+		return []string{"a non-empty string is always true"}
+	}
+
+	var problems []string
+	expression.Walk(tree, func(node expression.Node) bool {
+		binary, ok := node.(*expression.Binary)
+		if !ok || binary.Op == "&&" || binary.Op == "||" {
+			return true
+		}
+		left, right := typeOf(binary.Left), typeOf(binary.Right)
+		if left == typeAny || right == typeAny || left == right || !isComposite(left) && !isComposite(right) {
+			return true
+		}
+		result := "false"
+		if binary.Op == "!=" {
+			result = "true"
+		}
+		problems = append(problems, fmt.Sprintf("comparing %s to %s with %s is always %s", left, right, binary.Op, result))
+		return true
+	})
+	return problems
+}
+
+func isComposite(t exprType) bool {
+	return t == typeObject || t == typeArray
+}
+
+// typeOf returns the type of the value of node, or typeAny when it is only
+// known at run time.
+func typeOf(node expression.Node) exprType {
+	switch n := node.(type) {
+	case *expression.Literal:
+		switch n.Kind {
+		case expression.Null:
+			return typeNull
+		case expression.Bool:
+			return typeBool
+		case expression.Number:
+			return typeNumber
+		}
+		return typeString
+	case *expression.Context:
+		return typeObject
+	case *expression.Property:
+		return pathType(expression.Path(n))
+	case *expression.Filter:
+		return typeArray
+	case *expression.Call:
+		if function, ok := exprFunctions[strings.ToLower(n.Name)]; ok {
+			return function.result
+		}
+	case *expression.Not:
+		return typeBool
+	case *expression.Binary:
+		if n.Op != "&&" && n.Op != "||" {
+			return typeBool
+		}
+	}
+	return typeAny
+}
+
+// pathType returns the type of the value at a path of property accesses.
+func pathType(path []string) exprType {
+	if len(path) < 2 {
+		return typeAny
+	}
+	context := strings.ToLower(path[0])
+	switch {
+	case contextProperties[context] != nil && len(path) == 2:
+		if t, ok := contextProperties[context][strings.ToLower(path[1])]; ok {
+			return t
+		}
+	case idProperties[context] != nil:
+		if len(path) == 2 {
+			return typeObject
+		}
+		t := idProperties[context][strings.ToLower(path[2])]
+		if len(path) == 3 {
+			return t
+		}
+		if len(path) == 4 && t == typeObject {
+			return typeString
+		}
+	case stringContexts[context] && len(path) == 2:
+		return typeString
+	}
+	return typeAny
+}
+
+// suggestion returns a "did you mean" hint naming the candidate closest to
+// name, or an empty string when none is close.
+func suggestion(name string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		distance := editDistance(strings.ToLower(name), strings.ToLower(candidate))
+		if distance < bestDistance || distance == bestDistance && candidate < best {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if best == "" || bestDistance >= len(name) {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %s?)", best)
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func keys[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,42 @@
+package checks
+
+import (
+	"slices"
+	"testing"
+
+	"ghactionscheck/pkg/expression"
+)
+
+func TestExpressionProblems(t *testing.T) {
+	tests := []struct {
+		src      string
+		problems []string
+	}{
+		{src: "github.ref == 'refs/heads/main'"},
+		{src: "GitHub.Event_Name"},
+		{src: "github['event_name']"},
+		{src: "steps.build.outputs.version"},
+		{src: "matrix[inputs.key]"},
+		{src: "secrets.TOKEN && env.CI && vars.NAME"},
+		{src: "github.refs", problems: []string{"unknown property refs of the github context (did you mean ref?)"}},
+		{src: "github['refs']", problems: []string{"unknown property refs of the github context (did you mean ref?)"}},
+		{src: "gihtub.ref", problems: []string{"unknown context gihtub (did you mean github?)"}},
+		{src: "steps.build.output", problems: []string{"unknown property output of steps.build (did you mean outputs?)"}},
+		{src: "contain(github.ref, 'main')", problems: []string{"unknown function contain (did you mean contains?)"}},
+		{src: "contains(github.ref)", problems: []string{"contains takes at least 2 arguments, got 1"}},
+		{src: "success(github.ref)", problems: []string{"success takes at most 0 arguments, got 1"}},
+		{src: "format('{0} {1}', github.ref)", problems: []string{"the format string uses {1}, but there is no argument for it"}},
+		{src: "format('{{1}} {0}', github.ref)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			tree, err := expression.Parse(tt.src)
+			if err != nil {
+				t.Fatalf("Parse(%q) = %v", tt.src, err)
+			}
+			if problems := expressionProblems(tree); !slices.Equal(problems, tt.problems) {
+				t.Errorf("expressionProblems(%q) = %q, want %q", tt.src, problems, tt.problems)
+			}
+		})
+	}
+}
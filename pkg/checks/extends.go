@@ -0,0 +1,307 @@
+package checks
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ghactionscheck/pkg/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+// maxExtendsDepth limits the chain of configs extending each other.
+const maxExtendsDepth = 10
+
+// extendsTimeout limits fetching a config extended by URL.
+const extendsTimeout = 30 * time.Second
+
+// resolveExtends returns the contents of the config at location, read as
+// data, merged onto the config it extends with its extends key, if any. The
+// extended config is a path, relative to the directory of the config, or an
+// https URL, and can extend another itself. A config fetched by URL can't
+// have command plugins, nor WASM plugins with relative paths, as they would
+// run what whoever serves it chooses or resolve against the current
+// directory.
+//
+// The checks of the configs are merged by id and the plugins by name, the
+// ignore entries of both are kept, and other mappings are merged key by key
+// with the values of the extending config replacing the others. A check can
+// so change the severity or an option of a check in the extended config
+// without repeating the rest.
+func resolveExtends(data []byte, location string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		// Reported when parsing the config.
+		return data, nil
+	}
+	if _, extends := workflow.LookupKey(configRoot(&doc), "extends"); extends == nil {
+		return data, nil
+	}
+	if abs, err := filepath.Abs(location); err == nil {
+		location = abs
+	}
+	merged, err := loadExtended(&doc, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(merged)
+}
+
+// configRoot returns the mapping at the root of a config document, or nil.
+func configRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 && doc.Content[0].Kind == yaml.MappingNode {
+		return doc.Content[0]
+	}
+	return nil
+}
+
+// loadExtended merges the config document doc, read from location, onto
+// the configs it extends. chain lists the configs extending it, to report
+// cycles.
+func loadExtended(doc *yaml.Node, location string, chain []string) (*yaml.Node, error) {
+	root := configRoot(doc)
+	if root == nil {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	if isURL(location) {
+		if err := checkURLPlugins(root, location); err != nil {
+			return nil, err
+		}
+	} else {
+		resolvePluginPaths(root, filepath.Dir(location))
+	}
+	_, extends := workflow.LookupKey(root, "extends")
+	if extends == nil {
+		return root, nil
+	}
+	removeConfigKey(root, "extends")
+	if extends.Kind != yaml.ScalarNode || extends.Value == "" {
+		return nil, fmt.Errorf("extends in %s must be a path or URL", location)
+	}
+
+	base := resolveLocation(location, extends.Value)
+	if strings.HasPrefix(base, "http://") {
+		return nil, fmt.Errorf("extended config %s must be fetched with https", base)
+	}
+	chain = append(chain, location)
+	for _, extending := range chain {
+		if extending == base {
+			return nil, fmt.Errorf("%s extends itself through %s", base, strings.Join(chain, " -> "))
+		}
+	}
+	if len(chain) > maxExtendsDepth {
+		return nil, fmt.Errorf("more than %d configs extend each other from %s", maxExtendsDepth, chain[0])
+	}
+
+	data, err := readExtended(base)
+	if err != nil {
+		return nil, fmt.Errorf("error reading extended config %s: %v", base, err)
+	}
+	var baseDoc yaml.Node
+	if err := yaml.Unmarshal(data, &baseDoc); err != nil {
+		return nil, fmt.Errorf("error parsing extended config %s: %v", base, err)
+	}
+	baseRoot, err := loadExtended(&baseDoc, base, chain)
+	if err != nil {
+		return nil, err
+	}
+	return mergeConfig(baseRoot, root), nil
+}
+
+// mergeConfig merges the root mapping of a config onto that of the config
+// it extends.
+func mergeConfig(base, override *yaml.Node) *yaml.Node {
+	merged := mergeNode(base, override)
+	for _, list := range []struct{ key, id string }{{"checks", "id"}, {"plugins", "name"}} {
+		_, baseList := workflow.LookupKey(base, list.key)
+		_, overrideList := workflow.LookupKey(override, list.key)
+		if baseList != nil && overrideList != nil {
+			setConfigKey(merged, list.key, mergeList(baseList, overrideList, list.id))
+		}
+	}
+	_, baseIgnore := workflow.LookupKey(base, "ignore")
+	_, overrideIgnore := workflow.LookupKey(override, "ignore")
+	if baseIgnore != nil && overrideIgnore != nil && baseIgnore.Kind == yaml.SequenceNode && overrideIgnore.Kind == yaml.SequenceNode {
+		ignore := *overrideIgnore
+		ignore.Content = append(append([]*yaml.Node(nil), baseIgnore.Content...), overrideIgnore.Content...)
+		setConfigKey(merged, "ignore", &ignore)
+	}
+	return merged
+}
+
+// mergeNode merges mappings key by key, and otherwise returns override.
+func mergeNode(base, override *yaml.Node) *yaml.Node {
+	if base.Kind != yaml.MappingNode || override.Kind != yaml.MappingNode {
+		return override
+	}
+	merged := *base
+	merged.Content = append([]*yaml.Node(nil), base.Content...)
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		if _, baseValue := workflow.LookupKey(&merged, key.Value); baseValue != nil {
+			value = mergeNode(baseValue, value)
+		}
+		setConfigKey(&merged, key.Value, value)
+	}
+	return &merged
+}
+
+// mergeList merges the items of two sequences of mappings identified by
+// their key id, appending the items only in override.
+func mergeList(base, override *yaml.Node, id string) *yaml.Node {
+	if base.Kind != yaml.SequenceNode || override.Kind != yaml.SequenceNode {
+		return override
+	}
+	merged := *base
+	merged.Content = append([]*yaml.Node(nil), base.Content...)
+	index := make(map[string]int)
+	for i, item := range merged.Content {
+		if key := listItemID(item, id); key != "" {
+			index[key] = i
+		}
+	}
+	for _, item := range override.Content {
+		key := listItemID(item, id)
+		if i, ok := index[key]; ok && key != "" {
+			merged.Content[i] = mergeNode(merged.Content[i], item)
+			continue
+		}
+		merged.Content = append(merged.Content, item)
+	}
+	return &merged
+}
+
+// listItemID returns the value of the key id of an item of the checks or
+// plugins, with the ids of renamed checks updated.
+func listItemID(item *yaml.Node, id string) string {
+	_, value := workflow.LookupKey(item, id)
+	if value == nil {
+		return ""
+	}
+	if id == "id" {
+		return CanonicalID(value.Value)
+	}
+	return value.Value
+}
+
+// setConfigKey sets the value of key in a mapping, adding the key if it is
+// missing.
+func setConfigKey(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// removeConfigKey removes key from a mapping.
+func removeConfigKey(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// resolvePluginPaths makes the relative paths of the plugins of a config
+// absolute, resolving them against dir, the directory of the config, as
+// they would be without the config being extended.
+func resolvePluginPaths(root *yaml.Node, dir string) {
+	_, plugins := workflow.LookupKey(root, "plugins")
+	if plugins == nil {
+		return
+	}
+	for _, plugin := range plugins.Content {
+		var paths []*yaml.Node
+		if _, command := workflow.LookupKey(plugin, "command"); command != nil && command.Kind == yaml.SequenceNode && len(command.Content) > 0 &&
+			strings.ContainsRune(command.Content[0].Value, filepath.Separator) {
+			paths = append(paths, command.Content[0])
+		}
+		if _, wasm := workflow.LookupKey(plugin, "wasm"); wasm != nil && wasm.Kind == yaml.ScalarNode {
+			paths = append(paths, wasm)
+		}
+		for _, path := range paths {
+			if !filepath.IsAbs(path.Value) {
+				if abs, err := filepath.Abs(filepath.Join(dir, path.Value)); err == nil {
+					path.Value = abs
+				}
+			}
+		}
+	}
+}
+
+// checkURLPlugins reports an error for the plugins of a config fetched from
+// location that can't be used from a URL: those running commands, and WASM
+// modules with relative paths.
+func checkURLPlugins(root *yaml.Node, location string) error {
+	_, plugins := workflow.LookupKey(root, "plugins")
+	if plugins == nil {
+		return nil
+	}
+	for _, plugin := range plugins.Content {
+		name := listItemID(plugin, "name")
+		if _, command := workflow.LookupKey(plugin, "command"); command != nil {
+			return fmt.Errorf("plugin %s of extended config %s runs a command, which a config fetched by URL can't", name, location)
+		}
+		if _, wasm := workflow.LookupKey(plugin, "wasm"); wasm != nil && !filepath.IsAbs(wasm.Value) {
+			return fmt.Errorf("plugin %s of extended config %s has a relative wasm path %q, which can't be resolved for a config fetched by URL", name, location, wasm.Value)
+		}
+	}
+	return nil
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://")
+}
+
+// resolveLocation resolves the location of an extended config against that
+// of the config extending it.
+func resolveLocation(from, ref string) string {
+	if isURL(ref) {
+		return ref
+	}
+	if isURL(from) {
+		base, err := url.Parse(from)
+		if err != nil {
+			return ref
+		}
+		refURL, err := url.Parse(ref)
+		if err != nil {
+			return ref
+		}
+		return base.ResolveReference(refURL).String()
+	}
+	if !filepath.IsAbs(ref) {
+		ref = filepath.Join(filepath.Dir(from), ref)
+	}
+	if abs, err := filepath.Abs(ref); err == nil {
+		return abs
+	}
+	return ref
+}
+
+// readExtended returns the contents of the config at location, fetching it
+// when it is a URL.
+func readExtended(location string) ([]byte, error) {
+	if !isURL(location) {
+		return os.ReadFile(location)
+	}
+	client := &http.Client{Timeout: extendsTimeout}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", location, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,228 @@
+package checks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigs writes configs, by path relative to dir, and returns dir.
+func writeConfigs(t *testing.T, configs map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range configs {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadConfigExtends(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"org/base.yaml": `checks:
+  - id: timeout
+    severity: warning
+    options:
+      max_minutes: 60
+      default_minutes: 30
+  - id: permissions
+    severity: error
+plugins:
+  - name: org
+    wasm: org.wasm
+  - name: lint
+    command: ["./lint.sh", "--strict"]
+  - name: path
+    command: ["lint-tool"]
+ignore:
+  - files: ["vendor/**"]
+`,
+		"repo/.ghactionscheck.yaml": `extends: ../org/base.yaml
+checks:
+  - id: timeout
+    options:
+      max_minutes: 120
+  - id: step_name
+    severity: notice
+ignore:
+  - files: ["generated/**"]
+`,
+	})
+	config, err := LoadConfig(filepath.Join(dir, "repo", ConfigFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, check := range config.Checks {
+		ids = append(ids, check.ID)
+	}
+	if got, want := strings.Join(ids, " "), "timeout permissions step_name"; got != want {
+		t.Errorf("checks = %s, want %s", got, want)
+	}
+	timeout := config.Check("timeout")
+	if timeout == nil || timeout.Severity != "warning" || timeout.IntOption("max_minutes", 0) != 120 || timeout.IntOption("default_minutes", 0) != 30 {
+		t.Errorf("timeout = %+v, want the base check with max_minutes overridden", timeout)
+	}
+	if len(config.Ignore) != 2 {
+		t.Errorf("ignore = %+v, want the entries of both configs", config.Ignore)
+	}
+
+	paths := make(map[string]string)
+	for _, plugin := range config.Plugins {
+		if plugin.WASM != "" {
+			paths[plugin.Name] = plugin.path(plugin.WASM)
+		} else {
+			paths[plugin.Name] = plugin.commandPath(plugin.Command[0])
+		}
+	}
+	want := map[string]string{
+		"org":  filepath.Join(dir, "org", "org.wasm"),
+		"lint": filepath.Join(dir, "org", "lint.sh"),
+		"path": "lint-tool",
+	}
+	for name, path := range want {
+		if paths[name] != path {
+			t.Errorf("plugin %s runs %s, want %s", name, paths[name], path)
+		}
+	}
+}
+
+func TestLoadConfigPluginPaths(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		ConfigFileName: `plugins:
+  - name: bare
+    wasm: policy.wasm
+  - name: relative
+    wasm: ./rules/org.wasm
+  - name: absolute
+    wasm: /opt/rules/org.wasm
+  - name: script
+    command: ["./check.py"]
+  - name: tool
+    command: ["check-tool", "--json"]
+`,
+	})
+	config, err := LoadConfig(filepath.Join(dir, ConfigFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"bare":     filepath.Join(dir, "policy.wasm"),
+		"relative": filepath.Join(dir, "rules", "org.wasm"),
+		"absolute": "/opt/rules/org.wasm",
+		"script":   filepath.Join(dir, "check.py"),
+		"tool":     "check-tool",
+	}
+	for _, plugin := range config.Plugins {
+		var path string
+		if plugin.WASM != "" {
+			path = plugin.path(plugin.WASM)
+		} else {
+			path = plugin.commandPath(plugin.Command[0])
+		}
+		if path != want[plugin.Name] {
+			t.Errorf("plugin %s runs %s, want %s", plugin.Name, path, want[plugin.Name])
+		}
+	}
+}
+
+func TestLoadConfigExtendsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs map[string]string
+		err     string
+	}{
+		{
+			name:    "cycle",
+			configs: map[string]string{ConfigFileName: "extends: a.yaml\n", "a.yaml": "extends: b.yaml\n", "b.yaml": "extends: a.yaml\n"},
+			err:     "extends itself",
+		},
+		{
+			name:    "missing",
+			configs: map[string]string{ConfigFileName: "extends: missing.yaml\n"},
+			err:     "error reading extended config",
+		},
+		{
+			name:    "not a path",
+			configs: map[string]string{ConfigFileName: "extends: [a.yaml]\n"},
+			err:     "must be a path or URL",
+		},
+		{
+			name:    "http",
+			configs: map[string]string{ConfigFileName: "extends: http://example.com/base.yaml\n"},
+			err:     "must be fetched with https",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigs(t, tt.configs)
+			_, err := LoadConfig(filepath.Join(dir, ConfigFileName))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("LoadConfig = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigExtendsURL(t *testing.T) {
+	configs := map[string]string{
+		"/base.yaml":     "checks:\n  - id: timeout\n    severity: error\n",
+		"/command.yaml":  "plugins:\n  - name: lint\n    command: [\"lint-tool\"]\n",
+		"/relative.yaml": "plugins:\n  - name: org\n    wasm: org.wasm\n",
+		"/absolute.yaml": "plugins:\n  - name: org\n    wasm: /opt/rules/org.wasm\n",
+		"/chain.yaml":    "extends: base.yaml\n",
+		"/insecure.yaml": "extends: http://example.com/base.yaml\n",
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := configs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer server.Close()
+	transport := http.DefaultTransport
+	http.DefaultTransport = server.Client().Transport
+	defer func() { http.DefaultTransport = transport }()
+
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{path: "/base.yaml"},
+		{path: "/absolute.yaml"},
+		{path: "/chain.yaml"},
+		{path: "/command.yaml", err: "runs a command"},
+		{path: "/relative.yaml", err: "relative wasm path"},
+		{path: "/insecure.yaml", err: "must be fetched with https"},
+		{path: "/missing.yaml", err: "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			dir := writeConfigs(t, map[string]string{ConfigFileName: "extends: " + server.URL + tt.path + "\n"})
+			config, err := LoadConfig(filepath.Join(dir, ConfigFileName))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("LoadConfig = %v, want an error containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.path != "/absolute.yaml" && (config.Check("timeout") == nil || config.Check("timeout").Severity != "error") {
+				t.Errorf("timeout = %+v, want the check of the extended config", config.Check("timeout"))
+			}
+		})
+	}
+}
@@ -0,0 +1,546 @@
+package checks
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ghactionscheck/pkg/workflow"
+)
+
+const githubAPIURL = "https://api.github.com"
+
+var errNotFound = errors.New("not found")
+
+// ErrNoWorkflowFiles is returned by WorkflowFiles for a repository without
+// workflows.
+var ErrNoWorkflowFiles = errors.New("no workflow files")
+
+// versionPattern matches version tags such as v4, v4.1 and 4.1.2, capturing
+// the major version.
+var versionPattern = regexp.MustCompile(`^v?(\d+)(\.\d+){0,2}$`)
+
+// GitHubClient queries the GitHub REST API for the online checks. Results
+// are cached for the lifetime of the client, since the same actions are
+// usually referenced by many workflows. A client can be used from several
+// goroutines.
+type GitHubClient struct {
+	baseURL  string
+	token    string
+	http     *http.Client
+	cacheDir string
+
+	// mu guards the caches and rateLimit, the state of the rate limit sent
+	// with the last response.
+	mu             sync.Mutex
+	rateLimit      RateLimit
+	latestVersions map[string]string
+	actions        map[string]*workflow.Action
+	repositories   map[string]*Repository
+	tagLists       map[string][]tag
+	secretLists    map[string]map[string]bool
+}
+
+type tag struct {
+	Name   string `json:"name"`
+	Commit struct {
+		SHA string `json:"sha"`
+	} `json:"commit"`
+}
+
+// Repository holds the state of a repository. Missing is set when the
+// repository doesn't exist or isn't visible with the token.
+type Repository struct {
+	FullName      string `json:"full_name"`
+	Archived      bool   `json:"archived"`
+	Fork          bool   `json:"fork"`
+	DefaultBranch string `json:"default_branch"`
+	Missing       bool   `json:"-"`
+}
+
+// NewGitHubClient returns a client authenticating with token, or making
+// anonymous requests when it is empty.
+func NewGitHubClient(token string) *GitHubClient {
+	return &GitHubClient{
+		baseURL:        githubAPIURL,
+		token:          token,
+		http:           &http.Client{Timeout: 30 * time.Second},
+		latestVersions: make(map[string]string),
+		actions:        make(map[string]*workflow.Action),
+		repositories:   make(map[string]*Repository),
+		tagLists:       make(map[string][]tag),
+		secretLists:    make(map[string]map[string]bool),
+	}
+}
+
+// get requests path from the API and decodes the JSON response into v.
+func (c *GitHubClient) get(path string, v interface{}) error {
+	return c.do(http.MethodGet, path, nil, v)
+}
+
+// do sends a request with method to path, with body encoded as JSON unless
+// it is nil, and decodes the JSON response into v unless it is nil.
+func (c *GitHubClient) do(method, path string, body, v interface{}) error {
+	req, err := c.newRequest(method, path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return httpError(method, path, resp)
+	case v == nil:
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// httpError returns the error for a response with an unsuccessful status.
+func httpError(method, path string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+}
+
+// newRequest returns an authenticated request with method for path, with
+// body encoded as JSON unless it is nil.
+func (c *GitHubClient) newRequest(method, path string, body interface{}) (*http.Request, error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// latestVersion returns the latest version tag of repo ("owner/name"): the
+// tag of the latest release, or the highest version tag if the repository
+// has no releases. A failed lookup returns its error only the first time, so
+// it is reported once.
+func (c *GitHubClient) latestVersion(repo string) (string, error) {
+	c.mu.Lock()
+	version, ok := c.latestVersions[repo]
+	c.mu.Unlock()
+	if ok {
+		return version, nil
+	}
+
+	version, err := c.fetchLatestVersion(repo)
+	c.mu.Lock()
+	c.latestVersions[repo] = version
+	c.mu.Unlock()
+	return version, err
+}
+
+func (c *GitHubClient) fetchLatestVersion(repo string) (string, error) {
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	err := c.cachedGet("/repos/"+repo+"/releases/latest", cacheTTL, &release)
+	if err == nil {
+		return release.TagName, nil
+	}
+	if err != errNotFound {
+		return "", err
+	}
+
+	tags, err := c.tags(repo)
+	if err != nil {
+		return "", err
+	}
+	var versions []string
+	for _, tag := range tags {
+		if versionPattern.MatchString(tag.Name) {
+			versions = append(versions, tag.Name)
+		}
+	}
+	if len(versions) == 0 {
+		return "", nil
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) > 0
+	})
+	return versions[0], nil
+}
+
+// tags returns the most recent tags of repo.
+func (c *GitHubClient) tags(repo string) ([]tag, error) {
+	c.mu.Lock()
+	tags, ok := c.tagLists[repo]
+	c.mu.Unlock()
+	if ok {
+		return tags, nil
+	}
+
+	if err := c.cachedGet("/repos/"+repo+"/tags?per_page=100", cacheTTL, &tags); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.tagLists[repo] = tags
+	c.mu.Unlock()
+	return tags, nil
+}
+
+// CommitSHA returns the commit hash ref of repo points to.
+func (c *GitHubClient) CommitSHA(repo, ref string) (string, error) {
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	if err := c.cachedGet("/repos/"+repo+"/commits/"+url.PathEscape(ref), refCacheTTL(ref), &commit); err != nil {
+		return "", err
+	}
+	return commit.SHA, nil
+}
+
+// VersionOf returns the most specific version tag of repo pointing to sha,
+// so a ref such as v4 is described as v4.1.1. It falls back to ref.
+func (c *GitHubClient) VersionOf(repo, ref, sha string) string {
+	tags, err := c.tags(repo)
+	if err != nil {
+		return ref
+	}
+	best := ""
+	for _, t := range tags {
+		if t.Commit.SHA == sha && versionPattern.MatchString(t.Name) && (best == "" || moreSpecificVersion(t.Name, best)) {
+			best = t.Name
+		}
+	}
+	if best == "" {
+		return ref
+	}
+	return best
+}
+
+// moreSpecificVersion reports whether version a has more components than b,
+// or is higher with as many components.
+func moreSpecificVersion(a, b string) bool {
+	if na, nb := strings.Count(a, "."), strings.Count(b, "."); na != nb {
+		return na > nb
+	}
+	return compareVersions(a, b) > 0
+}
+
+// action returns the metadata of the action referenced by uses
+// ("owner/name[/path]@ref"). Like latestVersion, a failure is returned once.
+func (c *GitHubClient) action(uses string) (*workflow.Action, error) {
+	c.mu.Lock()
+	action, ok := c.actions[uses]
+	c.mu.Unlock()
+	if ok {
+		return action, nil
+	}
+
+	action, err := c.fetchAction(uses)
+	c.mu.Lock()
+	c.actions[uses] = action
+	c.mu.Unlock()
+	return action, err
+}
+
+func (c *GitHubClient) fetchAction(uses string) (*workflow.Action, error) {
+	repo, ref, ok := ActionRepo(uses)
+	if !ok {
+		return nil, fmt.Errorf("invalid action reference %s", uses)
+	}
+	dir := strings.TrimPrefix(strings.TrimPrefix(strings.SplitN(uses, "@", 2)[0], repo), "/")
+
+	for _, name := range workflow.ActionFileNames {
+		data, err := c.fileContent(repo, path.Join(dir, name), ref, refCacheTTL(ref))
+		if err == errNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return workflow.ParseAction(data)
+	}
+	return nil, fmt.Errorf("no action.yml in %s", uses)
+}
+
+// contentsPath returns the API path of the contents of file in repo at ref.
+func contentsPath(repo, file, ref string) string {
+	return "/repos/" + repo + "/contents/" + file + "?ref=" + url.QueryEscape(ref)
+}
+
+// fileContent returns the content of file in repo at ref, cached for ttl. A
+// zero ttl revalidates the cached content on each run.
+func (c *GitHubClient) fileContent(repo, file, ref string, ttl time.Duration) ([]byte, error) {
+	var content struct {
+		Content string `json:"content"`
+	}
+	if err := c.cachedGet(contentsPath(repo, file, ref), ttl, &content); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(content.Content)
+}
+
+// refCacheTTL returns how long the responses for ref are cached: a commit
+// hash always points to the same contents, unlike a branch or tag.
+func refCacheTTL(ref string) time.Duration {
+	if commitHashPattern.MatchString(ref) {
+		return immutableCacheTTL
+	}
+	return cacheTTL
+}
+
+// RepositoryFile is a file fetched from a repository.
+type RepositoryFile struct {
+	// Path is the path of the file in the repository.
+	Path    string
+	Content []byte
+}
+
+// WorkflowFiles returns the workflow files in .github/workflows of repo
+// ("owner/name") at ref.
+func (c *GitHubClient) WorkflowFiles(repo, ref string) ([]RepositoryFile, error) {
+	var entries []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}
+	err := c.cachedGet(contentsPath(repo, ".github/workflows", ref), 0, &entries)
+	if err == errNotFound {
+		return nil, fmt.Errorf("%w in %s@%s", ErrNoWorkflowFiles, repo, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []RepositoryFile
+	for _, entry := range entries {
+		if ext := path.Ext(entry.Path); entry.Type != "file" || ext != ".yml" && ext != ".yaml" {
+			continue
+		}
+		content, err := c.fileContent(repo, entry.Path, ref, 0)
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s: %v", entry.Path, err)
+		}
+		files = append(files, RepositoryFile{Path: entry.Path, Content: content})
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w in %s@%s", ErrNoWorkflowFiles, repo, ref)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// DefaultBranch returns the default branch of repo ("owner/name").
+func (c *GitHubClient) DefaultBranch(repo string) (string, error) {
+	r, err := c.repository(repo)
+	if err != nil {
+		return "", err
+	}
+	if r == nil || r.Missing {
+		return "", fmt.Errorf("repository %s not found", repo)
+	}
+	return r.DefaultBranch, nil
+}
+
+// repository returns the state of repo ("owner/name"). Like latestVersion, a
+// failure is returned once.
+func (c *GitHubClient) repository(repo string) (*Repository, error) {
+	c.mu.Lock()
+	r, ok := c.repositories[repo]
+	c.mu.Unlock()
+	if ok {
+		return r, nil
+	}
+
+	r = &Repository{}
+	err := c.cachedGet("/repos/"+repo, cacheTTL, r)
+	if err == errNotFound {
+		r.Missing, err = true, nil
+	}
+	if err != nil {
+		r = nil
+	}
+	c.mu.Lock()
+	c.repositories[repo] = r
+	c.mu.Unlock()
+	return r, err
+}
+
+// perPage is the page size of lists, the maximum the API allows.
+const perPage = 100
+
+// OrganizationRepositories returns the repositories of org visible with the
+// token.
+func (c *GitHubClient) OrganizationRepositories(org string) ([]Repository, error) {
+	var repos []Repository
+	for page := 1; ; page++ {
+		var batch []Repository
+		err := c.get(fmt.Sprintf("/orgs/%s/repos?per_page=%d&page=%d", url.PathEscape(org), perPage, page), &batch)
+		if err == errNotFound {
+			return nil, fmt.Errorf("organization %s not found", org)
+		}
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, batch...)
+		if len(batch) < perPage {
+			return repos, nil
+		}
+	}
+}
+
+// repositorySecrets returns the names of the secrets available to the
+// workflows of repo ("owner/name"): its own secrets, the organization
+// secrets it can access and its Dependabot secrets, which replace the others
+// in the runs Dependabot triggers. The names are in upper case, as GitHub
+// compares them case-insensitively. Listing secrets requires a token with
+// admin access to the repository. Like latestVersion, a failure is returned
+// once; the names are nil afterwards, as they are unknown.
+func (c *GitHubClient) repositorySecrets(repo string) (map[string]bool, error) {
+	return c.secretNames(repo, func() (map[string]bool, error) {
+		names, err := c.fetchSecretNames("/repos/" + repo + "/actions/secrets")
+		if err == errNotFound {
+			return nil, fmt.Errorf("repository %s not found", repo)
+		}
+		if err != nil {
+			return nil, err
+		}
+		// The lists are missing for repositories owned by users or without
+		// Dependabot.
+		for _, list := range []string{"/actions/organization-secrets", "/dependabot/secrets"} {
+			more, err := c.fetchSecretNames("/repos/" + repo + list)
+			if err != nil && err != errNotFound {
+				return nil, err
+			}
+			for name := range more {
+				names[name] = true
+			}
+		}
+		return names, nil
+	})
+}
+
+// environmentSecrets returns the names of the secrets of the environment of
+// repo, like repositorySecrets. An environment that doesn't exist yet has no
+// secrets, as GitHub creates it when a job first uses it.
+func (c *GitHubClient) environmentSecrets(repo, environment string) (map[string]bool, error) {
+	return c.secretNames(repo+"#"+environment, func() (map[string]bool, error) {
+		names, err := c.fetchSecretNames("/repos/" + repo + "/environments/" + url.PathEscape(environment) + "/secrets")
+		if err == errNotFound {
+			return map[string]bool{}, nil
+		}
+		return names, err
+	})
+}
+
+// secretNames returns the secret names cached under key, fetching them on
+// the first call.
+func (c *GitHubClient) secretNames(key string, fetch func() (map[string]bool, error)) (map[string]bool, error) {
+	c.mu.Lock()
+	names, ok := c.secretLists[key]
+	c.mu.Unlock()
+	if ok {
+		return names, nil
+	}
+
+	names, err := fetch()
+	c.mu.Lock()
+	c.secretLists[key] = names
+	c.mu.Unlock()
+	return names, err
+}
+
+// fetchSecretNames lists the secrets at path, returning errNotFound if the
+// list doesn't exist.
+func (c *GitHubClient) fetchSecretNames(path string) (map[string]bool, error) {
+	names := make(map[string]bool)
+	for page := 1; ; page++ {
+		var batch struct {
+			Secrets []struct {
+				Name string `json:"name"`
+			} `json:"secrets"`
+		}
+		if err := c.get(fmt.Sprintf("%s?per_page=%d&page=%d", path, perPage, page), &batch); err != nil {
+			return nil, err
+		}
+		for _, secret := range batch.Secrets {
+			names[strings.ToUpper(secret.Name)] = true
+		}
+		if len(batch.Secrets) < perPage {
+			return names, nil
+		}
+	}
+}
+
+// majorVersion returns the major version of a version tag.
+func majorVersion(version string) (int, bool) {
+	match := versionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return 0, false
+	}
+	major, err := strconv.Atoi(match[1])
+	return major, err == nil
+}
+
+// compareVersions compares two version tags numerically, returning a
+// negative number, zero or a positive number when a is lower than, equal to
+// or higher than b.
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// ActionRepo splits a step's uses reference into the action's repository
+// ("owner/name") and ref. ok is false for local and Docker actions.
+func ActionRepo(uses string) (repo, ref string, ok bool) {
+	if strings.HasPrefix(uses, "./") || strings.HasPrefix(uses, "docker://") {
+		return "", "", false
+	}
+	action, ref, found := strings.Cut(uses, "@")
+	if !found {
+		return "", "", false
+	}
+	parts := strings.SplitN(action, "/", 3)
+	if len(parts) < 2 {
+		return "", "", false
+	}
+	return parts[0] + "/" + parts[1], ref, true
+}
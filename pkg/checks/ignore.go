@@ -0,0 +1,125 @@
+package checks
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"ghactionscheck/pkg/report"
+)
+
+// Ignore disables a check for the files and jobs matching its patterns, so
+// that exceptions can be declared in the config rather than in comments in
+// each file. An empty Check ignores every check, and empty Files or Jobs
+// match every file or job.
+type Ignore struct {
+	Check string `yaml:"check"`
+	// Files are glob patterns of paths relative to the repository root, in
+	// which "**" matches any number of directories. A pattern without a
+	// slash matches the file name in any directory.
+	Files []string `yaml:"files"`
+	// Jobs are glob patterns of job ids; findings outside of jobs are in
+	// the job "workflow", or "action" in action metadata files.
+	Jobs []string `yaml:"jobs"`
+}
+
+// validate checks the patterns of an ignore entry.
+func (ig *Ignore) validate() error {
+	if ig.Check == "" && len(ig.Files) == 0 && len(ig.Jobs) == 0 {
+		return fmt.Errorf("no check, files or jobs")
+	}
+	for _, pattern := range slices.Concat(ig.Files, ig.Jobs) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+func (ig *Ignore) matches(file string, result report.Result) bool {
+	if ig.Check != "" && ig.Check != result.CheckID {
+		return false
+	}
+	if len(ig.Files) > 0 && !matchesAnyPath(ig.Files, file) {
+		return false
+	}
+	return len(ig.Jobs) == 0 || matchesAny(ig.Jobs, result.JobName)
+}
+
+// filterIgnored removes the results in file that an ignore entry of the
+// config matches.
+func (c *Checker) filterIgnored(file string, results []report.Result) []report.Result {
+	if len(c.ignore) == 0 {
+		return results
+	}
+	file = c.repoPath(file)
+	var filtered []report.Result
+	for _, result := range results {
+		ignored := false
+		for i := range c.ignore {
+			if c.ignore[i].matches(file, result) {
+				ignored = true
+				break
+			}
+		}
+		if !ignored {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// repoPath returns the slash-separated path of file relative to the root of
+// its repository.
+func (c *Checker) repoPath(file string) string {
+	if c.remote != nil {
+		return strings.TrimPrefix(file, c.remote.repo+"/")
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	rel, err := filepath.Rel(FindRepoRoot(file), abs)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// matchesAnyPath reports whether name matches one of the path patterns.
+func matchesAnyPath(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			pattern = "**/" + pattern
+		}
+		if matchPath(strings.Split(pattern, "/"), strings.Split(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath matches the segments of a path against those of a pattern, in
+// which "**" matches any number of segments.
+func matchPath(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchPath(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,232 @@
+package checks
+
+import (
+	"fmt"
+	"slices"
+
+	"ghactionscheck/pkg/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+// The keys GitHub accepts in each mapping of workflows and actions. A
+// misspelled key is ignored or rejected by GitHub, and hides the key from
+// the other checks too.
+var (
+	workflowKeys    = []string{"name", "run-name", "on", "permissions", "env", "defaults", "concurrency", "jobs"}
+	defaultsKeys    = []string{"run"}
+	runDefaultsKeys = []string{"shell", "working-directory"}
+	concurrencyKeys = []string{"group", "cancel-in-progress", "queue"}
+
+	jobKeys = []string{
+		"name", "permissions", "needs", "if", "runs-on", "snapshot", "environment", "concurrency",
+		"outputs", "env", "defaults", "steps", "timeout-minutes", "strategy",
+		"continue-on-error", "container", "services",
+	}
+	// reusableJobKeys are the keys of jobs calling a reusable workflow.
+	reusableJobKeys = []string{"name", "uses", "with", "secrets", "needs", "if", "permissions", "concurrency", "strategy"}
+	strategyKeys    = []string{"matrix", "fail-fast", "max-parallel"}
+	environmentKeys = []string{"name", "url", "deployment"}
+	containerKeys   = []string{"image", "credentials", "env", "ports", "volumes", "options"}
+	serviceKeys     = []string{"image", "credentials", "env", "ports", "volumes", "options", "command", "entrypoint"}
+	stepKeys        = []string{
+		"id", "if", "name", "uses", "run", "working-directory", "shell", "with", "env",
+		"continue-on-error", "timeout-minutes", "background", "wait", "wait-all", "cancel", "parallel",
+	}
+
+	dispatchInputKeys = []string{"description", "required", "default", "type", "options"}
+	callKeys          = []string{"inputs", "outputs", "secrets"}
+	callInputKeys     = []string{"description", "required", "default", "type"}
+	callOutputKeys    = []string{"description", "value"}
+	callSecretKeys    = []string{"description", "required"}
+
+	actionKeys       = []string{"name", "author", "description", "inputs", "outputs", "runs", "branding"}
+	actionRunsKeys   = []string{"using", "main", "pre", "pre-if", "post", "post-if", "steps", "image", "env", "entrypoint", "pre-entrypoint", "post-entrypoint", "args"}
+	actionInputKeys  = []string{"description", "required", "default", "deprecationMessage"}
+	actionOutputKeys = []string{"description", "value"}
+	brandingKeys     = []string{"icon", "color"}
+	// compositeStepKeys are the keys of the steps of composite actions.
+	compositeStepKeys = []string{"id", "if", "name", "uses", "run", "working-directory", "shell", "with", "env", "continue-on-error"}
+)
+
+// eventKeys lists the events that can trigger workflows, with the keys of
+// their configuration.
+var eventKeys = map[string][]string{
+	"branch_protection_rule":      {"types"},
+	"check_run":                   {"types"},
+	"check_suite":                 {"types"},
+	"create":                      nil,
+	"delete":                      nil,
+	"deployment":                  nil,
+	"deployment_status":           nil,
+	"discussion":                  {"types"},
+	"discussion_comment":          {"types"},
+	"fork":                        nil,
+	"gollum":                      nil,
+	"issue_comment":               {"types"},
+	"issues":                      {"types"},
+	"label":                       {"types"},
+	"merge_group":                 {"types"},
+	"milestone":                   {"types"},
+	"page_build":                  nil,
+	"project":                     {"types"},
+	"project_card":                {"types"},
+	"project_column":              {"types"},
+	"public":                      nil,
+	"pull_request":                {"types", "branches", "branches-ignore", "paths", "paths-ignore"},
+	"pull_request_review":         {"types"},
+	"pull_request_review_comment": {"types"},
+	"pull_request_target":         {"types", "branches", "branches-ignore", "paths", "paths-ignore"},
+	"push":                        {"branches", "branches-ignore", "tags", "tags-ignore", "paths", "paths-ignore"},
+	"registry_package":            {"types"},
+	"release":                     {"types"},
+	"repository_dispatch":         {"types"},
+	"schedule":                    nil,
+	"status":                      nil,
+	"watch":                       {"types"},
+	"workflow_call":               callKeys,
+	"workflow_dispatch":           {"inputs"},
+	"workflow_run":                {"types", "workflows", "branches", "branches-ignore"},
+}
+
+// checkUnknownKeys reports the keys of a workflow that GitHub doesn't know,
+// suggesting the closest known key.
+func checkUnknownKeys(r *reporter, w *workflow.Workflow) {
+	checkKeys(r, "workflow", w.Node, "(root)", workflowKeys)
+	checkDefaultsKeys(r, "workflow", w.Node, "defaults")
+	_, concurrency := workflow.LookupKey(w.Node, "concurrency")
+	checkKeys(r, "workflow", concurrency, "concurrency", concurrencyKeys)
+	checkEventKeys(r, w)
+
+	for _, jobName := range w.JobNames() {
+		job := w.Jobs[jobName]
+		path := "jobs." + jobName
+		if job.Uses != "" {
+			checkKeys(r, jobName, job.Node, path, reusableJobKeys)
+		} else {
+			checkKeys(r, jobName, job.Node, path, jobKeys)
+		}
+
+		checkDefaultsKeys(r, jobName, job.Node, path+".defaults")
+		for key, known := range map[string][]string{
+			"strategy":    strategyKeys,
+			"concurrency": concurrencyKeys,
+			"environment": environmentKeys,
+			"container":   containerKeys,
+		} {
+			_, value := workflow.LookupKey(job.Node, key)
+			checkKeys(r, jobName, value, path+"."+key, known)
+		}
+		_, services := workflow.LookupKey(job.Node, "services")
+		checkEachKeys(r, jobName, services, path+".services", serviceKeys)
+		for i, step := range job.Steps {
+			checkKeys(r, jobName, step.Node, fmt.Sprintf("%s.steps.%d", path, i), stepKeys)
+		}
+	}
+}
+
+// checkEventKeys reports unknown events and unknown keys in the
+// configuration of the known ones. A bare on: has no event to report, and
+// is left to the schema check.
+func checkEventKeys(r *reporter, w *workflow.Workflow) {
+	onKey, on := workflow.LookupKey(w.Node, "on")
+	for _, event := range workflow.ScalarNodes(on) {
+		if event.Tag == "!!null" || event.Value == "" {
+			continue
+		}
+		if _, ok := eventKeys[event.Value]; !ok {
+			reportUnknownKey(r, "workflow", event, "on", keys(eventKeys))
+		}
+	}
+	if onKey == nil || on.Kind != yaml.MappingNode {
+		return
+	}
+
+	checkKeys(r, "workflow", on, "on", keys(eventKeys))
+	for i := 0; i+1 < len(on.Content); i += 2 {
+		event, config := on.Content[i].Value, on.Content[i+1]
+		path := "on." + event
+		switch known, ok := eventKeys[event]; {
+		case event == "schedule" && config.Kind == yaml.SequenceNode:
+			for j, item := range config.Content {
+				checkKeys(r, "workflow", item, fmt.Sprintf("%s.%d", path, j), []string{"cron"})
+			}
+		case ok:
+			checkKeys(r, "workflow", config, path, known)
+		}
+	}
+
+	_, dispatch := workflow.LookupKey(on, "workflow_dispatch")
+	_, dispatchInputs := workflow.LookupKey(dispatch, "inputs")
+	checkEachKeys(r, "workflow", dispatchInputs, "on.workflow_dispatch.inputs", dispatchInputKeys)
+	_, call := workflow.LookupKey(on, "workflow_call")
+	for key, known := range map[string][]string{
+		"inputs":  callInputKeys,
+		"outputs": callOutputKeys,
+		"secrets": callSecretKeys,
+	} {
+		_, value := workflow.LookupKey(call, key)
+		checkEachKeys(r, "workflow", value, "on.workflow_call."+key, known)
+	}
+}
+
+// checkDefaultsKeys checks the defaults of a workflow or job.
+func checkDefaultsKeys(r *reporter, jobName string, parent *yaml.Node, path string) {
+	_, defaults := workflow.LookupKey(parent, "defaults")
+	checkKeys(r, jobName, defaults, path, defaultsKeys)
+	_, run := workflow.LookupKey(defaults, "run")
+	checkKeys(r, jobName, run, path+".run", runDefaultsKeys)
+}
+
+// checkActionKeys reports the keys of an action's metadata file that
+// GitHub doesn't know.
+func checkActionKeys(r *reporter, action *workflow.Action) {
+	checkKeys(r, actionJobName, action.Node, "(root)", actionKeys)
+	_, runs := workflow.LookupKey(action.Node, "runs")
+	checkKeys(r, actionJobName, runs, "runs", actionRunsKeys)
+	_, inputs := workflow.LookupKey(action.Node, "inputs")
+	checkEachKeys(r, actionJobName, inputs, "inputs", actionInputKeys)
+	_, outputs := workflow.LookupKey(action.Node, "outputs")
+	checkEachKeys(r, actionJobName, outputs, "outputs", actionOutputKeys)
+	_, branding := workflow.LookupKey(action.Node, "branding")
+	checkKeys(r, actionJobName, branding, "branding", brandingKeys)
+	for i, step := range action.Runs.Steps {
+		checkKeys(r, actionJobName, step.Node, fmt.Sprintf("runs.steps.%d", i), compositeStepKeys)
+	}
+}
+
+// checkKeys reports the keys of node not in known, if node is a mapping.
+// Its values are expressions or strings otherwise, checked elsewhere.
+func checkKeys(r *reporter, jobName string, node *yaml.Node, path string, known []string) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if key := node.Content[i]; !slices.Contains(known, key.Value) {
+			reportUnknownKey(r, jobName, key, path, known)
+		}
+	}
+}
+
+// checkEachKeys checks the keys of each value of a mapping with
+// user-defined keys, such as the inputs of a workflow.
+func checkEachKeys(r *reporter, jobName string, node *yaml.Node, path string, known []string) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		checkKeys(r, jobName, node.Content[i+1], path+"."+node.Content[i].Value, known)
+	}
+}
+
+// reportUnknownKey reports key, remembering it so schema violations don't
+// report it again.
+func reportUnknownKey(r *reporter, jobName string, key *yaml.Node, path string, known []string) {
+	if r.check("unknown_key") == nil {
+		return
+	}
+	if r.unknownKeys == nil {
+		r.unknownKeys = make(map[*yaml.Node]bool)
+	}
+	r.unknownKeys[key] = true
+	r.report("unknown_key", jobName, key, field{"Key", key.Value}, field{"Path", path}, field{"Suggestion", suggestion(key.Value, known)})
+}
@@ -0,0 +1,112 @@
+package checks
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Locale is the message and detail of a check in a language.
+type Locale struct {
+	Message string `yaml:"message,omitempty"`
+	Detail  string `yaml:"detail,omitempty"`
+}
+
+// DefaultLanguage is the language of the messages in the checks config.
+const DefaultLanguage = "en"
+
+//go:embed locales/*.yaml
+var localeFiles embed.FS
+
+// Language returns the language of a locale such as ja_JP.UTF-8, as set in
+// $LANG, or DefaultLanguage for the C and POSIX locales.
+func Language(locale string) string {
+	lang, _, _ := strings.Cut(locale, ".")
+	lang, _, _ = strings.Cut(lang, "@")
+	lang, _, _ = strings.Cut(strings.ReplaceAll(lang, "-", "_"), "_")
+	lang = strings.ToLower(lang)
+	if lang == "" || lang == "c" || lang == "posix" {
+		return DefaultLanguage
+	}
+	return lang
+}
+
+// Localize replaces the messages and details of the checks with those in
+// the language of locale, as given in their locales in the config. A
+// built-in check keeping its message or detail from checks.yaml gets the
+// built-in translation, if there is one, and the rest stay as they are.
+func (c *Config) Localize(locale string) error {
+	lang := Language(locale)
+	translations, err := builtinLocale(lang)
+	if err != nil {
+		return err
+	}
+	for i := range c.Checks {
+		if err := localize(&c.Checks[i], lang, translations); err != nil {
+			return err
+		}
+	}
+	c.lang = lang
+	return nil
+}
+
+// localize sets the message and detail of check in lang, from its locales
+// or else from translations when it keeps those of the built-in check.
+func localize(check *Check, lang string, translations map[string]Locale) error {
+	localized := check.Locales[lang]
+	if builtin, ok := builtinChecks()[check.ID]; ok {
+		if localized.Message == "" && check.Message == builtin.Message {
+			localized.Message = translations[check.ID].Message
+		}
+		if localized.Detail == "" && check.Detail == builtin.Detail {
+			localized.Detail = translations[check.ID].Detail
+		}
+	}
+	if localized.Message != "" {
+		if check.sourceMessage == "" {
+			check.sourceMessage, check.sourceTemplate = check.Message, check.template
+		}
+		check.Message = localized.Message
+		if err := check.compileMessage(); err != nil {
+			return fmt.Errorf("error in check %s: %s locale: %v", check.ID, lang, err)
+		}
+	}
+	if localized.Detail != "" {
+		check.Detail = localized.Detail
+	}
+	return nil
+}
+
+// builtinLocale returns the built-in translations of the checks to lang,
+// by check id, or nil when there are none.
+func builtinLocale(lang string) (map[string]Locale, error) {
+	data, err := localeFiles.ReadFile("locales/" + lang + ".yaml")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var translations map[string]Locale
+	if err := yaml.Unmarshal(data, &translations); err != nil {
+		return nil, fmt.Errorf("error parsing %s messages: %v", lang, err)
+	}
+	return translations, nil
+}
+
+// builtinChecks returns the checks of the built-in config by id.
+var builtinChecks = sync.OnceValue(func() map[string]Check {
+	config, err := parseConfig(defaultConfig, "")
+	if err != nil {
+		panic(err)
+	}
+	checks := make(map[string]Check, len(config.Checks))
+	for _, check := range config.Checks {
+		checks[check.ID] = check
+	}
+	return checks
+})
@@ -0,0 +1,115 @@
+package checks
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"ghactionscheck/pkg/report"
+)
+
+// field is a named value of a finding, which the message of its check
+// refers to as {{.Name}}.
+type field struct {
+	name  string
+	value interface{}
+}
+
+// isTemplate reports whether a message is a template rather than a message
+// with printf verbs, as written by configs before templates were supported.
+func isTemplate(message string) bool {
+	return strings.Contains(message, "{{")
+}
+
+// compileMessage parses the message of a check when it is a template.
+// Referring to a field the finding doesn't have is an error when the
+// message is formatted.
+func (c *Check) compileMessage() error {
+	c.template = nil
+	if !isTemplate(c.Message) {
+		return nil
+	}
+	tmpl, err := parseMessage(c)
+	if err != nil {
+		return fmt.Errorf("invalid message: %v", err)
+	}
+	c.template = tmpl
+	return nil
+}
+
+func parseMessage(c *Check) (*template.Template, error) {
+	return template.New(c.ID).Option("missingkey=error").Parse(c.Message)
+}
+
+// pendingMessage is the message of a result, formatted by formatMessages
+// once the steps of the results are known.
+type pendingMessage struct {
+	check  *Check
+	fields []field
+}
+
+// formatMessages sets the messages of the results reported in file, along
+// with their messages before translation when the check was localized. A
+// message that can't be formatted is warned about, once for each check, and
+// used as it is.
+func (r *reporter) formatMessages(file string) {
+	for i, pending := range r.messages {
+		message, err := formatMessage(pending.check, file, r.results[i], pending.fields)
+		if err != nil {
+			r.warnOnce("message "+pending.check.ID, fmt.Sprintf("Warning: formatting the message of check %s: %v", pending.check.ID, err))
+			message = pending.check.Message
+		}
+		r.results[i].Message = message
+		if pending.check.sourceMessage != "" {
+			source := *pending.check
+			source.Message, source.template = source.sourceMessage, source.sourceTemplate
+			if sourceMessage, err := formatMessage(&source, file, r.results[i], pending.fields); err == nil && sourceMessage != message {
+				r.results[i].SourceMessage = sourceMessage
+			}
+		}
+	}
+	r.messages = nil
+}
+
+// formatMessage formats the message of check for result. A template can
+// refer to the fields of the finding, and to its CheckID, Severity, File,
+// Line, Column, Job and Step. A message with printf verbs is formatted with
+// the values of the fields in order.
+func formatMessage(check *Check, file string, result report.Result, fields []field) (string, error) {
+	if !isTemplate(check.Message) {
+		if len(fields) == 0 || !strings.Contains(check.Message, "%") {
+			return check.Message, nil
+		}
+		args := make([]interface{}, len(fields))
+		for i, f := range fields {
+			args[i] = f.value
+		}
+		return fmt.Sprintf(check.Message, args...), nil
+	}
+
+	tmpl := check.template
+	if tmpl == nil {
+		// The check wasn't loaded with LoadConfig.
+		var err error
+		if tmpl, err = parseMessage(check); err != nil {
+			return "", err
+		}
+	}
+	data := map[string]interface{}{
+		"CheckID":  result.CheckID,
+		"Severity": result.Severity,
+		"File":     file,
+		"Line":     result.Line,
+		"Column":   result.Column,
+		"Job":      result.JobName,
+		"Step":     result.Step,
+	}
+	for _, f := range fields {
+		data[f.name] = f.value
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,136 @@
+package checks
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"ghactionscheck/pkg/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+// checkNeeds reports the needs of jobs that reference undefined jobs, the
+// dependency cycles between jobs, which GitHub rejects, and the needs
+// already implied by another job the job needs, unless the job reads the
+// outputs or result of the needed job.
+func checkNeeds(r *reporter, w *workflow.Workflow) {
+	names := w.JobNames()
+	for _, jobName := range names {
+		needs := w.Jobs[jobName].Needs
+		for i, need := range needs.Jobs {
+			if _, ok := w.Jobs[need]; !ok {
+				r.report("needs_job", jobName, needs.Nodes[i], field{"Job", jobName}, field{"Need", need}, field{"Suggestion", suggestion(need, names)})
+			}
+		}
+	}
+
+	if cycles := needsCycles(w); len(cycles) > 0 {
+		for _, cycle := range cycles {
+			needs := w.Jobs[cycle[0]].Needs
+			node := needs.Nodes[slices.Index(needs.Jobs, cycle[1])]
+			r.report("needs_cycle", cycle[0], node, field{"Cycle", strings.Join(cycle, " -> ")})
+		}
+		// Every job of a cycle needs the others, so redundant needs
+		// aren't meaningful until the cycles are fixed.
+		return
+	}
+
+	for _, jobName := range names {
+		needs := w.Jobs[jobName].Needs
+		used := neededContexts(w.Jobs[jobName].Node)
+		for i, need := range needs.Jobs {
+			if slices.Index(needs.Jobs, need) < i {
+				r.report("needs_redundant", jobName, needs.Nodes[i], field{"Need", need}, field{"Reason", "it is listed more than once"})
+				continue
+			}
+			// The needs context only holds the jobs needed directly.
+			if used[need] {
+				continue
+			}
+			for _, other := range needs.Jobs {
+				if other != need && needsJob(w, other, need) {
+					r.report("needs_redundant", jobName, needs.Nodes[i], field{"Need", need}, field{"Reason", "it is already needed by " + other})
+					break
+				}
+			}
+		}
+	}
+}
+
+// needsContextPattern matches references to the needs context, capturing
+// the job name.
+var needsContextPattern = regexp.MustCompile(`\bneeds\.([\w-]+)`)
+
+// neededContexts returns the jobs whose needs context is used in the values
+// under node.
+func neededContexts(node *yaml.Node) map[string]bool {
+	used := make(map[string]bool)
+	var walk func(*yaml.Node)
+	walk = func(node *yaml.Node) {
+		if node.Kind == yaml.ScalarNode {
+			for _, match := range needsContextPattern.FindAllStringSubmatch(node.Value, -1) {
+				used[match[1]] = true
+			}
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+	walk(node)
+	return used
+}
+
+// needsCycles returns the dependency cycles between the jobs of w, each as
+// the list of its jobs starting and ending with the same job. The jobs are
+// visited in document order, so each cycle is reported once, from its first
+// job to be visited.
+func needsCycles(w *workflow.Workflow) [][]string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var stack []string
+	var cycles [][]string
+
+	var visit func(string)
+	visit = func(jobName string) {
+		state[jobName] = visiting
+		stack = append(stack, jobName)
+		needs := w.Jobs[jobName].Needs.Jobs
+		for i, need := range needs {
+			if _, ok := w.Jobs[need]; !ok || slices.Index(needs, need) < i {
+				continue
+			}
+			switch state[need] {
+			case visiting:
+				start := slices.Index(stack, need)
+				cycle := append(slices.Clone(stack[start:]), need)
+				cycles = append(cycles, cycle)
+			case unvisited:
+				visit(need)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[jobName] = visited
+	}
+
+	for _, jobName := range w.JobNames() {
+		if state[jobName] == unvisited {
+			visit(jobName)
+		}
+	}
+	return cycles
+}
+
+// needsJob reports whether jobName needs target, directly or through the
+// jobs it needs. The jobs must not have cycles.
+func needsJob(w *workflow.Workflow, jobName, target string) bool {
+	for _, need := range w.Jobs[jobName].Needs.Jobs {
+		if need == target || needsJob(w, need, target) {
+			return true
+		}
+	}
+	return false
+}
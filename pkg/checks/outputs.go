@@ -0,0 +1,241 @@
+package checks
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ghactionscheck/pkg/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+// stepOutputWritePattern matches the lines of run scripts setting a step
+// output with echo or printf, capturing the output name.
+var stepOutputWritePattern = regexp.MustCompile(`(?m)^\s*(?:echo|printf)\s+(?:-\w+\s+)*["']?([A-Za-z_][\w-]*)(?:=|<<).*>>\s*["']?\$\{?GITHUB_OUTPUT\b`)
+
+// outputReferences holds the output names read from a job or step, with
+// all set when the whole outputs object is read, as with toJSON.
+type outputReferences struct {
+	names map[string]bool
+	all   bool
+}
+
+// add records the output read by path, whose output name is at index depth.
+func (o *outputReferences) add(path []string, depth int) {
+	if len(path) <= depth {
+		o.all = true
+		return
+	}
+	if o.names == nil {
+		o.names = make(map[string]bool)
+	}
+	o.names[strings.ToLower(path[depth])] = true
+}
+
+func (o *outputReferences) has(name string) bool {
+	return o != nil && (o.all || o.names[strings.ToLower(name)])
+}
+
+// checkOutputs reports the outputs of jobs and steps nothing reads, and the
+// references to outputs of jobs that don't declare them or that the job
+// doesn't need.
+func checkOutputs(r *reporter, w *workflow.Workflow) {
+	jobOutputs := make(map[string]*outputReferences)
+	read := func(jobName string) *outputReferences {
+		if jobOutputs[jobName] == nil {
+			jobOutputs[jobName] = &outputReferences{}
+		}
+		return jobOutputs[jobName]
+	}
+	reported := make(map[string]bool)
+	reportUndefined := func(jobName string, ref expressionPath, problem string) {
+		text := strings.Join(ref.path, ".")
+		if key := fmt.Sprintf("%p %s", ref.node, text); !reported[key] {
+			reported[key] = true
+			r.report("undefined_output", jobName, ref.node, field{"Expression", text}, field{"Problem", problem})
+		}
+	}
+
+	for _, jobName := range w.JobNames() {
+		job := w.Jobs[jobName]
+		for _, ref := range expressionPaths(job.Node) {
+			if !strings.EqualFold(ref.path[0], "needs") {
+				continue
+			}
+			if len(ref.path) < 2 {
+				for _, need := range job.Needs.Jobs {
+					read(need).all = true
+				}
+				continue
+			}
+			need := matchingJob(w, ref.path[1])
+			switch {
+			case need == "":
+				reportUndefined(jobName, ref, fmt.Sprintf("there is no job %s%s", ref.path[1], suggestion(ref.path[1], w.JobNames())))
+				continue
+			case !needsDirectly(job, need):
+				reportUndefined(jobName, ref, fmt.Sprintf("job %s doesn't need job %s", jobName, need))
+				continue
+			case len(ref.path) < 3 || !strings.EqualFold(ref.path[2], "outputs"):
+				continue
+			}
+			read(need).add(ref.path, 3)
+			if problem := undeclaredOutput(w.Jobs[need], need, ref.path); problem != "" {
+				reportUndefined(jobName, ref, problem)
+			}
+		}
+		checkStepOutputs(r, jobName, job)
+	}
+
+	// The outputs of a reusable workflow are set from the outputs of its
+	// jobs, in the jobs context.
+	_, callOutputs := workflow.LookupKey(w.On.Events["workflow_call"], "outputs")
+	for _, ref := range expressionPaths(callOutputs) {
+		if !strings.EqualFold(ref.path[0], "jobs") || len(ref.path) < 2 {
+			continue
+		}
+		need := matchingJob(w, ref.path[1])
+		if need == "" {
+			reportUndefined("workflow", ref, fmt.Sprintf("there is no job %s%s", ref.path[1], suggestion(ref.path[1], w.JobNames())))
+			continue
+		}
+		if len(ref.path) >= 3 && !strings.EqualFold(ref.path[2], "outputs") {
+			continue
+		}
+		read(need).add(ref.path, 3)
+		if problem := undeclaredOutput(w.Jobs[need], need, ref.path); problem != "" {
+			reportUndefined("workflow", ref, problem)
+		}
+	}
+
+	for _, jobName := range w.JobNames() {
+		_, outputs := workflow.LookupKey(w.Jobs[jobName].Node, "outputs")
+		if outputs == nil || outputs.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(outputs.Content); i += 2 {
+			if name := outputs.Content[i]; !jobOutputs[jobName].has(name.Value) {
+				r.report("unused_output", jobName, name, field{"Output", name.Value}, field{"Source", "job " + jobName})
+			}
+		}
+	}
+}
+
+// undeclaredOutput describes the problem with path, needs.<job>.outputs.<name>
+// or jobs.<job>.outputs.<name>, if job jobName doesn't declare the output.
+// Jobs calling reusable workflows are skipped, as the called workflow
+// declares their outputs.
+func undeclaredOutput(job workflow.Job, jobName string, path []string) string {
+	if len(path) < 4 || job.Uses != "" {
+		return ""
+	}
+	_, outputs := workflow.LookupKey(job.Node, "outputs")
+	if outputs != nil && outputs.Kind != yaml.MappingNode {
+		return ""
+	}
+	var names []string
+	if outputs != nil {
+		for i := 0; i+1 < len(outputs.Content); i += 2 {
+			names = append(names, outputs.Content[i].Value)
+			if strings.EqualFold(outputs.Content[i].Value, path[3]) {
+				return ""
+			}
+		}
+	}
+	return fmt.Sprintf("job %s declares no output %s%s", jobName, path[3], suggestion(path[3], names))
+}
+
+// checkStepOutputs reports the outputs that the run scripts of steps with an
+// id set, but that nothing in the job reads.
+func checkStepOutputs(r *reporter, jobName string, job workflow.Job) {
+	stepOutputs := make(map[string]*outputReferences)
+	for _, ref := range expressionPaths(job.Node) {
+		if !strings.EqualFold(ref.path[0], "steps") {
+			continue
+		}
+		if len(ref.path) < 2 {
+			// The whole steps context is read, as with toJSON(steps).
+			return
+		}
+		id := strings.ToLower(ref.path[1])
+		if stepOutputs[id] == nil {
+			stepOutputs[id] = &outputReferences{}
+		}
+		if len(ref.path) < 3 {
+			stepOutputs[id].all = true
+		} else if strings.EqualFold(ref.path[2], "outputs") {
+			stepOutputs[id].add(ref.path, 3)
+		}
+	}
+
+	for _, step := range job.Steps {
+		_, script := workflow.LookupKey(step.Node, "run")
+		if step.ID == "" || script == nil {
+			continue
+		}
+		reported := make(map[string]bool)
+		for _, match := range stepOutputWritePattern.FindAllStringSubmatch(script.Value, -1) {
+			if name := match[1]; !reported[name] && !stepOutputs[strings.ToLower(step.ID)].has(name) {
+				reported[name] = true
+				r.report("unused_output", jobName, script, field{"Output", name}, field{"Source", fmt.Sprintf("step %s of job %s", step.ID, jobName)})
+			}
+		}
+	}
+}
+
+// needsDirectly reports whether job lists jobName in its needs, compared
+// case-insensitively as GitHub does.
+func needsDirectly(job workflow.Job, jobName string) bool {
+	for _, need := range job.Needs.Jobs {
+		if strings.EqualFold(need, jobName) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchingJob returns the job of w named name, compared case-insensitively,
+// or an empty string.
+func matchingJob(w *workflow.Workflow, name string) string {
+	for _, jobName := range w.JobNames() {
+		if strings.EqualFold(jobName, name) {
+			return jobName
+		}
+	}
+	return ""
+}
+
+// checkInputs reports the inputs of a reusable or dispatchable workflow
+// that no expression of the workflow reads, through inputs or, for
+// workflow_dispatch, github.event.inputs.
+func checkInputs(r *reporter, w *workflow.Workflow) {
+	used := &outputReferences{}
+	for i := 0; i+1 < len(w.Node.Content); i += 2 {
+		if w.Node.Content[i].Value == "on" {
+			continue
+		}
+		for _, ref := range expressionPaths(w.Node.Content[i+1]) {
+			switch path := ref.path; {
+			case strings.EqualFold(path[0], "inputs"):
+				used.add(path, 1)
+			case len(path) >= 3 && strings.EqualFold(path[0], "github") && strings.EqualFold(path[1], "event") && strings.EqualFold(path[2], "inputs"):
+				used.add(path, 3)
+			case len(path) <= 2 && strings.EqualFold(path[0], "github") && (len(path) == 1 || strings.EqualFold(path[1], "event")):
+				// The inputs can be read from the whole event.
+				used.all = true
+			}
+		}
+	}
+
+	for _, event := range []string{"workflow_call", "workflow_dispatch"} {
+		_, inputs := workflow.LookupKey(w.On.Events[event], "inputs")
+		if inputs == nil || inputs.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(inputs.Content); i += 2 {
+			if name := inputs.Content[i]; !used.has(name.Value) {
+				r.report("unused_input", "workflow", name, field{"Input", name.Value}, field{"Event", event})
+			}
+		}
+	}
+}
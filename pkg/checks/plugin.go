@@ -0,0 +1,225 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ghactionscheck/pkg/report"
+	"ghactionscheck/pkg/workflow"
+)
+
+// Plugin is an external executable or WASM module providing custom checks.
+// It is run once per workflow file with a pluginInput as JSON on stdin, and
+// writes its findings to stdout in the same format as --format json:
+//
+//	{"findings": [{"line": 12, "column": 9, "job": "build", "check_id": "my_check", "message": "...", "severity": "error"}]}
+//
+// Findings are suppressed by their check_id like those of built-in checks.
+type Plugin struct {
+	Name string `yaml:"name"`
+	// Command is the executable and its arguments. A relative path is
+	// resolved against the directory of the config file.
+	Command []string `yaml:"command,omitempty"`
+	// WASM is the path of a WASI module run in a sandbox instead of
+	// Command, resolved like Command, and Args are its arguments.
+	WASM string   `yaml:"wasm,omitempty"`
+	Args []string `yaml:"args,omitempty"`
+	// Severity is used for findings that do not set one, and defaults to
+	// warning.
+	Severity string `yaml:"severity,omitempty"`
+	Enabled  *bool  `yaml:"enabled,omitempty"`
+
+	// dir is the directory of the config file declaring the plugin.
+	dir string
+}
+
+// pluginTimeout limits a run of a command plugin.
+const pluginTimeout = time.Minute
+
+// RemoveCommandPlugins removes the plugins running commands from the config,
+// leaving the sandboxed WASM plugins, and returns their names. It is used
+// for configs that aren't trusted to run commands, such as those of the
+// repository being checked.
+func (c *Config) RemoveCommandPlugins() []string {
+	var names []string
+	plugins := c.Plugins[:0:0]
+	for _, plugin := range c.Plugins {
+		if len(plugin.Command) > 0 && plugin.WASM == "" {
+			names = append(names, plugin.Name)
+			continue
+		}
+		plugins = append(plugins, plugin)
+	}
+	c.Plugins = plugins
+	return names
+}
+
+// pluginInput is the document sent to plugins.
+type pluginInput struct {
+	File string `json:"file"`
+	// Source is the text of the workflow file, for plugins that need
+	// positions or comments.
+	Source string `json:"source"`
+	// Workflow is the workflow as parsed from YAML, with anchors and
+	// aliases resolved.
+	Workflow interface{} `json:"workflow"`
+}
+
+// runPlugins runs the enabled plugins against a workflow.
+func (c *Checker) runPlugins(w *workflow.Workflow, data []byte) ([]report.Result, error) {
+	var plugins []Plugin
+	for _, plugin := range c.plugins {
+		if plugin.Enabled == nil || *plugin.Enabled {
+			plugins = append(plugins, plugin)
+		}
+	}
+	if len(plugins) == 0 {
+		return nil, nil
+	}
+
+	document, err := workflowDocument(w)
+	if err != nil {
+		return nil, err
+	}
+	input, err := json.Marshal(pluginInput{
+		File:     w.File,
+		Source:   string(data),
+		Workflow: document,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []report.Result
+	for _, plugin := range plugins {
+		pluginResults, err := c.runPlugin(plugin, input)
+		if err != nil {
+			return nil, fmt.Errorf("running plugin %s: %v", plugin.Name, err)
+		}
+		results = append(results, pluginResults...)
+	}
+	return results, nil
+}
+
+func (c *Checker) runPlugin(p Plugin, input []byte) ([]report.Result, error) {
+	var stdout []byte
+	var err error
+	switch {
+	case p.WASM != "":
+		c.wasmMu.Lock()
+		if c.wasm == nil {
+			c.wasm = newWasmRuntime(context.Background())
+		}
+		stdout, err = c.wasm.run(p.path(p.WASM), p.Args, input)
+		c.wasmMu.Unlock()
+	case len(p.Command) > 0:
+		stdout, err = p.exec(input)
+	default:
+		err = fmt.Errorf("no command or wasm module")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var output struct {
+		Findings []report.Result `json:"findings"`
+	}
+	if err := json.Unmarshal(stdout, &output); err != nil {
+		return nil, fmt.Errorf("invalid output: %v", err)
+	}
+
+	for i := range output.Findings {
+		finding := &output.Findings[i]
+		if finding.CheckID == "" {
+			finding.CheckID = p.Name
+		}
+		if finding.Severity == "" {
+			finding.Severity = p.Severity
+		}
+		severity, err := report.NormalizeSeverity(finding.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("finding %s: %v", finding.CheckID, err)
+		}
+		finding.Severity = severity
+	}
+	return output.Findings, nil
+}
+
+// exec runs the command of the plugin, killing it after pluginTimeout.
+func (p Plugin) exec(input []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.commandPath(p.Command[0]), p.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	// Don't wait for processes it started that keep its output open.
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("timed out after %s", pluginTimeout)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// path resolves a relative path of the plugin against the directory of the
+// config file.
+func (p Plugin) path(name string) string {
+	if !filepath.IsAbs(name) && p.dir != "" {
+		return filepath.Join(p.dir, name)
+	}
+	return name
+}
+
+// commandPath resolves the executable of a command plugin as path does,
+// except for names without a directory, which are looked up in PATH.
+func (p Plugin) commandPath(name string) string {
+	if !strings.ContainsRune(name, filepath.Separator) {
+		return name
+	}
+	return p.path(name)
+}
+
+// workflowDocument returns a workflow as a JSON-compatible value, with
+// anchors and aliases resolved.
+func workflowDocument(w *workflow.Workflow) (interface{}, error) {
+	var document interface{}
+	if err := w.Node.Decode(&document); err != nil {
+		return nil, err
+	}
+	return jsonValue(document), nil
+}
+
+// jsonValue converts a value decoded from YAML into one encoding/json can
+// marshal, turning mappings with non-string keys into string-keyed maps.
+func jsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, v := range value {
+			value[key] = jsonValue(v)
+		}
+		return value
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, v := range value {
+			converted[fmt.Sprint(key)] = jsonValue(v)
+		}
+		return converted
+	case []interface{}:
+		for i, v := range value {
+			value[i] = jsonValue(v)
+		}
+		return value
+	}
+	return value
+}
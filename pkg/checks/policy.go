@@ -0,0 +1,64 @@
+package checks
+
+import (
+	"path"
+	"strings"
+)
+
+// Policy restricts the actions workflows may use. Patterns follow GitHub's
+// allowed actions settings: "owner/*" matches every action of owner,
+// "owner/repo@*" every ref of an action and "owner/repo@v4" a single ref.
+// Names and refs may also contain other glob patterns.
+type Policy struct {
+	Actions ActionPolicy `yaml:"actions"`
+}
+
+type ActionPolicy struct {
+	// Allow lists the permitted actions; when empty, every action not
+	// denied is permitted.
+	Allow []string `yaml:"allow"`
+	// Deny lists forbidden actions and takes precedence over Allow.
+	Deny []string `yaml:"deny"`
+}
+
+// evaluate returns why the action referenced by uses violates the policy,
+// or an empty string if it is permitted. Local and Docker actions are not
+// subject to the policy.
+func (p ActionPolicy) evaluate(uses string) string {
+	if strings.HasPrefix(uses, "./") || strings.HasPrefix(uses, "docker://") {
+		return ""
+	}
+	for _, pattern := range p.Deny {
+		if matchActionPattern(pattern, uses) {
+			return "denied"
+		}
+	}
+	if len(p.Allow) == 0 {
+		return ""
+	}
+	for _, pattern := range p.Allow {
+		if matchActionPattern(pattern, uses) {
+			return ""
+		}
+	}
+	return "not allowed"
+}
+
+// matchActionPattern reports whether the action referenced by uses matches a
+// policy pattern.
+func matchActionPattern(pattern, uses string) bool {
+	name, ref, _ := strings.Cut(uses, "@")
+	namePattern, refPattern, hasRef := strings.Cut(pattern, "@")
+
+	if hasRef && refPattern != "*" {
+		if ok, _ := path.Match(refPattern, ref); !ok {
+			return false
+		}
+	}
+
+	if owner, ok := strings.CutSuffix(namePattern, "/*"); ok && !strings.Contains(owner, "/") {
+		return strings.HasPrefix(name, owner+"/")
+	}
+	ok, _ := path.Match(namePattern, name)
+	return ok
+}
@@ -0,0 +1,88 @@
+package checks
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// PullRequestFile is a file changed by a pull request. Patch is the unified
+// diff of its changes, empty for binary files and diffs too large to show.
+type PullRequestFile struct {
+	Filename string `json:"filename"`
+	Status   string `json:"status"`
+	Patch    string `json:"patch"`
+}
+
+// ReviewComment is a comment of a pull request review on a line of a file.
+// Line is 0 for a comment on a line that later commits changed.
+type ReviewComment struct {
+	ID   int64  `json:"id,omitempty"`
+	Path string `json:"path"`
+	Line int    `json:"line,omitempty"`
+	Side string `json:"side,omitempty"`
+	Body string `json:"body"`
+}
+
+// PullRequestHead returns the commit at the head of pull request number of
+// repo ("owner/name").
+func (c *GitHubClient) PullRequestHead(repo string, number int) (string, error) {
+	var pr struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	err := c.get(fmt.Sprintf("/repos/%s/pulls/%d", repo, number), &pr)
+	if err == errNotFound {
+		return "", fmt.Errorf("pull request %s#%d not found", repo, number)
+	}
+	return pr.Head.SHA, err
+}
+
+// PullRequestFiles returns the files changed by pull request number of repo.
+func (c *GitHubClient) PullRequestFiles(repo string, number int) ([]PullRequestFile, error) {
+	var files []PullRequestFile
+	for page := 1; ; page++ {
+		var batch []PullRequestFile
+		if err := c.get(fmt.Sprintf("/repos/%s/pulls/%d/files?per_page=%d&page=%d", repo, number, perPage, page), &batch); err != nil {
+			return nil, err
+		}
+		files = append(files, batch...)
+		if len(batch) < perPage {
+			return files, nil
+		}
+	}
+}
+
+// ReviewComments returns the review comments of pull request number of repo.
+func (c *GitHubClient) ReviewComments(repo string, number int) ([]ReviewComment, error) {
+	var comments []ReviewComment
+	for page := 1; ; page++ {
+		var batch []ReviewComment
+		if err := c.get(fmt.Sprintf("/repos/%s/pulls/%d/comments?per_page=%d&page=%d", repo, number, perPage, page), &batch); err != nil {
+			return nil, err
+		}
+		comments = append(comments, batch...)
+		if len(batch) < perPage {
+			return comments, nil
+		}
+	}
+}
+
+// CreateReview posts comments on pull request number of repo as a single
+// review of commit, so they are notified at once.
+func (c *GitHubClient) CreateReview(repo string, number int, commit string, comments []ReviewComment) error {
+	review := struct {
+		CommitID string          `json:"commit_id"`
+		Event    string          `json:"event"`
+		Comments []ReviewComment `json:"comments"`
+	}{commit, "COMMENT", comments}
+	return c.do(http.MethodPost, fmt.Sprintf("/repos/%s/pulls/%d/reviews", repo, number), review, nil)
+}
+
+// UpdateReviewComment replaces the body of review comment id of repo.
+func (c *GitHubClient) UpdateReviewComment(repo string, id int64, body string) error {
+	update := struct {
+		Body string `json:"body"`
+	}{body}
+	return c.do(http.MethodPatch, fmt.Sprintf("/repos/%s/pulls/comments/%d", repo, id), update, nil)
+}
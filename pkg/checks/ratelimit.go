@@ -0,0 +1,212 @@
+package checks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Requests refused by a rate limit are retried at most maxRetries times,
+// after waiting for the limit to reset, as long as that is no longer than
+// maxRateLimitWait. Secondary rate limits without a Retry-After are waited
+// out with an exponential backoff from secondaryRateLimitWait.
+const (
+	maxRetries             = 3
+	maxRateLimitWait       = 2 * time.Minute
+	secondaryRateLimitWait = time.Minute
+)
+
+// ErrBadToken is returned by Status when the API rejects the token.
+var ErrBadToken = errors.New("bad credentials")
+
+// RateLimitError is returned for the requests refused because the rate limit
+// is exceeded, when it doesn't reset soon enough to wait for it.
+type RateLimitError struct {
+	Reset     time.Time
+	Anonymous bool
+}
+
+func (e *RateLimitError) Error() string {
+	reset := e.Reset.Local().Format(time.TimeOnly)
+	if e.Anonymous {
+		return fmt.Sprintf("GitHub API rate limit of anonymous requests exceeded until %s; set $GITHUB_TOKEN or $GH_TOKEN for a higher limit", reset)
+	}
+	return fmt.Sprintf("GitHub API rate limit of the token exceeded until %s", reset)
+}
+
+// RateLimit is the state of an API rate limit.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// APIStatus is what the API reports about the requests of a client.
+type APIStatus struct {
+	// RateLimit is the limit of the REST API, 60 requests an hour for
+	// anonymous requests. Its Limit is 0 when rate limiting is disabled, as
+	// it can be on GitHub Enterprise Server.
+	RateLimit RateLimit
+	// Scopes are the OAuth scopes of the token. They are only reported for
+	// classic tokens, so ScopesReported is false for fine-grained tokens,
+	// app tokens such as the GITHUB_TOKEN of Actions, and anonymous requests.
+	Scopes         []string
+	ScopesReported bool
+}
+
+// Status queries the rate limit of the client, which doesn't count against
+// it, and the scopes of its token. A GitHub Enterprise Server answers 404
+// when rate limiting is disabled. An error is returned when the API can't
+// be reached or rejects the token.
+func (c *GitHubClient) Status() (*APIStatus, error) {
+	req, err := c.newRequest(http.MethodGet, "/rate_limit", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrBadToken
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	unlimited := resp.StatusCode == http.StatusNotFound && bytes.Contains(body, []byte("Rate limiting is not enabled"))
+	if resp.StatusCode != http.StatusOK && !unlimited {
+		return nil, fmt.Errorf("GET /rate_limit: %s", resp.Status)
+	}
+
+	status := &APIStatus{}
+	if header, ok := resp.Header["X-Oauth-Scopes"]; ok {
+		status.ScopesReported = true
+		for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				status.Scopes = append(status.Scopes, scope)
+			}
+		}
+	}
+	if unlimited {
+		return status, nil
+	}
+
+	var limits struct {
+		Resources struct {
+			Core struct {
+				Limit     int   `json:"limit"`
+				Remaining int   `json:"remaining"`
+				Reset     int64 `json:"reset"`
+			} `json:"core"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(body, &limits); err != nil {
+		return nil, err
+	}
+	core := limits.Resources.Core
+	status.RateLimit = RateLimit{core.Limit, core.Remaining, time.Unix(core.Reset, 0)}
+	return status, nil
+}
+
+// send sends req, waiting and retrying when it is refused by the primary or
+// a secondary rate limit. When the last response showed the primary rate
+// limit exhausted, the reset is waited for before sending, so requests made
+// once the limit is hit fail right away instead of being refused one by one.
+func (c *GitHubClient) send(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		c.mu.Lock()
+		limit := c.rateLimit
+		c.mu.Unlock()
+		if limit.Limit > 0 && limit.Remaining == 0 && time.Now().Before(limit.Reset) {
+			if err := c.waitForRateLimit(time.Until(limit.Reset), attempt); err != nil {
+				return nil, err
+			}
+		}
+
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		c.updateRateLimit(resp.Header)
+		wait, limited := rateLimitWait(resp, attempt)
+		if !limited {
+			return resp, nil
+		}
+		resp.Body.Close()
+		if err := c.waitForRateLimit(wait, attempt); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitForRateLimit waits for a rate limit to reset before retrying a
+// request, or returns a RateLimitError when that would take too long.
+func (c *GitHubClient) waitForRateLimit(wait time.Duration, attempt int) error {
+	if attempt >= maxRetries || wait > maxRateLimitWait {
+		return &RateLimitError{Reset: time.Now().Add(wait), Anonymous: c.token == ""}
+	}
+	fmt.Fprintf(os.Stderr, "Waiting %s for the GitHub API rate limit to reset\n", wait.Round(time.Second))
+	time.Sleep(wait)
+	return nil
+}
+
+// updateRateLimit records the state of the primary rate limit of the REST
+// API sent with a response.
+func (c *GitHubClient) updateRateLimit(header http.Header) {
+	if resource := header.Get("X-RateLimit-Resource"); resource != "" && resource != "core" {
+		return
+	}
+	limit, err1 := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	remaining, err2 := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	reset, err3 := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return
+	}
+	c.mu.Lock()
+	c.rateLimit = RateLimit{limit, remaining, time.Unix(reset, 0)}
+	c.mu.Unlock()
+}
+
+// rateLimitWait returns how long to wait before retrying the request of a
+// response refused by a rate limit, and whether it was. The primary rate
+// limit resets at the time of X-RateLimit-Reset; secondary rate limits give
+// a Retry-After, or are waited out with an exponential backoff.
+func rateLimitWait(resp *http.Response, attempt int) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Until(time.Unix(reset, 0)), 0), true
+		}
+	}
+
+	// A 403 response is only a secondary rate limit when its message says
+	// so; the body is restored for the caller otherwise.
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if resp.StatusCode == http.StatusTooManyRequests || bytes.Contains(bytes.ToLower(body), []byte("secondary rate limit")) {
+		return secondaryRateLimitWait << attempt, true
+	}
+	return 0, false
+}
@@ -0,0 +1,130 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"ghactionscheck/pkg/report"
+	"ghactionscheck/pkg/workflow"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// regoNamespace is the package policies define their rules in, as with
+// Conftest.
+const regoNamespace = "main"
+
+// regoRules maps the rules evaluated in policies to the severity of their
+// findings.
+var regoRules = map[string]string{
+	"deny":      report.SeverityError,
+	"violation": report.SeverityError,
+	"warn":      report.SeverityWarning,
+}
+
+// regoPolicy evaluates Conftest-style Rego policies against workflows. The
+// input is the workflow document, and each message of the deny, violation
+// and warn rules of package main is a finding. A message is a string or an
+// object with a msg and optionally the line, column, job and check_id of the
+// finding.
+type regoPolicy struct {
+	query rego.PreparedEvalQuery
+}
+
+// loadRegoPolicy loads the .rego files and data files under paths.
+func loadRegoPolicy(paths []string) (*regoPolicy, error) {
+	query, err := rego.New(
+		rego.Query("data."+regoNamespace),
+		rego.Load(paths, func(_ string, info fs.FileInfo, _ int) bool {
+			return !info.IsDir() && strings.HasSuffix(info.Name(), "_test.rego")
+		}),
+	).PrepareForEval(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &regoPolicy{query: query}, nil
+}
+
+func (p *regoPolicy) evaluate(w *workflow.Workflow) ([]report.Result, error) {
+	input, err := workflowDocument(w)
+	if err != nil {
+		return nil, err
+	}
+	resultSet, err := p.query.Eval(context.Background(), rego.EvalInput(input))
+	if err != nil {
+		return nil, err
+	}
+
+	var results []report.Result
+	for _, result := range resultSet {
+		for _, expression := range result.Expressions {
+			rules, _ := expression.Value.(map[string]interface{})
+			names := make([]string, 0, len(rules))
+			for name := range rules {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				severity, ok := regoRules[name]
+				if !ok {
+					continue
+				}
+				messages, _ := rules[name].([]interface{})
+				for _, message := range messages {
+					result, err := regoFinding(message, severity)
+					if err != nil {
+						return nil, fmt.Errorf("rule %s: %v", name, err)
+					}
+					if result.Line == 0 {
+						result.Line, result.Column = w.Node.Line, w.Node.Column
+					}
+					results = append(results, result)
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// regoFinding converts a message of a policy rule to a finding.
+func regoFinding(message interface{}, severity string) (report.Result, error) {
+	result := report.Result{CheckID: "policy", JobName: "workflow", Severity: severity}
+	switch message := message.(type) {
+	case string:
+		result.Message = message
+	case map[string]interface{}:
+		msg, ok := message["msg"].(string)
+		if !ok {
+			return result, fmt.Errorf("message has no msg")
+		}
+		result.Message = msg
+		if id, ok := message["check_id"].(string); ok {
+			result.CheckID = id
+		}
+		if job, ok := message["job"].(string); ok {
+			result.JobName = job
+		}
+		if line, ok := regoInt(message["line"]); ok {
+			result.Line = line
+		}
+		if column, ok := regoInt(message["column"]); ok {
+			result.Column = column
+		}
+	default:
+		return result, fmt.Errorf("message must be a string or an object, not %T", message)
+	}
+	return result, nil
+}
+
+// regoInt converts a number from a policy result, which is a json.Number.
+func regoInt(value interface{}) (int, bool) {
+	number, ok := value.(interface{ Int64() (int64, error) })
+	if !ok {
+		return 0, false
+	}
+	n, err := number.Int64()
+	return int(n), err == nil
+}
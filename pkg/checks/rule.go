@@ -0,0 +1,227 @@
+package checks
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"ghactionscheck/pkg/workflow"
+	"github.com/google/cel-go/cel"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule defines a check in the config instead of in Go. Path selects nodes of
+// the workflow, and the findings are the selected scalars matching Regex or
+// Equals, the parents lacking the final key of Path when Absent is set, or
+// every selected node when no condition is given. Alternatively, Expr is a
+// CEL condition over the workflow, job and step.
+type Rule struct {
+	// Path is a dot-separated selector such as "jobs.*.steps[*].uses".
+	// Mapping keys are glob patterns, and "[*]" or "[N]" select the items
+	// of a sequence.
+	Path   string  `yaml:"path"`
+	Regex  string  `yaml:"regex,omitempty"`
+	Equals *string `yaml:"equals,omitempty"`
+	Absent bool    `yaml:"absent,omitempty"`
+	Expr   string  `yaml:"expr,omitempty"`
+
+	segments []pathSegment
+	regexp   *regexp.Regexp
+	program  cel.Program
+	scope    celScope
+}
+
+// pathSegment is a step of a rule path: a mapping key pattern, or a sequence
+// index when isIndex is set, with index -1 selecting every item.
+type pathSegment struct {
+	key     string
+	isIndex bool
+	index   int
+}
+
+// compile parses the path and regular expression of a rule.
+func (r *Rule) compile() error {
+	conditions := 0
+	if r.Regex != "" {
+		conditions++
+	}
+	if r.Equals != nil {
+		conditions++
+	}
+	if r.Absent {
+		conditions++
+	}
+	if conditions > 1 {
+		return fmt.Errorf("rule can have only one of regex, equals and absent")
+	}
+	if r.Expr != "" {
+		if r.Path != "" || conditions > 0 {
+			return fmt.Errorf("rule expr cannot be combined with path, regex, equals or absent")
+		}
+		return r.compileExpr()
+	}
+
+	segments, err := parseRulePath(r.Path)
+	if err != nil {
+		return err
+	}
+	if r.Absent && segments[len(segments)-1].isIndex {
+		return fmt.Errorf("rule path %q must end with a key to check it is absent", r.Path)
+	}
+	r.segments = segments
+
+	if r.Regex != "" {
+		if r.regexp, err = regexp.Compile(r.Regex); err != nil {
+			return fmt.Errorf("invalid rule regex: %v", err)
+		}
+	}
+	return nil
+}
+
+func parseRulePath(rulePath string) ([]pathSegment, error) {
+	if rulePath == "" {
+		return nil, fmt.Errorf("rule has no path")
+	}
+
+	var segments []pathSegment
+	for _, part := range strings.Split(rulePath, ".") {
+		key, indexes, _ := strings.Cut(part, "[")
+		if key != "" {
+			if _, err := path.Match(key, ""); err != nil {
+				return nil, fmt.Errorf("invalid rule path %q: %v", rulePath, err)
+			}
+			segments = append(segments, pathSegment{key: key})
+		}
+		if indexes == "" {
+			if key == "" {
+				return nil, fmt.Errorf("invalid rule path %q: empty key", rulePath)
+			}
+			continue
+		}
+
+		if !strings.HasSuffix(indexes, "]") {
+			return nil, fmt.Errorf("invalid rule path %q: unclosed [", rulePath)
+		}
+		for _, index := range strings.Split(strings.TrimSuffix(indexes, "]"), "][") {
+			segment := pathSegment{isIndex: true, index: -1}
+			if index != "*" {
+				n, err := strconv.Atoi(index)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("invalid rule path %q: bad index %q", rulePath, index)
+				}
+				segment.index = n
+			}
+			segments = append(segments, segment)
+		}
+	}
+	return segments, nil
+}
+
+// selectedNode is a node selected by a rule path, along with the key it is
+// the value of, if any, and the keys and indexes leading to it.
+type selectedNode struct {
+	key   *yaml.Node
+	value *yaml.Node
+	path  []string
+}
+
+// position returns the node findings about n are reported at.
+func (n selectedNode) position() *yaml.Node {
+	if n.key != nil {
+		return n.key
+	}
+	return n.value
+}
+
+// jobName returns the job a selected node belongs to, or "workflow".
+func (n selectedNode) jobName() string {
+	if len(n.path) > 1 && n.path[0] == "jobs" {
+		return n.path[1]
+	}
+	return "workflow"
+}
+
+// selectNodes returns the nodes of root selected by segments.
+func selectNodes(root *yaml.Node, segments []pathSegment) []selectedNode {
+	nodes := []selectedNode{{value: root}}
+	for _, segment := range segments {
+		var next []selectedNode
+		for _, n := range nodes {
+			next = append(next, selectChildren(n, segment)...)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+func selectChildren(n selectedNode, segment pathSegment) []selectedNode {
+	value := n.value
+	if value.Kind == yaml.AliasNode {
+		value = value.Alias
+	}
+
+	var children []selectedNode
+	child := func(key, value *yaml.Node, name string) {
+		children = append(children, selectedNode{
+			key:   key,
+			value: value,
+			path:  append(append([]string(nil), n.path...), name),
+		})
+	}
+
+	switch {
+	case segment.isIndex && value.Kind == yaml.SequenceNode:
+		for i, item := range value.Content {
+			if segment.index < 0 || segment.index == i {
+				child(nil, item, strconv.Itoa(i))
+			}
+		}
+	case !segment.isIndex && value.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			if ok, _ := path.Match(segment.key, value.Content[i].Value); ok {
+				child(value.Content[i], value.Content[i+1], value.Content[i].Value)
+			}
+		}
+	}
+	return children
+}
+
+// checkRules reports the findings of the enabled checks defined by rules.
+func checkRules(r *reporter, w *workflow.Workflow) {
+	for _, check := range r.checks {
+		if check.Rule == nil || findCheck(r.checks, check.ID) == nil {
+			continue
+		}
+		rule := check.Rule
+
+		if rule.program != nil {
+			checkExprRule(r, w, check)
+			continue
+		}
+
+		if rule.Absent {
+			last := rule.segments[len(rule.segments)-1]
+			for _, parent := range selectNodes(w.Node, rule.segments[:len(rule.segments)-1]) {
+				if parent.value.Kind == yaml.MappingNode && len(selectChildren(parent, last)) == 0 {
+					r.report(check.ID, parent.jobName(), parent.position())
+				}
+			}
+			continue
+		}
+
+		for _, n := range selectNodes(w.Node, rule.segments) {
+			if rule.regexp == nil && rule.Equals == nil {
+				r.report(check.ID, n.jobName(), n.position(), field{"Value", n.value.Value})
+				continue
+			}
+			for _, scalar := range workflow.ScalarNodes(n.value) {
+				if rule.regexp != nil && rule.regexp.MatchString(scalar.Value) ||
+					rule.Equals != nil && scalar.Value == *rule.Equals {
+					r.report(check.ID, n.jobName(), scalar, field{"Value", scalar.Value})
+				}
+			}
+		}
+	}
+}
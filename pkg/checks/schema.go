@@ -0,0 +1,230 @@
+package checks
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"ghactionscheck/pkg/workflow"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"gopkg.in/yaml.v3"
+)
+
+// The workflow and action schemas are those of SchemaStore
+// (https://github.com/SchemaStore/schemastore), under the Apache License 2.0.
+var (
+	//go:embed schema/github-workflow.json
+	workflowSchemaJSON []byte
+	//go:embed schema/github-action.json
+	actionSchemaJSON []byte
+)
+
+const (
+	workflowSchemaURL = "https://json.schemastore.org/github-workflow.json"
+	actionSchemaURL   = "https://json.schemastore.org/github-action.json"
+)
+
+// schemaPrinter formats the messages of schema violations.
+var schemaPrinter = message.NewPrinter(language.English)
+
+// schemas holds the compiled workflow and action schemas.
+type schemas struct {
+	workflow, action *jsonschema.Schema
+}
+
+// compileSchemas compiles the embedded schemas once, as checkers are
+// created for every file in watch mode and for every repository by org.
+var compileSchemas = sync.OnceValues(func() (*schemas, error) {
+	compiler := jsonschema.NewCompiler()
+	s := &schemas{}
+	for _, schema := range []struct {
+		url    string
+		data   []byte
+		target **jsonschema.Schema
+	}{
+		{workflowSchemaURL, workflowSchemaJSON, &s.workflow},
+		{actionSchemaURL, actionSchemaJSON, &s.action},
+	} {
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema.data))
+		if err != nil {
+			return nil, fmt.Errorf("loading schema %s: %v", schema.url, err)
+		}
+		if err := compiler.AddResource(schema.url, doc); err != nil {
+			return nil, fmt.Errorf("loading schema %s: %v", schema.url, err)
+		}
+		if *schema.target, err = compiler.Compile(schema.url); err != nil {
+			return nil, fmt.Errorf("compiling schema %s: %v", schema.url, err)
+		}
+	}
+	return s, nil
+})
+
+// WithSchema validates workflows and actions against their JSON schemas,
+// reporting violations with the schema check.
+func WithSchema() Option {
+	return func(c *Checker) error {
+		s, err := compileSchemas()
+		if err != nil {
+			return err
+		}
+		c.schemas = s
+		return nil
+	}
+}
+
+// checkSchema reports where the document rooted at root violates schema.
+func checkSchema(r *reporter, schema *jsonschema.Schema, root *yaml.Node, jobName string) {
+	err := schema.Validate(schemaValue(root))
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, violation := range schemaViolations(validationErr) {
+		location := violation.InstanceLocation
+		node := schemaNode(root, location)
+		if additional, ok := violation.ErrorKind.(*kind.AdditionalProperties); ok {
+			// Point at the unexpected key rather than its mapping.
+			for _, property := range additional.Properties {
+				key, _ := workflow.LookupKey(node, property)
+				if r.unknownKeys[key] {
+					continue
+				}
+				if key == nil {
+					key = node
+				}
+				reportViolation(r, seen, jobName, key, append(location, property), fmt.Sprintf("unknown key %q", property))
+			}
+			continue
+		}
+		reportViolation(r, seen, jobName, node, location, violation.ErrorKind.LocalizedString(schemaPrinter))
+	}
+}
+
+func reportViolation(r *reporter, seen map[string]bool, jobName string, node *yaml.Node, location []string, problem string) {
+	path := strings.Join(location, ".")
+	if path == "" {
+		path = "(root)"
+	}
+	if key := path + "\x00" + problem; !seen[key] {
+		seen[key] = true
+		if len(location) > 1 && location[0] == "jobs" && jobName == "workflow" {
+			jobName = location[1]
+		}
+		r.report("schema", jobName, node, field{"Path", path}, field{"Problem", problem})
+	}
+}
+
+// schemaViolations returns the violations of a validation error. Of the
+// alternatives of a oneOf or anyOf, only the one matching the document best
+// is kept: the one failing deepest in the document, then the one failing
+// least, as it is most likely the one that was meant.
+func schemaViolations(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+
+	switch err.ErrorKind.(type) {
+	case *kind.OneOf, *kind.AnyOf:
+		var best []*jsonschema.ValidationError
+		bestDepth := -1
+		for _, cause := range err.Causes {
+			violations := schemaViolations(cause)
+			depth := 0
+			for _, violation := range violations {
+				depth = max(depth, len(violation.InstanceLocation))
+			}
+			if depth > bestDepth || depth == bestDepth && len(violations) < len(best) {
+				best, bestDepth = violations, depth
+			}
+		}
+		return best
+	}
+
+	var violations []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		violations = append(violations, schemaViolations(cause)...)
+	}
+	return violations
+}
+
+// schemaNode returns the node at location in the document, or the deepest
+// existing node on the way to it.
+func schemaNode(node *yaml.Node, location []string) *yaml.Node {
+	for _, segment := range location {
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			_, next = workflow.LookupKey(node, segment)
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(segment); err == nil && i < len(node.Content) {
+				next = node.Content[i]
+			}
+		}
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+	return node
+}
+
+// schemaValue converts a YAML node into the JSON value the schemas
+// validate, following YAML's core schema for scalars.
+func schemaValue(node *yaml.Node) interface{} {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) > 0 {
+			return schemaValue(node.Content[0])
+		}
+		return nil
+	case yaml.AliasNode:
+		return schemaValue(node.Alias)
+	case yaml.MappingNode:
+		value := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value[node.Content[i].Value] = schemaValue(node.Content[i+1])
+		}
+		return value
+	case yaml.SequenceNode:
+		value := make([]interface{}, len(node.Content))
+		for i, item := range node.Content {
+			value[i] = schemaValue(item)
+		}
+		return value
+	}
+
+	switch node.ShortTag() {
+	case "!!null":
+		return nil
+	case "!!bool", "!!int", "!!float":
+		var value interface{}
+		if err := node.Decode(&value); err == nil {
+			return value
+		}
+	}
+	return node.Value
+}
+
+// checkWorkflowSchema validates a workflow against the workflow schema.
+func checkWorkflowSchema(r *reporter, w *workflow.Workflow) {
+	if r.schemas == nil || r.check("schema") == nil {
+		return
+	}
+	checkSchema(r, r.schemas.workflow, w.Node, "workflow")
+}
+
+// checkActionSchema validates an action against the action schema.
+func checkActionSchema(r *reporter, action *workflow.Action) {
+	if r.schemas == nil || r.check("schema") == nil {
+		return
+	}
+	checkSchema(r, r.schemas.action, action.Node, actionJobName)
+}
@@ -0,0 +1,164 @@
+package checks
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"ghactionscheck/pkg/workflow"
+)
+
+// checkUndefinedSecrets reports the secrets read by a workflow that neither
+// its repository, its organization nor the environment of the job defines,
+// which evaluate to empty strings instead of failing the run. Reusable
+// workflows are skipped, as their secrets are passed by the caller.
+func checkUndefinedSecrets(r *reporter, w *workflow.Workflow) {
+	if r.github == nil || r.check("undefined_secret") == nil || w.On.Has("workflow_call") {
+		return
+	}
+	repo := r.workflowRepository(w.File)
+	if repo == "" {
+		r.warnOnce("undefined_secret", "Warning: could not find the GitHub repository of "+w.File+" to list its secrets")
+		return
+	}
+	defined, err := r.github.repositorySecrets(repo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not list the secrets of %s: %v\n", repo, err)
+	}
+	if defined == nil {
+		return
+	}
+
+	reported := make(map[string]bool)
+	check := func(jobName string, refs []expressionPath, environment string, environmentDefined map[string]bool) {
+		for _, ref := range refs {
+			if len(ref.path) < 2 || !strings.EqualFold(ref.path[0], "secrets") {
+				continue
+			}
+			name := strings.ToUpper(ref.path[1])
+			if name == "GITHUB_TOKEN" || defined[name] || environmentDefined[name] {
+				continue
+			}
+			key := fmt.Sprintf("%p %s", ref.node, name)
+			if reported[key] {
+				continue
+			}
+			reported[key] = true
+			where := "repository " + repo
+			candidates := keys(defined)
+			if environment != "" {
+				where += " or environment " + environment
+				candidates = append(candidates, keys(environmentDefined)...)
+			}
+			r.report("undefined_secret", jobName, ref.node, field{"Secret", ref.path[1]}, field{"Scope", where}, field{"Suggestion", suggestion(name, candidates)})
+		}
+	}
+
+	for i := 0; i+1 < len(w.Node.Content); i += 2 {
+		if w.Node.Content[i].Value != "jobs" {
+			check("workflow", expressionPaths(w.Node.Content[i+1]), "", nil)
+		}
+	}
+	for _, jobName := range w.JobNames() {
+		job := w.Jobs[jobName]
+		environment := environmentName(job)
+		var environmentDefined map[string]bool
+		if environment != "" {
+			if strings.Contains(environment, "${{") {
+				// The secrets of a computed environment are unknown.
+				continue
+			}
+			environmentDefined, err = r.github.environmentSecrets(repo, environment)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not list the secrets of environment %s of %s: %v\n", environment, repo, err)
+			}
+			if environmentDefined == nil {
+				continue
+			}
+		}
+		check(jobName, expressionPaths(job.Node), environment, environmentDefined)
+	}
+}
+
+// environmentName returns the name of the environment of job, given either
+// as a name or as a mapping with a name and URL, or an empty string.
+func environmentName(job workflow.Job) string {
+	switch env := job.Environment.(type) {
+	case string:
+		return env
+	case map[string]interface{}:
+		name, _ := env["name"].(string)
+		return name
+	}
+	return ""
+}
+
+// workflowRepository returns the repository ("owner/name") of a workflow
+// file: the remote repository, or the repository the origin remote of the
+// local clone points to. It is empty if neither is known.
+func (c *Checker) workflowRepository(file string) string {
+	if c.remote != nil {
+		return c.remote.repo
+	}
+	return OriginRepository(FindRepoRoot(file), cmp.Or(c.host, githubHost))
+}
+
+// remotePattern returns the pattern matching the URLs of repositories on
+// host in the HTTPS, SSH and scp-like forms, capturing "owner/name".
+func remotePattern(host string) *regexp.Regexp {
+	host = regexp.QuoteMeta(host)
+	return regexp.MustCompile(`^(?:(?:https?|ssh|git)://(?:[^@/]+@)?` + host + `(?::\d+)?/|(?:[^@/:]+@)?` + host + `:)([\w.-]+/[\w.-]+?)(?:\.git)?/?$`)
+}
+
+// OriginRepository returns the repository on host, such as github.com, of
+// the origin remote of the git clone at root, or an empty string. Worktrees
+// are followed to the config of their main repository.
+func OriginRepository(root, host string) string {
+	gitDir := filepath.Join(root, ".git")
+	if data, err := os.ReadFile(gitDir); err == nil {
+		dir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+		if !ok {
+			return ""
+		}
+		gitDir = resolvePath(root, strings.TrimSpace(dir))
+		if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+			gitDir = resolvePath(gitDir, strings.TrimSpace(string(common)))
+		}
+	}
+	config, err := os.ReadFile(filepath.Join(gitDir, "config"))
+	if err != nil {
+		return ""
+	}
+
+	inOrigin := false
+	scanner := bufio.NewScanner(bytes.NewReader(config))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inOrigin = strings.ReplaceAll(line, " ", "") == `[remote"origin"]`
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !inOrigin || !ok || strings.TrimSpace(key) != "url" {
+			continue
+		}
+		if match := remotePattern(host).FindStringSubmatch(strings.TrimSpace(value)); match != nil {
+			return match[1]
+		}
+		return ""
+	}
+	return ""
+}
+
+// resolvePath returns path, resolved against dir if it is relative.
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
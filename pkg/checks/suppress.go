@@ -0,0 +1,109 @@
+package checks
+
+import (
+	"regexp"
+	"strings"
+
+	"ghactionscheck/pkg/report"
+	"gopkg.in/yaml.v3"
+)
+
+// suppressionPattern matches inline suppression comments such as
+// "# ghactionscheck:disable=action_ref,timeout". Without "=", every check is
+// suppressed.
+var suppressionPattern = regexp.MustCompile(`ghactionscheck:disable(?:=([\w,-]+))?`)
+
+// suppression disables checks for the lines from start to end.
+type suppression struct {
+	start, end int
+	checks     []string
+}
+
+func (s suppression) matches(result report.Result) bool {
+	if result.Line < s.start || result.Line > s.end {
+		return false
+	}
+	if len(s.checks) == 0 {
+		return true
+	}
+	for _, id := range s.checks {
+		if id == result.CheckID {
+			return true
+		}
+	}
+	return false
+}
+
+// findSuppressions collects the suppression comments in a workflow document.
+// A comment at the top of the file applies to the whole file; a comment
+// above or at the end of a key applies to the key and its value, so placing
+// it on a job or step disables the checks for that whole block.
+func findSuppressions(doc *yaml.Node) []suppression {
+	var suppressions []suppression
+	add := func(comment string, start, end int) {
+		for _, match := range suppressionPattern.FindAllStringSubmatch(comment, -1) {
+			s := suppression{start: start, end: end}
+			if match[1] != "" {
+				for _, id := range strings.Split(match[1], ",") {
+					s.checks = append(s.checks, CanonicalID(id))
+				}
+			}
+			suppressions = append(suppressions, s)
+		}
+	}
+
+	add(doc.HeadComment, 0, lastLine(doc))
+
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, child := range node.Content {
+				walk(child)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				add(key.HeadComment+"\n"+key.LineComment, key.Line, lastLine(value))
+				add(value.HeadComment+"\n"+value.LineComment, value.Line, lastLine(value))
+				walk(value)
+			}
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				add(item.HeadComment+"\n"+item.LineComment, item.Line, lastLine(item))
+				walk(item)
+			}
+		}
+	}
+	walk(doc)
+
+	return suppressions
+}
+
+// lastLine returns the last line spanned by node.
+func lastLine(node *yaml.Node) int {
+	line := node.Line
+	for _, child := range node.Content {
+		if l := lastLine(child); l > line {
+			line = l
+		}
+	}
+	return line
+}
+
+func filterSuppressed(results []report.Result, suppressions []suppression) []report.Result {
+	var filtered []report.Result
+	for _, result := range results {
+		suppressed := false
+		for _, s := range suppressions {
+			if s.matches(result) {
+				suppressed = true
+				break
+			}
+		}
+		if !suppressed {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
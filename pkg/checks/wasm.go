@@ -0,0 +1,89 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// wasmMemoryLimitPages limits the memory of a WASM plugin to 128 MiB, in
+// pages of 64 KiB.
+const wasmMemoryLimitPages = 2048
+
+// wasmRuntime runs WASM plugins. Plugins are WASI commands using the same
+// protocol as executables, but are sandboxed: they see no files, network or
+// environment variables, only the input on stdin, their memory is limited to
+// wasmMemoryLimitPages and they are stopped after pluginTimeout.
+type wasmRuntime struct {
+	runtime wazero.Runtime
+	// modules caches compiled plugins by path, as compiling is much slower
+	// than running them.
+	modules map[string]wazero.CompiledModule
+}
+
+func newWasmRuntime(ctx context.Context) *wasmRuntime {
+	config := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(wasmMemoryLimitPages)
+	runtime := wazero.NewRuntimeWithConfig(ctx, config)
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+	return &wasmRuntime{runtime: runtime, modules: make(map[string]wazero.CompiledModule)}
+}
+
+func (w *wasmRuntime) compile(ctx context.Context, path string) (wazero.CompiledModule, error) {
+	if module, ok := w.modules[path]; ok {
+		return module, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	module, err := w.runtime.CompileModule(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	w.modules[path] = module
+	return module, nil
+}
+
+// run runs the WASM plugin at path with args, returning what it wrote to
+// stdout.
+func (w *wasmRuntime) run(path string, args []string, input []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+	module, err := w.compile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout bytes.Buffer
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(append([]string{filepath.Base(path)}, args...)...).
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(&stdout).
+		WithStderr(os.Stderr)
+	instance, err := w.runtime.InstantiateModule(ctx, module, config)
+	if err != nil {
+		var exitErr *sys.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == sys.ExitCodeDeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %s", pluginTimeout)
+		}
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 0 {
+			return nil, err
+		}
+	}
+	if instance != nil {
+		if err := instance.Close(ctx); err != nil {
+			return nil, fmt.Errorf("closing module: %v", err)
+		}
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,193 @@
+// Package expression parses the expressions of GitHub Actions workflows, the
+// ${{ }} placeholders and the conditions of if: keys, so checks can inspect
+// the contexts and functions they use.
+package expression
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Node is a node of a parsed expression.
+type Node interface {
+	// Pos returns the offset of the node in the expression.
+	Pos() int
+}
+
+// LiteralKind is the type of a literal.
+type LiteralKind int
+
+const (
+	Null LiteralKind = iota
+	Bool
+	Number
+	String
+)
+
+// Literal is a null, boolean, number or string literal. Value holds the
+// literal as written, except for strings, which are unquoted.
+type Literal struct {
+	Offset int
+	Kind   LiteralKind
+	Value  string
+}
+
+// Context is a reference to a context, such as github or steps.
+type Context struct {
+	Offset int
+	Name   string
+}
+
+// Property is a property access with a dot, or an index with a string
+// literal, such as github.event or github['event'].
+type Property struct {
+	Offset   int
+	Receiver Node
+	Name     string
+}
+
+// Index is an index with any other expression, such as matrix[inputs.key].
+type Index struct {
+	Offset   int
+	Receiver Node
+	Index    Node
+}
+
+// Filter is an object filter (.*), selecting the values of an array or
+// object.
+type Filter struct {
+	Offset   int
+	Receiver Node
+}
+
+// Call is a function call.
+type Call struct {
+	Offset int
+	Name   string
+	Args   []Node
+}
+
+// Not is a logical not.
+type Not struct {
+	Offset  int
+	Operand Node
+}
+
+// Binary is a comparison or a logical operation. Op is one of ==, !=, <,
+// <=, >, >=, && and ||.
+type Binary struct {
+	Offset      int
+	Op          string
+	Left, Right Node
+}
+
+func (n *Literal) Pos() int  { return n.Offset }
+func (n *Context) Pos() int  { return n.Offset }
+func (n *Property) Pos() int { return n.Offset }
+func (n *Index) Pos() int    { return n.Offset }
+func (n *Filter) Pos() int   { return n.Offset }
+func (n *Call) Pos() int     { return n.Offset }
+func (n *Not) Pos() int      { return n.Offset }
+func (n *Binary) Pos() int   { return n.Offset }
+
+// Error is a syntax error at an offset of the parsed text.
+type Error struct {
+	Offset  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Offset+1)
+}
+
+// Walk calls fn for node and, while fn returns true, for its children.
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+	switch n := node.(type) {
+	case *Property:
+		Walk(n.Receiver, fn)
+	case *Index:
+		Walk(n.Receiver, fn)
+		Walk(n.Index, fn)
+	case *Filter:
+		Walk(n.Receiver, fn)
+	case *Call:
+		for _, arg := range n.Args {
+			Walk(arg, fn)
+		}
+	case *Not:
+		Walk(n.Operand, fn)
+	case *Binary:
+		Walk(n.Left, fn)
+		Walk(n.Right, fn)
+	}
+}
+
+// Path returns the context and property names of a chain of property
+// accesses, such as ["github", "event", "inputs"] for github.event.inputs,
+// or nil if node is not one. Filters and other indexes end the chain.
+func Path(node Node) []string {
+	switch n := node.(type) {
+	case *Context:
+		return []string{n.Name}
+	case *Property:
+		if path := Path(n.Receiver); path != nil {
+			return append(path, n.Name)
+		}
+	}
+	return nil
+}
+
+// Placeholder is a ${{ }} placeholder of a value.
+type Placeholder struct {
+	// Offset is the offset of ${{ in the value, and Source the expression
+	// between the braces.
+	Offset int
+	Source string
+}
+
+// Placeholders returns the ${{ }} placeholders of value. An expression ends
+// at the first }} outside its string literals; the error reports a
+// placeholder that is never closed.
+func Placeholders(value string) ([]Placeholder, error) {
+	var placeholders []Placeholder
+	for offset := 0; ; {
+		start := strings.Index(value[offset:], "${{")
+		if start < 0 {
+			return placeholders, nil
+		}
+		start += offset
+		end := closingBraces(value, start+3)
+		if end < 0 {
+			return placeholders, &Error{Offset: start, Message: "unterminated ${{, missing }}"}
+		}
+		placeholders = append(placeholders, Placeholder{Offset: start, Source: value[start+3 : end]})
+		offset = end + 2
+	}
+}
+
+// closingBraces returns the offset of the }} closing the expression starting
+// at offset, or -1.
+func closingBraces(value string, offset int) int {
+	inString := false
+	for i := offset; i < len(value); i++ {
+		switch {
+		case value[i] == '\'':
+			inString = !inString
+		case !inString && strings.HasPrefix(value[i:], "}}"):
+			return i
+		}
+	}
+	return -1
+}
+
+// IsPlaceholder reports whether value is a single placeholder with nothing
+// around it but spaces.
+func IsPlaceholder(value string) bool {
+	value = strings.TrimSpace(value)
+	placeholders, err := Placeholders(value)
+	return err == nil && len(placeholders) == 1 && placeholders[0].Offset == 0 &&
+		len(placeholders[0].Source)+5 == len(value)
+}
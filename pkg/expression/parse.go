@@ -0,0 +1,371 @@
+package expression
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tokenKind is the kind of a lexical token.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+	tokenPunct
+)
+
+type token struct {
+	kind   tokenKind
+	value  string
+	offset int
+}
+
+// operators lists the operators, longest first so they are matched before
+// their prefixes.
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!"}
+
+// lex splits an expression into tokens.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, src[start:i], start})
+
+		case isDigit(c) || c == '-' && i+1 < len(src) && (isDigit(src[i+1]) || src[i+1] == '.') ||
+			c == '.' && i+1 < len(src) && isDigit(src[i+1]) && !afterOperand(tokens):
+			start := i
+			i++
+			for i < len(src) && (isIdentPart(src[i]) || src[i] == '.' ||
+				(src[i] == '+' || src[i] == '-') && (src[i-1] == 'e' || src[i-1] == 'E') && !strings.HasPrefix(src[start:], "0x")) {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, src[start:i], start})
+
+		case c == '\'':
+			start := i
+			var value strings.Builder
+			for i++; ; i++ {
+				if i >= len(src) {
+					return nil, &Error{Offset: start, Message: "unterminated string"}
+				}
+				if src[i] == '\'' {
+					if i+1 < len(src) && src[i+1] == '\'' {
+						value.WriteByte('\'')
+						i++
+						continue
+					}
+					i++
+					break
+				}
+				value.WriteByte(src[i])
+			}
+			tokens = append(tokens, token{tokenString, value.String(), start})
+
+		case strings.ContainsRune(".,()[]*", rune(c)):
+			tokens = append(tokens, token{tokenPunct, string(c), i})
+			i++
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{tokenOperator, op, i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &Error{Offset: i, Message: fmt.Sprintf("unexpected character %q", c)}
+			}
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(src)}), nil
+}
+
+// afterOperand reports whether the last token ends an operand, so a dot
+// after it is a property access rather than the start of a number.
+func afterOperand(tokens []token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	last := tokens[len(tokens)-1]
+	return last.kind == tokenIdent || last.value == ")" || last.value == "]"
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '-'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// parser is a recursive descent parser over the tokens of an expression.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses an expression, the text between the braces of a ${{ }}
+// placeholder or an if: condition without them.
+func Parse(src string) (Node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, &Error{Offset: 0, Message: "empty expression"}
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.unexpected(t)
+	}
+	return node, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator or punctuation value.
+func (p *parser) accept(value string) bool {
+	if t := p.peek(); (t.kind == tokenOperator || t.kind == tokenPunct) && t.value == value {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(value string) error {
+	if !p.accept(value) {
+		return p.unexpected(p.peek())
+	}
+	return nil
+}
+
+func (p *parser) unexpected(t token) error {
+	if t.kind == tokenEOF {
+		return &Error{Offset: t.offset, Message: "unexpected end of expression"}
+	}
+	return &Error{Offset: t.offset, Message: fmt.Sprintf("unexpected %q", t.value)}
+}
+
+// parseBinary parses a left-associative sequence of operands joined by ops.
+func (p *parser) parseBinary(operand func() (Node, error), ops ...string) (Node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		matched := false
+		for _, op := range ops {
+			if t.kind == tokenOperator && t.value == op {
+				matched = true
+			}
+		}
+		if !matched {
+			return left, nil
+		}
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Offset: t.offset, Op: t.value, Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseOr() (Node, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	return p.parseBinary(p.parseEquality, "&&")
+}
+
+func (p *parser) parseEquality() (Node, error) {
+	return p.parseBinary(p.parseComparison, "==", "!=")
+}
+
+func (p *parser) parseComparison() (Node, error) {
+	return p.parseBinary(p.parseUnary, "<", "<=", ">", ">=")
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if t := p.peek(); t.kind == tokenOperator && t.value == "!" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Offset: t.offset, Operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+// parsePostfix parses an operand followed by property accesses, indexes and
+// filters.
+func (p *parser) parsePostfix() (Node, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case p.accept("."):
+			if p.accept("*") {
+				node = &Filter{Offset: t.offset, Receiver: node}
+				continue
+			}
+			name := p.next()
+			if name.kind != tokenIdent {
+				return nil, p.unexpected(name)
+			}
+			node = &Property{Offset: t.offset, Receiver: node, Name: name.value}
+
+		case p.accept("["):
+			if p.accept("*") {
+				node = &Filter{Offset: t.offset, Receiver: node}
+			} else {
+				index, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				if literal, ok := index.(*Literal); ok && literal.Kind == String {
+					node = &Property{Offset: t.offset, Receiver: node, Name: literal.Value}
+				} else {
+					node = &Index{Offset: t.offset, Receiver: node, Index: index}
+				}
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		if !validNumber(t.value) {
+			return nil, &Error{Offset: t.offset, Message: fmt.Sprintf("invalid number %q", t.value)}
+		}
+		return &Literal{Offset: t.offset, Kind: Number, Value: t.value}, nil
+
+	case tokenString:
+		return &Literal{Offset: t.offset, Kind: String, Value: t.value}, nil
+
+	case tokenIdent:
+		switch t.value {
+		case "true", "false":
+			return &Literal{Offset: t.offset, Kind: Bool, Value: t.value}, nil
+		case "null":
+			return &Literal{Offset: t.offset, Kind: Null, Value: t.value}, nil
+		}
+		if !p.accept("(") {
+			return &Context{Offset: t.offset, Name: t.value}, nil
+		}
+		call := &Call{Offset: t.offset, Name: t.value}
+		if p.accept(")") {
+			return call, nil
+		}
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.Args = append(call.Args, arg)
+			if p.accept(")") {
+				return call, nil
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+	case tokenPunct:
+		if t.value == "(" {
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+	}
+	return nil, p.unexpected(t)
+}
+
+// validNumber reports whether s is a number literal: a decimal number with
+// an optional sign, fraction and exponent, or a hexadecimal one.
+func validNumber(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	if hex, ok := strings.CutPrefix(s, "0x"); ok {
+		if hex == "" {
+			return false
+		}
+		for _, c := range hex {
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return false
+			}
+		}
+		return true
+	}
+
+	mantissa, exponent, hasExponent := strings.Cut(strings.ToLower(s), "e")
+	whole, fraction, _ := strings.Cut(mantissa, ".")
+	if whole == "" && fraction == "" || !digits(whole) || !digits(fraction) {
+		return false
+	}
+	if hasExponent {
+		exponent = strings.TrimLeft(exponent, "+-")
+		return exponent != "" && digits(exponent)
+	}
+	return true
+}
+
+func digits(s string) bool {
+	for _, c := range s {
+		if !isDigit(byte(c)) {
+			return false
+		}
+	}
+	return true
+}
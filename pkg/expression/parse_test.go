@@ -0,0 +1,111 @@
+package expression
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		// err is the syntax error, or empty for a valid expression.
+		err string
+	}{
+		{name: "context", src: "github.ref"},
+		{name: "comparison", src: "github.event_name == 'push' && !cancelled()"},
+		{name: "nested parens", src: "((github.ref == 'refs/heads/main') || (github.ref == 'refs/heads/dev'))"},
+		{name: "call", src: "contains(github.event.head_commit.message, '[skip ci]')"},
+		{name: "string index", src: "github['event_name'] == 'push'"},
+		{name: "expression index", src: "matrix[inputs.key]"},
+		{name: "filter", src: "github.event.commits.*.message"},
+		{name: "filter index", src: "needs.build.outputs[*]"},
+		{name: "escaped quote", src: "format('it''s {0}', github.actor)"},
+		{name: "numbers", src: "0x1F < 1.5e3 && -2 != .5"},
+		{name: "empty", src: "  ", err: "empty expression at position 1"},
+		{name: "unclosed paren", src: "(github.ref == 'main'", err: "unexpected end of expression at position 22"},
+		{name: "extra paren", src: "github.ref == 'main')", err: `unexpected ")" at position 21`},
+		{name: "unclosed call", src: "contains(github.ref, 'main'", err: "unexpected end of expression at position 28"},
+		{name: "double dot", src: "github..x", err: `unexpected "." at position 8`},
+		{name: "trailing dot", src: "github.", err: "unexpected end of expression at position 8"},
+		{name: "unterminated string", src: "github.ref == 'main", err: "unterminated string at position 15"},
+		{name: "unclosed index", src: "matrix['os'", err: "unexpected end of expression at position 12"},
+		{name: "empty index", src: "matrix[]", err: `unexpected "]" at position 8`},
+		{name: "missing operand", src: "github.ref ==", err: "unexpected end of expression at position 14"},
+		{name: "invalid number", src: "1.2.3", err: `invalid number "1.2.3" at position 1`},
+		{name: "invalid character", src: "github.ref = 'main'", err: `unexpected character '=' at position 12`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("Parse(%q) = %v, want no error", tt.src, err)
+			case tt.err != "" && err == nil:
+				t.Errorf("Parse(%q) succeeded, want %q", tt.src, tt.err)
+			case tt.err != "" && err.Error() != tt.err:
+				t.Errorf("Parse(%q) = %q, want %q", tt.src, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	tests := []struct {
+		value   string
+		sources []string
+		err     bool
+	}{
+		{value: "make build"},
+		{value: "${{ github.ref }}", sources: []string{" github.ref "}},
+		{value: "echo ${{ inputs.a }} and ${{inputs.b}}", sources: []string{" inputs.a ", "inputs.b"}},
+		{value: "${{ format('}}{0}', github.ref) }}", sources: []string{" format('}}{0}', github.ref) "}},
+		{value: "echo ${{ github.ref", err: true},
+		{value: "${{ github.ref }} ${{ github.sha", sources: []string{" github.ref "}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			placeholders, err := Placeholders(tt.value)
+			if (err != nil) != tt.err {
+				t.Errorf("Placeholders(%q) error = %v, want error %t", tt.value, err, tt.err)
+			}
+			if len(placeholders) != len(tt.sources) {
+				t.Fatalf("Placeholders(%q) = %+v, want %q", tt.value, placeholders, tt.sources)
+			}
+			for i, placeholder := range placeholders {
+				if placeholder.Source != tt.sources[i] {
+					t.Errorf("placeholder %d = %q, want %q", i, placeholder.Source, tt.sources[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParsePaths(t *testing.T) {
+	tests := []struct {
+		src  string
+		path []string
+	}{
+		{src: "github.event.inputs", path: []string{"github", "event", "inputs"}},
+		{src: "github['event']['inputs']", path: []string{"github", "event", "inputs"}},
+		{src: "matrix[inputs.key]"},
+		{src: "steps.*.outputs"},
+		{src: "contains(github.ref, 'main')"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			node, err := Parse(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := Path(node)
+			if len(path) != len(tt.path) {
+				t.Fatalf("Path(%q) = %q, want %q", tt.src, path, tt.path)
+			}
+			for i := range path {
+				if path[i] != tt.path[i] {
+					t.Errorf("Path(%q) = %q, want %q", tt.src, path, tt.path)
+				}
+			}
+		})
+	}
+}
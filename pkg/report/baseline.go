@@ -0,0 +1,70 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Baseline holds the findings recorded in a baseline file, so that only the
+// findings added since are reported. Findings are matched by file, check,
+// job and untranslated message, ignoring their position, so they still match
+// when lines are added above them or the language of messages changes.
+type Baseline struct {
+	counts map[baselineKey]int
+}
+
+type baselineKey struct {
+	file, checkID, job, message string
+}
+
+func keyOf(result Result) baselineKey {
+	return baselineKey{filepath.ToSlash(result.File), result.CheckID, result.JobName, result.UntranslatedMessage()}
+}
+
+// WriteBaseline writes results as a baseline file, in the format of
+// WriteJSON.
+func WriteBaseline(out io.Writer, results []Result) error {
+	return WriteJSON(out, results)
+}
+
+// ReadBaseline reads a baseline file written by WriteBaseline, or the output
+// of the json format.
+func ReadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Findings []Result `json:"findings"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", path, err)
+	}
+
+	b := &Baseline{counts: make(map[baselineKey]int)}
+	for _, result := range file.Findings {
+		b.counts[keyOf(result)]++
+	}
+	return b, nil
+}
+
+// Filter returns the results not in the baseline. A finding recorded once
+// matches one result, so a second occurrence of it is new.
+func (b *Baseline) Filter(results []Result) []Result {
+	remaining := make(map[baselineKey]int, len(b.counts))
+	for key, count := range b.counts {
+		remaining[key] = count
+	}
+	var filtered []Result
+	for _, result := range results {
+		if key := keyOf(result); remaining[key] > 0 {
+			remaining[key]--
+			continue
+		}
+		filtered = append(filtered, result)
+	}
+	return filtered
+}
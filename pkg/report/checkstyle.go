@@ -0,0 +1,67 @@
+package report
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// The elements of a checkstyle XML report.
+type (
+	checkstyleReport struct {
+		XMLName xml.Name         `xml:"checkstyle"`
+		Version string           `xml:"version,attr"`
+		Files   []checkstyleFile `xml:"file"`
+	}
+
+	checkstyleFile struct {
+		Name   string            `xml:"name,attr"`
+		Errors []checkstyleError `xml:"error"`
+	}
+
+	checkstyleError struct {
+		Line     int    `xml:"line,attr"`
+		Column   int    `xml:"column,attr"`
+		Severity string `xml:"severity,attr"`
+		Message  string `xml:"message,attr"`
+		Source   string `xml:"source,attr"`
+	}
+)
+
+// checkstyleSeverities maps severities to those of checkstyle.
+var checkstyleSeverities = map[string]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+	SeverityNotice:  "info",
+}
+
+// WriteCheckstyle writes results as a checkstyle XML report, with an element
+// for each of files, empty when it has no findings. The source of each
+// finding is its check, prefixed with the tool name.
+func WriteCheckstyle(out io.Writer, files []string, results []Result) error {
+	byFile := make(map[string][]checkstyleError)
+	for _, result := range results {
+		byFile[result.File] = append(byFile[result.File], checkstyleError{
+			Line:     result.Line,
+			Column:   result.Column,
+			Severity: checkstyleSeverities[result.Severity],
+			Message:  result.Message,
+			Source:   toolName + "." + result.CheckID,
+		})
+	}
+
+	report := checkstyleReport{Version: "4.3"}
+	for _, file := range orderedFiles(files, results) {
+		report.Files = append(report.Files, checkstyleFile{Name: file, Errors: byFile[file]})
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
@@ -0,0 +1,19 @@
+package report
+
+import (
+	"fmt"
+	"io"
+)
+
+// WriteCompact writes each result on a line, as file:line:column: severity:
+// message [check], the format of compilers that editors and terminals link
+// to the file.
+func WriteCompact(out io.Writer, results []Result) error {
+	for _, result := range results {
+		if _, err := fmt.Fprintf(out, "%s:%d:%d: %s: %s [%s]\n",
+			result.File, result.Line, result.Column, result.Severity, result.Message, result.CheckID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvHeader lists the columns of the csv format, which are kept stable so
+// that spreadsheets and queries reading them keep working. New columns are
+// only added at the end.
+var csvHeader = []string{"file", "line", "column", "job", "step", "check_id", "severity", "message", "detail"}
+
+// WriteCSV writes results as CSV with a header row.
+func WriteCSV(out io.Writer, results []Result) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, result := range results {
+		record := []string{
+			csvCell(result.File),
+			strconv.Itoa(result.Line),
+			strconv.Itoa(result.Column),
+			csvCell(result.JobName),
+			csvCell(result.Step),
+			csvCell(result.CheckID),
+			csvCell(result.Severity),
+			csvCell(result.Message),
+			csvCell(result.Description),
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// csvCell escapes a cell that a spreadsheet would read as a formula, such as
+// a step name starting with =, by prefixing it with a quote.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
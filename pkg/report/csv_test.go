@@ -0,0 +1,47 @@
+package report
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	tests := []struct {
+		step string
+		want string
+	}{
+		{step: "Build", want: "Build"},
+		{step: "", want: ""},
+		{step: "=HYPERLINK(\"https://example.com\")", want: "'=HYPERLINK(\"https://example.com\")"},
+		{step: "+1", want: "'+1"},
+		{step: "-1", want: "'-1"},
+		{step: "@SUM(A1)", want: "'@SUM(A1)"},
+		{step: "\t=1", want: "'\t=1"},
+		{step: "\r=1", want: "'\r=1"},
+		{step: "a=1", want: "a=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.step, func(t *testing.T) {
+			var b strings.Builder
+			err := WriteCSV(&b, []Result{{File: "ci.yml", Line: 3, Column: 5, JobName: "build", Step: tt.step, CheckID: "timeout", Severity: SeverityWarning, Message: "No timeout specified"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			records, err := csv.NewReader(strings.NewReader(b.String())).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 2 {
+				t.Fatalf("got %d records, want a header and a finding", len(records))
+			}
+			record := records[1]
+			if step := record[4]; step != tt.want {
+				t.Errorf("step = %q, want %q", step, tt.want)
+			}
+			if record[1] != "3" || record[2] != "5" {
+				t.Errorf("line and column = %s and %s, want them unescaped", record[1], record[2])
+			}
+		})
+	}
+}
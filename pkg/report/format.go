@@ -0,0 +1,251 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// toolName names the tool in the formats that identify the linter.
+const toolName = "ghactionscheck"
+
+// Options control how the table format is rendered.
+type Options struct {
+	// Color styles findings by severity with ANSI escape sequences.
+	Color bool
+	// Summary replaces the findings with their number for each check and
+	// severity.
+	Summary bool
+	// MaxFindings, when positive, limits the findings written to the first
+	// ones. The table notes how many were left out.
+	MaxFindings int
+	// GroupBy splits the table into a table for each "job", "check" or
+	// "file", in the order they are first found. It is empty to write a
+	// single table.
+	GroupBy string
+	// Checks are the ids of the checks that ran, for the junit format to
+	// list those that passed. Without them, only the checks with findings
+	// are listed.
+	Checks []string
+	// Stats, when set, are appended to the table and included in the other
+	// formats where they have a place. Those with none, the rdjsonl,
+	// checkstyle, csv and compact formats, which other tools read line by
+	// line or row by row, have them written to stderr instead.
+	Stats *Stats
+}
+
+// Write writes the results of checking files in format.
+func Write(out io.Writer, format string, files []string, results []Result, options Options) error {
+	if format == "table" && options.Summary {
+		WriteSummary(out, files, results)
+		writeStats(out, options.Stats, results, true)
+		return nil
+	}
+	stats := NewStatistics(options.Stats, results)
+	all := results
+	omitted := 0
+	if options.MaxFindings > 0 && len(results) > options.MaxFindings {
+		omitted = len(results) - options.MaxFindings
+		results = results[:options.MaxFindings]
+	}
+
+	switch format {
+	case "table":
+		WriteTable(out, files, results, options)
+		if omitted > 0 {
+			fmt.Fprintf(out, "%d more findings not shown\n", omitted)
+		}
+		writeStats(out, options.Stats, all, false)
+		return nil
+	case "json":
+		return writeJSON(out, results, stats)
+	case "rdjson":
+		return writeRDJSON(out, results, stats)
+	case "rdjsonl":
+		return statsToStderr(WriteRDJSONL(out, results), options.Stats, all)
+	case "junit":
+		return writeJUnit(out, files, results, options.Checks, options.Stats)
+	case "checkstyle":
+		return statsToStderr(WriteCheckstyle(out, files, results), options.Stats, all)
+	case "markdown":
+		return writeMarkdownStats(out, files, results, stats)
+	case "html":
+		return writeHTML(out, files, results, stats)
+	case "csv":
+		return statsToStderr(WriteCSV(out, results), options.Stats, all)
+	case "tap":
+		return writeTAP(out, files, results, stats)
+	case "sarif":
+		return writeSARIF(out, results, stats)
+	case "github":
+		return writeGitHubAnnotations(out, results, stats)
+	case "compact":
+		return statsToStderr(WriteCompact(out, results), options.Stats, all)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// statsToStderr writes the statistics of a run to stderr after a report
+// written without error in a format that has no place for them.
+func statsToStderr(err error, stats *Stats, results []Result) error {
+	if err == nil {
+		writeStats(os.Stderr, stats, results, false)
+	}
+	return err
+}
+
+// WriteJSON writes results as a JSON object with a findings array.
+func WriteJSON(out io.Writer, results []Result) error {
+	return writeJSON(out, results, nil)
+}
+
+// writeJSON writes results as WriteJSON does, followed by the statistics of
+// the run if any.
+func writeJSON(out io.Writer, results []Result, stats *Statistics) error {
+	if results == nil {
+		results = []Result{}
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(struct {
+		Findings []Result    `json:"findings"`
+		Stats    *Statistics `json:"stats,omitempty"`
+	}{results, stats})
+}
+
+// WriteTable writes a table of the results. The results of several files are
+// aggregated in one table with a file column, followed by the files without
+// findings. With color, errors are red, warnings yellow and notices dim.
+func WriteTable(out io.Writer, files []string, results []Result, options Options) {
+	if len(files) == 1 {
+		fmt.Fprintln(out, files[0])
+		if len(results) == 0 {
+			fmt.Fprintln(out, "No issues found!")
+			return
+		}
+		writeFindings(out, results, tableColumns{job: true}, options)
+		return
+	}
+
+	found := make(map[string]bool)
+	for _, result := range results {
+		found[result.File] = true
+	}
+	if len(results) > 0 {
+		writeFindings(out, results, tableColumns{file: true, job: true}, options)
+	}
+	for _, file := range files {
+		if !found[file] {
+			fmt.Fprintf(out, "%s: No issues found!\n", file)
+		}
+	}
+}
+
+// dim is the SGR parameter of faint text, which tablewriter has no constant
+// for.
+const dim = 2
+
+// severityColors are the colors of the severity column of each severity.
+var severityColors = map[string]tablewriter.Colors{
+	SeverityError:   {tablewriter.Bold, tablewriter.FgRedColor},
+	SeverityWarning: {tablewriter.Bold, tablewriter.FgYellowColor},
+	SeverityNotice:  {dim},
+}
+
+// tableColumns are the optional columns of a table of findings.
+type tableColumns struct {
+	file, job bool
+}
+
+// writeFindings writes the results as a table, or as a table for each group
+// of options.GroupBy, headed by the group and leaving out its column.
+func writeFindings(out io.Writer, results []Result, columns tableColumns, options Options) {
+	var group func(Result) string
+	switch options.GroupBy {
+	case "file":
+		group = func(result Result) string { return result.File }
+		columns.file = false
+	case "job":
+		withFile := columns.file
+		group = func(result Result) string {
+			if withFile {
+				return result.File + ": " + result.JobName
+			}
+			return result.JobName
+		}
+		columns.file, columns.job = false, false
+	case "check":
+		group = func(result Result) string { return result.CheckID }
+	default:
+		writeTable(out, results, columns, options.Color)
+		return
+	}
+
+	var groups []string
+	byGroup := make(map[string][]Result)
+	for _, result := range results {
+		name := group(result)
+		if byGroup[name] == nil {
+			groups = append(groups, name)
+		}
+		byGroup[name] = append(byGroup[name], result)
+	}
+	for i, name := range groups {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "%s (%d findings)\n", name, len(byGroup[name]))
+		writeTable(out, byGroup[name], columns, options.Color)
+	}
+}
+
+func writeTable(out io.Writer, results []Result, columns tableColumns, color bool) {
+	table := tablewriter.NewWriter(out)
+	header := []string{"Line", "Severity", "Message", "Description"}
+	if columns.job {
+		header = slices.Insert(header, 2, "Job")
+	}
+	if columns.file {
+		header = append([]string{"File"}, header...)
+	}
+	table.SetHeader(header)
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetCenterSeparator("|")
+	table.SetRowLine(true)
+
+	for _, result := range results {
+		row := []string{
+			fmt.Sprintf("%d:%d", result.Line, result.Column),
+			result.Severity,
+			result.Message,
+			result.Description,
+		}
+		if columns.job {
+			row = slices.Insert(row, 2, result.JobName)
+		}
+		if columns.file {
+			row = append([]string{result.File}, row...)
+		}
+		if !color {
+			table.Append(row)
+			continue
+		}
+		// Notices are dimmed entirely, the others only in their severity.
+		colors := make([]tablewriter.Colors, len(row))
+		for i := range colors {
+			if result.Severity == SeverityNotice {
+				colors[i] = tablewriter.Colors{dim}
+			}
+		}
+		colors[slices.Index(header, "Severity")] = severityColors[result.Severity]
+		table.Rich(row, colors)
+	}
+
+	table.Render()
+}
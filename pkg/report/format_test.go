@@ -0,0 +1,134 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testResults = []Result{
+	{File: "ci.yml", Line: 4, Column: 5, JobName: "build", CheckID: "timeout", Message: "No timeout specified", Severity: SeverityWarning},
+	{File: "ci.yml", Line: 6, Column: 9, JobName: "build", CheckID: "action_ref", Message: "Non-commit hash reference", Severity: SeverityWarning},
+	{File: "ci.yml", Line: 8, Column: 9, JobName: "build", CheckID: "action_ref", Message: "Non-commit hash reference", Severity: SeverityWarning},
+}
+
+var testStats = &Stats{Files: 1, Jobs: 1, Elapsed: 1500 * time.Millisecond}
+
+// writeReport writes testResults in format with options, returning the
+// report and what was written to stderr.
+func writeReport(t *testing.T, format string, options Options) (string, string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	var out strings.Builder
+	err = Write(&out, format, []string{"ci.yml", "release.yml"}, testResults, options)
+	os.Stderr = stderr
+	w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.String(), string(written)
+}
+
+func TestWriteStats(t *testing.T) {
+	tests := []struct {
+		format string
+		// out is expected in the report, or err on stderr.
+		out, err string
+	}{
+		{format: "table", out: "Checked 1 file and 1 job in 1.5s\n3 findings: 0 errors, 3 warnings, 0 notices\nBy check: action_ref 2, timeout 1\n"},
+		{format: "json", out: `"stats": {`},
+		{format: "rdjson", out: `"stats": {`},
+		{format: "sarif", out: `"stats": {`},
+		{format: "junit", out: `time="1.500"`},
+		{format: "markdown", out: "Checked 1 file and 1 job in 1.5s."},
+		{format: "html", out: "<h2>Statistics</h2>\n<p>Checked 1 file and 1 job in 1.5s.</p>"},
+		{format: "tap", out: "# Checked 1 file and 1 job in 1.5s\n# 3 findings: 0 errors, 3 warnings, 0 notices\n# By check: action_ref 2, timeout 1\n"},
+		{format: "github", out: "::notice title=ghactionscheck statistics::Checked 1 file and 1 job in 1.5s%0A3 findings"},
+		{format: "rdjsonl", err: "Checked 1 file and 1 job in 1.5s"},
+		{format: "checkstyle", err: "Checked 1 file and 1 job in 1.5s"},
+		{format: "csv", err: "Checked 1 file and 1 job in 1.5s"},
+		{format: "compact", err: "Checked 1 file and 1 job in 1.5s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out, stderr := writeReport(t, tt.format, Options{Stats: testStats})
+			if tt.out != "" && !strings.Contains(out, tt.out) {
+				t.Errorf("report doesn't contain %q:\n%s", tt.out, out)
+			}
+			if tt.err != "" && !strings.Contains(stderr, tt.err) {
+				t.Errorf("stderr doesn't contain %q:\n%s", tt.err, stderr)
+			}
+			if tt.err != "" && strings.Contains(out, "Checked") {
+				t.Errorf("report has the statistics, want them on stderr only:\n%s", out)
+			}
+			if tt.out != "" && stderr != "" {
+				t.Errorf("stderr = %q, want nothing", stderr)
+			}
+
+			out, stderr = writeReport(t, tt.format, Options{})
+			if strings.Contains(out, "Checked") || strings.Contains(out, "stats") || stderr != "" {
+				t.Errorf("without stats, got the report:\n%s\nand stderr %q", out, stderr)
+			}
+		})
+	}
+}
+
+func TestWriteStatsCountsOmittedFindings(t *testing.T) {
+	out, _ := writeReport(t, "json", Options{Stats: testStats, MaxFindings: 1})
+	var report struct {
+		Findings []Result
+		Stats    Statistics
+	}
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Findings) != 1 || report.Stats.Findings != 3 || report.Stats.Checks["action_ref"] != 2 {
+		t.Errorf("got %d findings and stats %+v, want 1 finding and the 3 of the run counted", len(report.Findings), report.Stats)
+	}
+}
+
+func TestWriteJUnitChecks(t *testing.T) {
+	out, _ := writeReport(t, "junit", Options{Checks: []string{"action_ref", "step_name", "timeout"}})
+	var suites junitTestSuites
+	if err := xml.Unmarshal([]byte(out), &suites); err != nil {
+		t.Fatal(err)
+	}
+	if len(suites.Suites) != 2 {
+		t.Fatalf("got %d test suites, want one for each file", len(suites.Suites))
+	}
+
+	failures := make(map[string]int)
+	for _, c := range suites.Suites[0].Cases {
+		failures[c.Name] = len(c.Failures)
+	}
+	want := map[string]int{"action_ref": 2, "step_name": 0, "timeout": 1}
+	for name, n := range want {
+		if got, ok := failures[name]; !ok || got != n {
+			t.Errorf("test case %s of ci.yml has %d failures (listed: %t), want %d", name, got, ok, n)
+		}
+	}
+	if len(failures) != len(want) {
+		t.Errorf("ci.yml test cases = %v, want %v", failures, want)
+	}
+	if suites.Suites[0].Failures != 2 {
+		t.Errorf("ci.yml has %d failed test cases, want 2", suites.Suites[0].Failures)
+	}
+	for _, c := range suites.Suites[1].Cases {
+		if len(c.Failures) > 0 {
+			t.Errorf("test case %s of release.yml failed, want it passed", c.Name)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// githubDataEscaper and githubPropertyEscaper escape the message and the
+// properties of workflow commands, as done by @actions/core.
+var (
+	githubDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// WriteGitHubAnnotations writes results as workflow commands, which GitHub
+// Actions shows as annotations on the lines of the findings in the run and
+// in the files changed by pull requests. The severities map to the error,
+// warning and notice commands.
+func WriteGitHubAnnotations(out io.Writer, results []Result) error {
+	return writeGitHubAnnotations(out, results, nil)
+}
+
+// writeGitHubAnnotations writes results as WriteGitHubAnnotations does,
+// followed by a notice with the statistics of the run if stats are given.
+func writeGitHubAnnotations(out io.Writer, results []Result, stats *Statistics) error {
+	for _, result := range results {
+		properties := []string{"file=" + githubPropertyEscaper.Replace(filepath.ToSlash(result.File))}
+		if result.Line > 0 {
+			properties = append(properties, fmt.Sprintf("line=%d", result.Line))
+		}
+		if result.Column > 0 {
+			properties = append(properties, fmt.Sprintf("col=%d", result.Column))
+		}
+		properties = append(properties, "title="+githubPropertyEscaper.Replace(toolName+" "+result.CheckID))
+
+		message := result.Message
+		if result.Description != "" {
+			message += "\n" + result.Description
+		}
+		if _, err := fmt.Fprintf(out, "::%s %s::%s\n", result.Severity, strings.Join(properties, ","), githubDataEscaper.Replace(message)); err != nil {
+			return err
+		}
+	}
+	if lines := statsLines(stats); lines != nil {
+		title := githubPropertyEscaper.Replace(toolName + " statistics")
+		if _, err := fmt.Fprintf(out, "::notice title=%s::%s\n", title, githubDataEscaper.Replace(strings.Join(lines, "\n"))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"sort"
+)
+
+// htmlTemplate is the standalone HTML report. Its script filters the rows
+// of the findings table by severity, check and file.
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>ghactionscheck report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+.summary td:last-child { text-align: right; }
+.error { color: #cf222e; font-weight: bold; }
+.warning { color: #9a6700; font-weight: bold; }
+.notice { color: #0969da; }
+.filters { margin: 1em 0; }
+.filters label { margin-right: 1em; }
+.muted { color: #656d76; }
+</style>
+</head>
+<body>
+<h1>ghactionscheck report</h1>
+<p>{{len .Results}} findings in {{.FilesWithFindings}} of {{len .Files}} files.</p>
+<table class="summary">
+<tr><th>Severity</th><th>Findings</th></tr>
+{{range .Severities}}<tr><td class="{{.Name}}">{{.Name}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{with .Stats}}
+<h2>Statistics</h2>
+<p>{{.Summary}}.</p>
+{{if .Checks}}<table class="summary">
+<tr><th>Check</th><th>Findings</th></tr>
+{{range .Checks}}<tr><td><code>{{.ID}}</code></td><td>{{.Count}}</td></tr>
+{{end}}</table>{{end}}
+{{end}}
+{{if .Results}}
+<div class="filters">
+<label>Severity <select id="severity"><option value="">All</option>{{range .Severities}}{{if .Count}}<option>{{.Name}}</option>{{end}}{{end}}</select></label>
+<label>Check <select id="check"><option value="">All</option>{{range .Checks}}<option>{{.}}</option>{{end}}</select></label>
+<label>File <select id="file"><option value="">All</option>{{range .Files}}<option>{{.}}</option>{{end}}</select></label>
+<span id="shown" class="muted"></span>
+</div>
+<table id="findings">
+<thead><tr><th>File</th><th>Line</th><th>Severity</th><th>Job</th><th>Check</th><th>Message</th><th>Detail</th></tr></thead>
+<tbody>
+{{range .Results}}<tr data-severity="{{.Severity}}" data-check="{{.CheckID}}" data-file="{{.File}}">
+<td>{{.File}}</td><td>{{.Line}}:{{.Column}}</td><td class="{{.Severity}}">{{.Severity}}</td><td>{{.JobName}}</td>
+<td>{{if .URL}}<a href="{{.URL}}"><code>{{.CheckID}}</code></a>{{else}}<code>{{.CheckID}}</code>{{end}}</td>
+<td>{{.Message}}</td><td>{{.Description}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+<script>
+const filters = ["severity", "check", "file"].map(id => document.getElementById(id));
+function filter() {
+  let shown = 0;
+  for (const row of document.querySelectorAll("#findings tbody tr")) {
+    const visible = filters.every(select => !select.value || row.dataset[select.id] === select.value);
+    row.hidden = !visible;
+    if (visible) shown++;
+  }
+  document.getElementById("shown").textContent = shown + " shown";
+}
+filters.forEach(select => select.addEventListener("change", filter));
+filter();
+</script>
+{{else}}
+<p>No issues found!</p>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes results as a standalone HTML report, with a summary of
+// the findings by severity and a table of them that can be filtered by
+// severity, check and file.
+func WriteHTML(out io.Writer, files []string, results []Result) error {
+	return writeHTML(out, files, results, nil)
+}
+
+// htmlStats are the statistics of the run in the HTML report: the files,
+// jobs and time, and the checks having the most findings first.
+type htmlStats struct {
+	Summary string
+	Checks  []htmlCheckCount
+}
+
+type htmlCheckCount struct {
+	ID    string
+	Count int
+}
+
+// writeHTML writes results as WriteHTML does, with a section of the
+// statistics of the run if stats are given.
+func writeHTML(out io.Writer, files []string, results []Result, stats *Statistics) error {
+	type severityCount struct {
+		Name  string
+		Count int
+	}
+	counts := make(map[string]int)
+	withFindings := make(map[string]bool)
+	seen := make(map[string]bool)
+	var checks []string
+	for _, result := range results {
+		counts[result.Severity]++
+		withFindings[result.File] = true
+		if !seen[result.CheckID] {
+			seen[result.CheckID] = true
+			checks = append(checks, result.CheckID)
+		}
+	}
+	sort.Strings(checks)
+	var severities []severityCount
+	for _, severity := range []string{SeverityError, SeverityWarning, SeverityNotice} {
+		severities = append(severities, severityCount{severity, counts[severity]})
+	}
+
+	var runStats *htmlStats
+	if lines := statsLines(stats); lines != nil {
+		runStats = &htmlStats{Summary: lines[0]}
+		for _, id := range stats.checksByCount() {
+			runStats.Checks = append(runStats.Checks, htmlCheckCount{id, stats.Checks[id]})
+		}
+	}
+
+	return htmlTemplate.Execute(out, struct {
+		Files             []string
+		FilesWithFindings int
+		Results           []Result
+		Severities        []severityCount
+		Checks            []string
+		Stats             *htmlStats
+	}{orderedFiles(files, results), len(withFindings), results, severities, checks, runStats})
+}
@@ -0,0 +1,125 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// The elements of a JUnit XML report.
+type (
+	junitTestSuites struct {
+		XMLName  xml.Name         `xml:"testsuites"`
+		Name     string           `xml:"name,attr"`
+		Tests    int              `xml:"tests,attr"`
+		Failures int              `xml:"failures,attr"`
+		Time     string           `xml:"time,attr,omitempty"`
+		Suites   []junitTestSuite `xml:"testsuite"`
+	}
+
+	junitTestSuite struct {
+		Name     string          `xml:"name,attr"`
+		Tests    int             `xml:"tests,attr"`
+		Failures int             `xml:"failures,attr"`
+		Cases    []junitTestCase `xml:"testcase"`
+	}
+
+	junitTestCase struct {
+		Name      string         `xml:"name,attr"`
+		ClassName string         `xml:"classname,attr"`
+		Failures  []junitFailure `xml:"failure"`
+	}
+
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Type    string `xml:"type,attr"`
+		Text    string `xml:",chardata"`
+	}
+)
+
+// WriteJUnit writes results as a JUnit XML report with a test suite for each
+// of files. Each check with findings in a file is a failed test case named
+// after it, with a failure for each finding, so CI systems list findings
+// like failed tests, and a file without findings has a single passed test
+// case.
+func WriteJUnit(out io.Writer, files []string, results []Result) error {
+	return writeJUnit(out, files, results, nil, nil)
+}
+
+// writeJUnit writes results as WriteJUnit does, with a test case for each of
+// checks in each file, followed by the other checks with findings, so that
+// the checks passing are listed as passed tests. The time of the run, if
+// given by stats, is that of the test suites.
+func writeJUnit(out io.Writer, files []string, results []Result, checks []string, stats *Stats) error {
+	type fileCheck struct{ file, checkID string }
+	byCheck := make(map[fileCheck][]Result)
+	byFile := make(map[string][]string)
+	for _, result := range results {
+		key := fileCheck{result.File, result.CheckID}
+		if byCheck[key] == nil && !slices.Contains(checks, result.CheckID) {
+			byFile[result.File] = append(byFile[result.File], result.CheckID)
+		}
+		byCheck[key] = append(byCheck[key], result)
+	}
+
+	report := junitTestSuites{Name: toolName}
+	if stats != nil {
+		report.Time = fmt.Sprintf("%.3f", stats.Elapsed.Seconds())
+	}
+	for _, file := range orderedFiles(files, results) {
+		suite := junitTestSuite{Name: file}
+		for _, checkID := range slices.Concat(checks, byFile[file]) {
+			testCase := junitTestCase{Name: checkID, ClassName: file}
+			for _, result := range byCheck[fileCheck{file, checkID}] {
+				testCase.Failures = append(testCase.Failures, junitFailure{
+					Message: result.Message,
+					Type:    result.Severity,
+					Text:    fmt.Sprintf("%s:%d:%d: %s (job %s)\n%s", file, result.Line, result.Column, result.Message, result.JobName, result.Description),
+				})
+			}
+			if len(testCase.Failures) > 0 {
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+		if len(suite.Cases) == 0 {
+			suite.Cases = []junitTestCase{{Name: toolName, ClassName: file}}
+		}
+		suite.Tests = len(suite.Cases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+// orderedFiles returns files followed by the other files of results, in
+// order and without duplicates.
+func orderedFiles(files []string, results []Result) []string {
+	var ordered []string
+	seen := make(map[string]bool)
+	add := func(file string) {
+		if !seen[file] {
+			seen[file] = true
+			ordered = append(ordered, file)
+		}
+	}
+	for _, file := range files {
+		add(file)
+	}
+	for _, result := range results {
+		add(result.File)
+	}
+	return ordered
+}
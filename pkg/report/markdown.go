@@ -0,0 +1,98 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteMarkdown writes results as a Markdown report: a summary table of the
+// findings by severity, then a section for each file with findings, listing
+// them, its score and how to resolve them with links to the documentation
+// of their checks. The files without findings are listed last.
+func WriteMarkdown(out io.Writer, files []string, results []Result) error {
+	return writeMarkdownStats(out, files, results, nil)
+}
+
+// writeMarkdownStats writes results as WriteMarkdown does, ending with the
+// files and jobs checked and the time taken if stats are given.
+func writeMarkdownStats(out io.Writer, files []string, results []Result, stats *Statistics) error {
+	var b strings.Builder
+	writeMarkdown(&b, files, results)
+	if stats != nil {
+		fmt.Fprintf(&b, "\nChecked %s and %s in %s.\n", plural(stats.Files, "file"), plural(stats.Jobs, "job"), time.Duration(stats.ElapsedMS)*time.Millisecond)
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func writeMarkdown(b *strings.Builder, files []string, results []Result) {
+	byFile := make(map[string][]Result)
+	counts := make(map[string]int)
+	for _, result := range results {
+		byFile[result.File] = append(byFile[result.File], result)
+		counts[result.Severity]++
+	}
+	ordered := orderedFiles(files, results)
+
+	fmt.Fprintf(b, "# %s report\n\n", toolName)
+	fmt.Fprintf(b, "%d findings in %d of %d files.\n\n", len(results), len(byFile), len(ordered))
+	b.WriteString("| Severity | Findings |\n| --- | ---: |\n")
+	for _, severity := range []string{SeverityError, SeverityWarning, SeverityNotice} {
+		fmt.Fprintf(b, "| %s | %d |\n", severity, counts[severity])
+	}
+
+	var clean []string
+	for _, file := range ordered {
+		fileResults := byFile[file]
+		if len(fileResults) == 0 {
+			clean = append(clean, file)
+			continue
+		}
+		fmt.Fprintf(b, "\n## %s\n\n", markdownCell(file))
+		fmt.Fprintf(b, "Score: %d/100\n\n", Score(fileResults))
+		b.WriteString("| Line | Severity | Job | Check | Message |\n| --- | --- | --- | --- | --- |\n")
+		var checks []Result
+		seen := make(map[string]bool)
+		for _, result := range fileResults {
+			fmt.Fprintf(b, "| %d:%d | %s | %s | %s | %s |\n", result.Line, result.Column, result.Severity,
+				markdownCell(result.JobName), markdownCheck(result), markdownCell(result.Message))
+			if !seen[result.CheckID] {
+				seen[result.CheckID] = true
+				checks = append(checks, result)
+			}
+		}
+
+		b.WriteString("\nHow to resolve:\n\n")
+		for _, check := range checks {
+			fmt.Fprintf(b, "- %s: %s\n", markdownCheck(check), markdownCell(check.Description))
+		}
+	}
+
+	if len(clean) > 0 {
+		b.WriteString("\n## Files without findings\n\n")
+		for _, file := range clean {
+			fmt.Fprintf(b, "- %s\n", markdownCell(file))
+		}
+	}
+}
+
+// markdownCheck returns the id of the check of result, linked to its
+// documentation if it has any.
+func markdownCheck(result Result) string {
+	id := "`" + result.CheckID + "`"
+	if result.URL == "" {
+		return id
+	}
+	return "[" + id + "](" + result.URL + ")"
+}
+
+// markdownCell escapes text for a table cell or list item, which must stay
+// on one line and can't contain unescaped pipes.
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "\\", "\\\\")
+	text = strings.ReplaceAll(text, "|", "\\|")
+	text = strings.ReplaceAll(text, "<", "&lt;")
+	return strings.Join(strings.Fields(text), " ")
+}
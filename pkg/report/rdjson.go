@@ -0,0 +1,105 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// The types of the Reviewdog Diagnostic Format, which reviewdog reads with
+// -f=rdjson or -f=rdjsonl to post findings as review comments.
+type (
+	rdjsonResult struct {
+		Source      rdjsonSource       `json:"source"`
+		Diagnostics []rdjsonDiagnostic `json:"diagnostics"`
+		// Stats are the statistics of the run, which reviewdog ignores.
+		Stats *Statistics `json:"stats,omitempty"`
+	}
+
+	rdjsonSource struct {
+		Name string `json:"name"`
+	}
+
+	rdjsonDiagnostic struct {
+		Message  string         `json:"message"`
+		Location rdjsonLocation `json:"location"`
+		Severity string         `json:"severity"`
+		Source   rdjsonSource   `json:"source"`
+		Code     rdjsonCode     `json:"code"`
+	}
+
+	rdjsonLocation struct {
+		Path  string      `json:"path"`
+		Range rdjsonRange `json:"range"`
+	}
+
+	rdjsonRange struct {
+		Start rdjsonPosition `json:"start"`
+	}
+
+	rdjsonPosition struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	}
+
+	rdjsonCode struct {
+		Value string `json:"value"`
+	}
+)
+
+// rdjsonSeverities maps severities to those of the format.
+var rdjsonSeverities = map[string]string{
+	SeverityError:   "ERROR",
+	SeverityWarning: "WARNING",
+	SeverityNotice:  "INFO",
+}
+
+// WriteRDJSON writes results as a Reviewdog Diagnostic Format result.
+func WriteRDJSON(out io.Writer, results []Result) error {
+	return writeRDJSON(out, results, nil)
+}
+
+// writeRDJSON writes results as WriteRDJSON does, with the statistics of
+// the run if any.
+func writeRDJSON(out io.Writer, results []Result, stats *Statistics) error {
+	diagnostics := make([]rdjsonDiagnostic, len(results))
+	for i, result := range results {
+		diagnostics[i] = rdjsonDiagnosticOf(result)
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(rdjsonResult{Source: rdjsonSource{Name: toolName}, Diagnostics: diagnostics, Stats: stats})
+}
+
+// WriteRDJSONL writes results as Reviewdog Diagnostic Format diagnostics,
+// one per line.
+func WriteRDJSONL(out io.Writer, results []Result) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	for _, result := range results {
+		if err := encoder.Encode(rdjsonDiagnosticOf(result)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rdjsonDiagnosticOf returns the diagnostic of result, whose message is
+// followed by the detail of the check so the review comment says how to
+// resolve it.
+func rdjsonDiagnosticOf(result Result) rdjsonDiagnostic {
+	message := result.Message
+	if result.Description != "" {
+		message += "\n\n" + result.Description
+	}
+	return rdjsonDiagnostic{
+		Message: message,
+		Location: rdjsonLocation{
+			Path:  result.File,
+			Range: rdjsonRange{Start: rdjsonPosition{Line: result.Line, Column: result.Column}},
+		},
+		Severity: rdjsonSeverities[result.Severity],
+		Source:   rdjsonSource{Name: toolName},
+		Code:     rdjsonCode{Value: result.CheckID},
+	}
+}
@@ -0,0 +1,87 @@
+// Package report defines the findings of ghactionscheck and writes them in
+// the supported output formats.
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Result is a finding of a check in a workflow file.
+type Result struct {
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+	Column      int    `json:"column"`
+	JobName     string `json:"job"`
+	Step        string `json:"step,omitempty"`
+	CheckID     string `json:"check_id"`
+	Message     string `json:"message"`
+	Description string `json:"detail"`
+	URL         string `json:"url,omitempty"`
+	Severity    string `json:"severity"`
+	Category    string `json:"category,omitempty"`
+
+	// SourceMessage is the message before it was translated, if it was.
+	SourceMessage string `json:"source_message,omitempty"`
+}
+
+// UntranslatedMessage returns the message of the result before it was
+// translated, which identifies the finding whatever the language.
+func (r Result) UntranslatedMessage() string {
+	if r.SourceMessage != "" {
+		return r.SourceMessage
+	}
+	return r.Message
+}
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityNotice  = "notice"
+
+	DefaultSeverity = SeverityWarning
+)
+
+// NormalizeSeverity validates a configured severity, defaulting to
+// DefaultSeverity when empty and accepting "info" as an alias for "notice".
+func NormalizeSeverity(severity string) (string, error) {
+	switch strings.ToLower(severity) {
+	case "":
+		return DefaultSeverity, nil
+	case SeverityError:
+		return SeverityError, nil
+	case SeverityWarning:
+		return SeverityWarning, nil
+	case SeverityNotice, "info":
+		return SeverityNotice, nil
+	}
+	return "", fmt.Errorf("unknown severity %q", severity)
+}
+
+// SeverityRank orders severities so that more severe findings rank higher.
+// Unknown severities rank 0 and never trigger failure.
+func SeverityRank(severity string) int {
+	switch severity {
+	case SeverityError:
+		return 3
+	case SeverityWarning:
+		return 2
+	case SeverityNotice:
+		return 1
+	}
+	return 0
+}
+
+// ShouldFail reports whether any result has at least the failOn severity.
+func ShouldFail(results []Result, failOn string) bool {
+	threshold := SeverityRank(failOn)
+	if threshold == 0 {
+		return false
+	}
+	for _, result := range results {
+		if SeverityRank(result.Severity) >= threshold {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+)
+
+// The objects of a SARIF 2.1.0 log.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+
+	sarifRun struct {
+		Tool       sarifTool      `json:"tool"`
+		Results    []sarifResult  `json:"results"`
+		Properties *sarifRunStats `json:"properties,omitempty"`
+	}
+
+	sarifRunStats struct {
+		Stats *Statistics `json:"stats"`
+	}
+
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+
+	sarifRule struct {
+		ID                   string             `json:"id"`
+		ShortDescription     sarifMessage       `json:"shortDescription"`
+		HelpURI              string             `json:"helpUri,omitempty"`
+		DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	}
+
+	sarifConfiguration struct {
+		Level string `json:"level"`
+	}
+
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		RuleIndex int             `json:"ruleIndex"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+
+	sarifLocation struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region struct {
+				StartLine   int `json:"startLine"`
+				StartColumn int `json:"startColumn"`
+			} `json:"region"`
+		} `json:"physicalLocation"`
+	}
+)
+
+// sarifLevels maps severities to the levels of SARIF.
+var sarifLevels = map[string]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+	SeverityNotice:  "note",
+}
+
+// WriteSARIF writes results as a SARIF 2.1.0 log, as read by GitHub code
+// scanning, with a rule for each check that has findings. The files are
+// given by their paths, which code scanning expects to be relative to the
+// root of the repository.
+func WriteSARIF(out io.Writer, results []Result) error {
+	return writeSARIF(out, results, nil)
+}
+
+// writeSARIF writes results as WriteSARIF does, with the statistics of the
+// run, if any, in the properties of the run.
+func writeSARIF(out io.Writer, results []Result, stats *Statistics) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: toolName, Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+	ruleIndexes := make(map[string]int)
+	for _, result := range results {
+		index, ok := ruleIndexes[result.CheckID]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			ruleIndexes[result.CheckID] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:                   result.CheckID,
+				ShortDescription:     sarifMessage{result.Description},
+				HelpURI:              result.URL,
+				DefaultConfiguration: sarifConfiguration{sarifLevels[result.Severity]},
+			})
+		}
+
+		var location sarifLocation
+		location.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(result.File)
+		location.PhysicalLocation.Region.StartLine = max(result.Line, 1)
+		location.PhysicalLocation.Region.StartColumn = max(result.Column, 1)
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    result.CheckID,
+			RuleIndex: index,
+			Level:     sarifLevels[result.Severity],
+			Message:   sarifMessage{result.Message},
+			Locations: []sarifLocation{location},
+		})
+	}
+
+	if stats != nil {
+		run.Properties = &sarifRunStats{stats}
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,71 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// The categories of checks, which weigh their findings in scores.
+const (
+	CategorySecurity        = "security"
+	CategoryCorrectness     = "correctness"
+	CategoryReliability     = "reliability"
+	CategoryEfficiency      = "efficiency"
+	CategoryMaintainability = "maintainability"
+)
+
+// Categories lists the categories of checks, the most weighed first.
+var Categories = []string{CategorySecurity, CategoryCorrectness, CategoryReliability, CategoryEfficiency, CategoryMaintainability}
+
+// NormalizeCategory validates a configured category, which may be empty.
+func NormalizeCategory(category string) (string, error) {
+	category = strings.ToLower(category)
+	if category != "" && !slices.Contains(Categories, category) {
+		return "", fmt.Errorf("unknown category %q, expected one of %s", category, strings.Join(Categories, ", "))
+	}
+	return category, nil
+}
+
+// The penalties of findings by severity, multiplied by the weight of their
+// category. Findings without a category, such as those of plugins, weigh 1.
+var (
+	severityPenalties = map[string]float64{SeverityError: 10, SeverityWarning: 4, SeverityNotice: 1}
+	categoryWeights   = map[string]float64{
+		CategorySecurity:        2,
+		CategoryCorrectness:     1.5,
+		CategoryReliability:     1,
+		CategoryEfficiency:      0.5,
+		CategoryMaintainability: 0.5,
+	}
+)
+
+// Score rates the results of a file from 100, without findings, down
+// towards 0. Each finding adds the penalty of its severity times the weight
+// of its category, and the score is 100 * 100 / (100 + penalty): 50 for a
+// penalty of 100, so that it keeps moving however many findings there are.
+func Score(results []Result) int {
+	penalty := 0.0
+	for _, result := range results {
+		weight, ok := categoryWeights[result.Category]
+		if !ok {
+			weight = 1
+		}
+		penalty += severityPenalties[result.Severity] * weight
+	}
+	return int(math.Round(100 * 100 / (100 + penalty)))
+}
+
+// Scores returns the score of each of files, by file.
+func Scores(files []string, results []Result) map[string]int {
+	byFile := make(map[string][]Result)
+	for _, result := range results {
+		byFile[result.File] = append(byFile[result.File], result)
+	}
+	scores := make(map[string]int, len(files))
+	for _, file := range orderedFiles(files, results) {
+		scores[file] = Score(byFile[file])
+	}
+	return scores
+}
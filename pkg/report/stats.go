@@ -0,0 +1,117 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Stats describes a run, for the statistics appended to its report.
+type Stats struct {
+	// Files is the number of files checked.
+	Files int
+	// Jobs is the number of jobs of the workflows checked.
+	Jobs int
+	// Elapsed is how long the run took until the report was written.
+	Elapsed time.Duration
+}
+
+// Statistics are the statistics of a run with its findings counted, as
+// written in the structured formats.
+type Statistics struct {
+	Files      int            `json:"files"`
+	Jobs       int            `json:"jobs"`
+	Findings   int            `json:"findings"`
+	Severities map[string]int `json:"severities"`
+	Checks     map[string]int `json:"checks"`
+	ElapsedMS  int64          `json:"elapsed_ms"`
+}
+
+// NewStatistics counts the findings of results by severity and check for
+// stats, or returns nil without stats.
+func NewStatistics(stats *Stats, results []Result) *Statistics {
+	if stats == nil {
+		return nil
+	}
+	s := &Statistics{
+		Files:      stats.Files,
+		Jobs:       stats.Jobs,
+		Findings:   len(results),
+		Severities: map[string]int{SeverityError: 0, SeverityWarning: 0, SeverityNotice: 0},
+		Checks:     make(map[string]int),
+		ElapsedMS:  stats.Elapsed.Milliseconds(),
+	}
+	for _, result := range results {
+		s.Severities[result.Severity]++
+		s.Checks[result.CheckID]++
+	}
+	return s
+}
+
+// WriteStats writes the statistics of a run, counting the findings of
+// results, as appended to the table format.
+func WriteStats(out io.Writer, stats *Stats, results []Result) {
+	writeStats(out, stats, results, false)
+}
+
+// writeStats writes the statistics of a run after the table of its
+// findings. After a summary, which counts the findings already, only the
+// files, jobs and time are written.
+func writeStats(out io.Writer, stats *Stats, results []Result, summary bool) {
+	lines := statsLines(NewStatistics(stats, results))
+	if lines == nil {
+		return
+	}
+	if summary {
+		lines = lines[:1]
+	}
+	fmt.Fprintf(out, "\n%s\n", strings.Join(lines, "\n"))
+}
+
+// statsLines returns the lines of the statistics of a run, the files, jobs
+// and time, the findings by severity and by check, or nil without stats.
+func statsLines(s *Statistics) []string {
+	if s == nil {
+		return nil
+	}
+	lines := []string{
+		fmt.Sprintf("Checked %s and %s in %s", plural(s.Files, "file"), plural(s.Jobs, "job"), time.Duration(s.ElapsedMS)*time.Millisecond),
+		fmt.Sprintf("%s: %s, %s, %s", plural(s.Findings, "finding"), plural(s.Severities[SeverityError], "error"),
+			plural(s.Severities[SeverityWarning], "warning"), plural(s.Severities[SeverityNotice], "notice")),
+	}
+	if len(s.Checks) == 0 {
+		return lines
+	}
+	ids := s.checksByCount()
+	counts := make([]string, len(ids))
+	for i, id := range ids {
+		counts[i] = fmt.Sprintf("%s %d", id, s.Checks[id])
+	}
+	return append(lines, "By check: "+strings.Join(counts, ", "))
+}
+
+// checksByCount returns the ids of the checks with findings, the checks
+// having the most findings first.
+func (s *Statistics) checksByCount() []string {
+	ids := make([]string, 0, len(s.Checks))
+	for id := range s.Checks {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if s.Checks[ids[i]] != s.Checks[ids[j]] {
+			return s.Checks[ids[i]] > s.Checks[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// plural returns n with noun, adding an s unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// WriteSummary writes the number of findings of each check and severity as
+// a table, the most frequent first, followed by the totals by severity and
+// the score of each of files.
+func WriteSummary(out io.Writer, files []string, results []Result) {
+	if len(results) == 0 {
+		fmt.Fprintln(out, "No issues found!")
+		writeScores(out, files, results)
+		return
+	}
+
+	type row struct{ checkID, severity string }
+	counts := make(map[row]int)
+	totals := make(map[string]int)
+	for _, result := range results {
+		counts[row{result.CheckID, result.Severity}]++
+		totals[result.Severity]++
+	}
+	rows := make([]row, 0, len(counts))
+	for r := range counts {
+		rows = append(rows, r)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if counts[rows[i]] != counts[rows[j]] {
+			return counts[rows[i]] > counts[rows[j]]
+		}
+		if rows[i].checkID != rows[j].checkID {
+			return rows[i].checkID < rows[j].checkID
+		}
+		return SeverityRank(rows[i].severity) > SeverityRank(rows[j].severity)
+	})
+
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"Check", "Severity", "Findings"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetCenterSeparator("|")
+	table.SetColumnAlignment([]int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT})
+	for _, r := range rows {
+		table.Append([]string{r.checkID, r.severity, strconv.Itoa(counts[r])})
+	}
+	table.Render()
+	fmt.Fprintf(out, "%d findings: %d errors, %d warnings, %d notices\n",
+		len(results), totals[SeverityError], totals[SeverityWarning], totals[SeverityNotice])
+	writeScores(out, files, results)
+}
+
+// writeScores writes a table of the scores of files, the lowest first.
+func writeScores(out io.Writer, files []string, results []Result) {
+	scores := Scores(files, results)
+	ordered := orderedFiles(files, results)
+	sort.SliceStable(ordered, func(i, j int) bool { return scores[ordered[i]] < scores[ordered[j]] })
+
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"File", "Score"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetCenterSeparator("|")
+	table.SetColumnAlignment([]int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT})
+	for _, file := range ordered {
+		table.Append([]string{file, strconv.Itoa(scores[file])})
+	}
+	table.Render()
+}
@@ -0,0 +1,99 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// tapFinding is a finding in the YAML diagnostics of a TAP test point.
+type tapFinding struct {
+	Line    int    `yaml:"line"`
+	Column  int    `yaml:"column"`
+	Step    string `yaml:"step,omitempty"`
+	Message string `yaml:"message"`
+}
+
+// tapDiagnostics is the YAML block following a failed TAP test point.
+type tapDiagnostics struct {
+	Severity string       `yaml:"severity"`
+	Detail   string       `yaml:"detail,omitempty"`
+	URL      string       `yaml:"url,omitempty"`
+	Findings []tapFinding `yaml:"findings"`
+}
+
+// WriteTAP writes results as TAP version 13, for harnesses such as prove
+// and bats. Each check failing for a job of a file is a failed test point,
+// followed by the findings in a YAML diagnostics block, and a file without
+// findings is a single passed test point.
+func WriteTAP(out io.Writer, files []string, results []Result) error {
+	return writeTAP(out, files, results, nil)
+}
+
+// writeTAP writes results as WriteTAP does, ending with the statistics of
+// the run as comments if stats are given.
+func writeTAP(out io.Writer, files []string, results []Result, stats *Statistics) error {
+	type point struct{ file, job, checkID string }
+	var points []point
+	byPoint := make(map[point][]Result)
+	for _, result := range results {
+		p := point{result.File, result.JobName, result.CheckID}
+		if byPoint[p] == nil {
+			points = append(points, p)
+		}
+		byPoint[p] = append(byPoint[p], result)
+	}
+	found := make(map[string]bool)
+	for _, result := range results {
+		found[result.File] = true
+	}
+	var clean []string
+	for _, file := range orderedFiles(files, results) {
+		if !found[file] {
+			clean = append(clean, file)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "TAP version 13\n1..%d\n", len(points)+len(clean))
+	n := 0
+	for _, p := range points {
+		n++
+		fmt.Fprintf(&b, "not ok %d - %s: %s: %s\n", n, tapEscape(p.file), tapEscape(p.job), p.checkID)
+		group := byPoint[p]
+		diagnostics := tapDiagnostics{Severity: group[0].Severity, Detail: group[0].Description, URL: group[0].URL}
+		for _, result := range group {
+			diagnostics.Findings = append(diagnostics.Findings, tapFinding{result.Line, result.Column, result.Step, result.Message})
+		}
+		var data strings.Builder
+		encoder := yaml.NewEncoder(&data)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(diagnostics); err != nil {
+			return err
+		}
+		b.WriteString("  ---\n")
+		for _, line := range strings.SplitAfter(strings.TrimSuffix(data.String(), "\n"), "\n") {
+			b.WriteString("  " + line)
+		}
+		b.WriteString("\n  ...\n")
+	}
+	for _, file := range clean {
+		n++
+		fmt.Fprintf(&b, "ok %d - %s\n", n, tapEscape(file))
+	}
+	for _, line := range statsLines(stats) {
+		fmt.Fprintf(&b, "# %s\n", line)
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// tapEscape escapes the characters of a test point description that TAP
+// reads as a directive or line break.
+func tapEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "#", `\#`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
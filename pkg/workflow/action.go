@@ -0,0 +1,95 @@
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ActionFileNames are the metadata file names of an action, in the order
+// GitHub looks them up.
+var ActionFileNames = []string{"action.yml", "action.yaml"}
+
+// Action holds the parts of an action's metadata file used by the checks.
+type Action struct {
+	Name        string                  `yaml:"name"`
+	Description string                  `yaml:"description"`
+	Inputs      map[string]ActionInput  `yaml:"inputs"`
+	Outputs     map[string]ActionOutput `yaml:"outputs"`
+	Branding    map[string]string       `yaml:"branding"`
+	Runs        ActionRuns              `yaml:"runs"`
+
+	// Node is the root mapping of the file, and Document its document node.
+	Node     *yaml.Node `yaml:"-"`
+	Document *yaml.Node `yaml:"-"`
+}
+
+type ActionInput struct {
+	Description string `yaml:"description"`
+}
+
+type ActionOutput struct {
+	// Value is the expression setting the output of a composite action.
+	Value string `yaml:"value"`
+}
+
+type ActionRuns struct {
+	Using string `yaml:"using"`
+	// Steps are the steps of a composite action.
+	Steps []Step `yaml:"steps"`
+}
+
+// IsComposite reports whether the action is a composite action.
+func (a *Action) IsComposite() bool {
+	return a.Runs.Using == "composite"
+}
+
+// ParseAction decodes an action metadata file.
+func ParseAction(data []byte) (*Action, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("empty document")
+	}
+
+	var action Action
+	root := doc.Content[0]
+	if err := resolveAliases(root); err != nil {
+		return nil, err
+	}
+	if err := root.Decode(&action); err != nil {
+		return nil, err
+	}
+	action.Node, action.Document = root, &doc
+	return &action, nil
+}
+
+// IsActionFile reports whether path is the metadata file of an action
+// rather than a workflow.
+func IsActionFile(path string) bool {
+	dir := filepath.Dir(path)
+	if filepath.Base(dir) == "workflows" && filepath.Base(filepath.Dir(dir)) == ".github" {
+		return false
+	}
+	return slices.Contains(ActionFileNames, filepath.Base(path))
+}
+
+// ReadAction reads the metadata of the action in dir.
+func ReadAction(dir string) (*Action, error) {
+	for _, name := range ActionFileNames {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return ParseAction(data)
+	}
+	return nil, fmt.Errorf("no action.yml in %s", dir)
+}
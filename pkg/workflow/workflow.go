@@ -0,0 +1,490 @@
+// Package workflow parses GitHub Actions workflow files, keeping the YAML
+// nodes of workflows, jobs and steps so findings can point back into the
+// document.
+package workflow
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workflow is a parsed workflow file.
+type Workflow struct {
+	Name        string         `yaml:"name"`
+	On          Triggers       `yaml:"on"`
+	Jobs        map[string]Job `yaml:"jobs"`
+	Defaults    *Defaults      `yaml:"defaults"`
+	Concurrency interface{}    `yaml:"concurrency"`
+	Permissions *Permissions   `yaml:"permissions"`
+
+	// File is the path of the workflow file, if it was read from one.
+	File string `yaml:"-"`
+	// Node is the root mapping of the workflow, and Document the document
+	// node holding it along with the comments of the file.
+	Node     *yaml.Node `yaml:"-"`
+	Document *yaml.Node `yaml:"-"`
+	// Duplicates lists the keys that were defined more than once.
+	Duplicates []DuplicateKey `yaml:"-"`
+}
+
+// DuplicateKey is a repeated key of a mapping, which is dropped from the
+// document in favor of the first definition.
+type DuplicateKey struct {
+	Key, First *yaml.Node
+	// Parent is the mapping node containing the key.
+	Parent *yaml.Node
+}
+
+// Triggers holds the events of the "on" key, which can be a single event, a
+// list of events or a mapping of events to their configuration.
+type Triggers struct {
+	// Events maps each event name to its configuration node, which is nil
+	// when the event has no configuration.
+	Events map[string]*yaml.Node
+
+	node *yaml.Node
+}
+
+func (t *Triggers) UnmarshalYAML(node *yaml.Node) error {
+	t.Events = make(map[string]*yaml.Node)
+	t.node = node
+	switch node.Kind {
+	case yaml.ScalarNode, yaml.SequenceNode:
+		for _, event := range ScalarNodes(node) {
+			t.Events[event.Value] = nil
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			t.Events[node.Content[i].Value] = node.Content[i+1]
+		}
+	default:
+		return fmt.Errorf("line %d: invalid on: value", node.Line)
+	}
+	return nil
+}
+
+// Has reports whether the workflow is triggered by event.
+func (t Triggers) Has(event string) bool {
+	_, ok := t.Events[event]
+	return ok
+}
+
+// Permissions holds a permissions: value, which is either read-all or
+// write-all, or a mapping of scopes to access levels.
+type Permissions struct {
+	// All is read-all or write-all for the scalar form.
+	All    string
+	Scopes map[string]string
+
+	node *yaml.Node
+}
+
+func (p *Permissions) UnmarshalYAML(node *yaml.Node) error {
+	p.node = node
+	if node.Kind == yaml.ScalarNode {
+		p.All = node.Value
+		return nil
+	}
+	return node.Decode(&p.Scopes)
+}
+
+// WriteAll returns the nodes granting write-all, either as the whole value
+// or for a scope.
+func (p *Permissions) WriteAll() []*yaml.Node {
+	if p.All == "write-all" {
+		return []*yaml.Node{p.node}
+	}
+
+	var nodes []*yaml.Node
+	for i := 0; i+1 < len(p.node.Content); i += 2 {
+		if p.node.Content[i+1].Value == "write-all" {
+			nodes = append(nodes, p.node.Content[i+1])
+		}
+	}
+	return nodes
+}
+
+type Defaults struct {
+	Run *RunDefaults `yaml:"run"`
+}
+
+type RunDefaults struct {
+	Shell string `yaml:"shell"`
+}
+
+// Job is a job of a workflow.
+type Job struct {
+	TimeoutMinutes *int                 `yaml:"timeout-minutes"`
+	Permissions    *Permissions         `yaml:"permissions"`
+	Steps          []Step               `yaml:"steps"`
+	RunsOn         interface{}          `yaml:"runs-on"`
+	Container      *Container           `yaml:"container"`
+	Services       map[string]Container `yaml:"services"`
+	Strategy       *Strategy            `yaml:"strategy"`
+	Environment    interface{}          `yaml:"environment"`
+	Needs          Needs                `yaml:"needs"`
+	// Uses references the reusable workflow the job calls.
+	Uses string `yaml:"uses"`
+
+	// Key and Node are the key of the job and its mapping.
+	Key  *yaml.Node `yaml:"-"`
+	Node *yaml.Node `yaml:"-"`
+}
+
+// Needs holds the needs: value of a job, which is either a job name or a
+// list of them.
+type Needs struct {
+	// Jobs lists the names of the jobs, and Nodes their nodes.
+	Jobs  []string
+	Nodes []*yaml.Node
+}
+
+func (n *Needs) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode && node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: invalid needs: value", node.Line)
+	}
+	for _, job := range ScalarNodes(node) {
+		n.Jobs = append(n.Jobs, job.Value)
+		n.Nodes = append(n.Nodes, job)
+	}
+	return nil
+}
+
+// Container holds a container: or services: entry, which is either an image
+// name or a mapping with an image.
+type Container struct {
+	Image string `yaml:"image"`
+
+	// ImageNode is the node of the image name.
+	ImageNode *yaml.Node `yaml:"-"`
+}
+
+func (c *Container) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		c.Image, c.ImageNode = node.Value, node
+		return nil
+	}
+	type plain Container
+	if err := node.Decode((*plain)(c)); err != nil {
+		return err
+	}
+	_, c.ImageNode = LookupKey(node, "image")
+	return nil
+}
+
+type Strategy struct {
+	Matrix      *Matrix     `yaml:"matrix"`
+	FailFast    interface{} `yaml:"fail-fast"`
+	MaxParallel interface{} `yaml:"max-parallel"`
+}
+
+// Matrix holds a strategy matrix. Computed is set when the matrix or any of
+// its dimensions comes from an expression, so its values are only known at
+// run time.
+type Matrix struct {
+	// Dimensions maps each matrix key to its values, and Keys lists the keys
+	// in document order.
+	Dimensions map[string][]interface{}
+	Keys       []string
+	Include    []map[string]interface{}
+	Exclude    []map[string]interface{}
+	Computed   bool
+}
+
+func (m *Matrix) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		m.Computed = true
+		return nil
+	}
+
+	m.Dimensions = make(map[string][]interface{})
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		if value.Kind != yaml.SequenceNode {
+			m.Computed = true
+			continue
+		}
+
+		var err error
+		switch key {
+		case "include":
+			err = value.Decode(&m.Include)
+		case "exclude":
+			err = value.Decode(&m.Exclude)
+		default:
+			var values []interface{}
+			err = value.Decode(&values)
+			m.Dimensions[key] = values
+			m.Keys = append(m.Keys, key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Size returns the approximate number of jobs the matrix expands to: the
+// combinations of its dimensions less the excluded ones, or the number of
+// included combinations if it has no dimensions. ok is false for computed
+// matrices.
+func (m *Matrix) Size() (size int, ok bool) {
+	if m.Computed {
+		return 0, false
+	}
+	if len(m.Keys) == 0 {
+		return len(m.Include), true
+	}
+
+	size = 1
+	for _, key := range m.Keys {
+		size *= len(m.Dimensions[key])
+	}
+	if size -= len(m.Exclude); size < 0 {
+		size = 0
+	}
+	return size, true
+}
+
+// Step is a step of a job.
+type Step struct {
+	ID             string                 `yaml:"id"`
+	Name           string                 `yaml:"name"`
+	If             string                 `yaml:"if"`
+	Uses           string                 `yaml:"uses"`
+	With           map[string]interface{} `yaml:"with"`
+	TimeoutMinutes interface{}            `yaml:"timeout-minutes"`
+
+	// Node is the mapping of the step.
+	Node *yaml.Node `yaml:"-"`
+}
+
+// Input returns the key and value nodes of the named with: input.
+func (s Step) Input(name string) (*yaml.Node, *yaml.Node) {
+	_, with := LookupKey(s.Node, "with")
+	return LookupKey(with, name)
+}
+
+func (s *Step) UnmarshalYAML(node *yaml.Node) error {
+	type plain Step
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	s.Node = node
+	return nil
+}
+
+// Parse decodes a workflow file.
+func Parse(data []byte) (*Workflow, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("empty document")
+	}
+
+	var workflow Workflow
+	root := doc.Content[0]
+	if err := resolveAliases(root); err != nil {
+		return nil, err
+	}
+	workflow.Duplicates = removeDuplicateKeys(root)
+	if err := root.Decode(&workflow); err != nil {
+		return nil, err
+	}
+	workflow.Node, workflow.Document = root, &doc
+
+	_, jobs := LookupKey(root, "jobs")
+	for _, name := range workflow.JobNames() {
+		job := workflow.Jobs[name]
+		job.Key, job.Node = LookupKey(jobs, name)
+		workflow.Jobs[name] = job
+	}
+
+	return &workflow, nil
+}
+
+// maxExpandedNodes is the most nodes a document may have once its aliases
+// are resolved. Each alias shares the nodes of its anchor, so nested aliases
+// could otherwise expand a small file into a tree too large to walk.
+const maxExpandedNodes = 100000
+
+// resolveAliases replaces the aliases in node with the nodes they refer to
+// and expands merge keys (<<), so the checks see the effective keys of each
+// mapping. The resolved nodes are shared with their anchors, so findings
+// about them point at the anchored definition. It fails if the aliases
+// expand the document to more than maxExpandedNodes nodes.
+func resolveAliases(node *yaml.Node) error {
+	resolveAliasesIn(node, make(map[*yaml.Node]bool), make(map[*yaml.Node]bool))
+	if expandedNodes(node, make(map[*yaml.Node]int)) > maxExpandedNodes {
+		return fmt.Errorf("aliases expand the document to more than %d nodes", maxExpandedNodes)
+	}
+	return nil
+}
+
+// expandedNodes returns the number of nodes under node, counting shared
+// nodes each time they are reached, and stops counting past
+// maxExpandedNodes.
+func expandedNodes(node *yaml.Node, counts map[*yaml.Node]int) int {
+	if count, ok := counts[node]; ok {
+		return count
+	}
+	count := 1
+	for _, child := range node.Content {
+		if count += expandedNodes(child, counts); count > maxExpandedNodes {
+			break
+		}
+	}
+	counts[node] = count
+	return count
+}
+
+// resolveAliasesIn resolves the aliases under node. Aliases of an enclosing
+// node (active) are left in place, as resolving them would make the
+// document infinite; decoding reports them.
+func resolveAliasesIn(node *yaml.Node, resolved, active map[*yaml.Node]bool) {
+	if resolved[node] {
+		return
+	}
+	resolved[node], active[node] = true, true
+	defer delete(active, node)
+	for i, child := range node.Content {
+		target := child
+		for target.Kind == yaml.AliasNode && target.Alias != nil {
+			target = target.Alias
+		}
+		if active[target] {
+			continue
+		}
+		node.Content[i] = target
+		resolveAliasesIn(target, resolved, active)
+	}
+	if node.Kind == yaml.MappingNode {
+		mergeKeys(node)
+	}
+}
+
+// mergeKeys replaces the merge keys of a mapping with the keys of the merged
+// mappings it doesn't define itself, earlier mappings of a merged list
+// taking precedence. The merged keys are added after the mapping's own keys,
+// so its first key is still on the line after its parent key. Invalid merges
+// are kept for decoding to report.
+func mergeKeys(node *yaml.Node) {
+	defined := make(map[string]bool)
+	var merges []*yaml.Node
+	content := make([]*yaml.Node, 0, len(node.Content))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Kind == yaml.ScalarNode && key.ShortTag() == "!!merge" && isMergeable(value) {
+			merges = append(merges, value)
+			continue
+		}
+		defined[key.Value] = true
+		content = append(content, key, value)
+	}
+
+	for _, merge := range merges {
+		sources := []*yaml.Node{merge}
+		if merge.Kind == yaml.SequenceNode {
+			sources = merge.Content
+		}
+		for _, source := range sources {
+			for i := 0; i+1 < len(source.Content); i += 2 {
+				if key := source.Content[i]; !defined[key.Value] {
+					defined[key.Value] = true
+					content = append(content, key, source.Content[i+1])
+				}
+			}
+		}
+	}
+	node.Content = content
+}
+
+// isMergeable reports whether node is a valid merge key value: a mapping or
+// a sequence of mappings.
+func isMergeable(node *yaml.Node) bool {
+	if node.Kind == yaml.SequenceNode {
+		for _, item := range node.Content {
+			if item.Kind != yaml.MappingNode {
+				return false
+			}
+		}
+		return true
+	}
+	return node.Kind == yaml.MappingNode
+}
+
+// removeDuplicateKeys removes repeated keys from the mappings in node, which
+// would otherwise fail decoding, and returns them.
+func removeDuplicateKeys(node *yaml.Node) []DuplicateKey {
+	var duplicates []DuplicateKey
+	if node.Kind == yaml.MappingNode {
+		seen := make(map[string]*yaml.Node)
+		content := node.Content[:0]
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if first, ok := seen[key.Value]; ok && key.Kind == yaml.ScalarNode {
+				duplicates = append(duplicates, DuplicateKey{Key: key, First: first, Parent: node})
+				continue
+			}
+			seen[key.Value] = key
+			content = append(content, key, value)
+		}
+		node.Content = content
+	}
+	for _, child := range node.Content {
+		duplicates = append(duplicates, removeDuplicateKeys(child)...)
+	}
+	return duplicates
+}
+
+// JobNames returns the job names in the order they appear in the document.
+func (w *Workflow) JobNames() []string {
+	_, jobs := LookupKey(w.Node, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var names []string
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		names = append(names, jobs.Content[i].Value)
+	}
+	return names
+}
+
+// LookupKey returns the key and value nodes for key in a mapping node, or
+// nils if node is not a mapping or doesn't contain key.
+func LookupKey(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// ScalarNodes returns node itself if it is a scalar, or the scalar items of
+// node if it is a sequence.
+func ScalarNodes(node *yaml.Node) []*yaml.Node {
+	if node == nil {
+		return nil
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return []*yaml.Node{node}
+	case yaml.SequenceNode:
+		var scalars []*yaml.Node
+		for _, item := range node.Content {
+			if item.Kind == yaml.ScalarNode {
+				scalars = append(scalars, item)
+			}
+		}
+		return scalars
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseResolvesAliases(t *testing.T) {
+	w, err := Parse([]byte(`on: push
+x-defaults: &defaults
+  runs-on: ubuntu-22.04
+  timeout-minutes: 10
+jobs:
+  build:
+    <<: *defaults
+    timeout-minutes: 20
+    steps:
+      - &checkout
+        uses: actions/checkout@v4
+  test:
+    <<: *defaults
+    steps:
+      - *checkout
+      - run: make test
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	build, test := w.Jobs["build"], w.Jobs["test"]
+	if build.RunsOn != "ubuntu-22.04" || test.RunsOn != "ubuntu-22.04" {
+		t.Errorf("runs-on = %v and %v, want ubuntu-22.04 from the anchor", build.RunsOn, test.RunsOn)
+	}
+	if build.TimeoutMinutes == nil || *build.TimeoutMinutes != 20 {
+		t.Errorf("build timeout-minutes = %v, want 20 from the job's own key", build.TimeoutMinutes)
+	}
+	if test.TimeoutMinutes == nil || *test.TimeoutMinutes != 10 {
+		t.Errorf("test timeout-minutes = %v, want 10 from the anchor", test.TimeoutMinutes)
+	}
+	if len(test.Steps) != 2 || test.Steps[0].Uses != "actions/checkout@v4" {
+		t.Fatalf("test steps = %+v, want the aliased checkout and a run step", test.Steps)
+	}
+	if build.Steps[0].Node != test.Steps[0].Node {
+		t.Error("the aliased step has its own node, want the anchored one")
+	}
+	if line := test.Steps[0].Node.Line; line != 10 {
+		t.Errorf("aliased step at line %d, want 10, the line of the anchored step", line)
+	}
+}
+
+func TestParseLimitsAliasExpansion(t *testing.T) {
+	tests := []struct {
+		name   string
+		levels int
+		err    bool
+	}{
+		{name: "few levels", levels: 3},
+		{name: "limit exceeded", levels: 5, err: true},
+		{name: "far past the limit", levels: 30, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Each level is a list of ten aliases of the previous one, so the
+			// document expands to more than 10^levels nodes.
+			var b strings.Builder
+			b.WriteString("on: push\nx-0: &l0 [a, a, a, a, a, a, a, a, a, a]\n")
+			for i := 1; i <= tt.levels; i++ {
+				aliases := strings.TrimSuffix(strings.Repeat(fmt.Sprintf("*l%d, ", i-1), 10), ", ")
+				fmt.Fprintf(&b, "x-%d: &l%d [%s]\n", i, i, aliases)
+			}
+			b.WriteString("jobs:\n  build:\n    runs-on: ubuntu-22.04\n    steps:\n      - run: make\n")
+
+			_, err := Parse([]byte(b.String()))
+			switch {
+			case tt.err && (err == nil || !strings.Contains(err.Error(), "aliases expand")):
+				t.Errorf("Parse = %v, want the alias expansion error", err)
+			case !tt.err && err != nil:
+				t.Errorf("Parse = %v, want no error", err)
+			}
+		})
+	}
+}
+
+func TestParseLeavesRecursiveAliases(t *testing.T) {
+	// An alias of an enclosing node would make the document infinite if it
+	// were resolved, and is left for decoding to reject.
+	if _, err := Parse([]byte("on: push\njobs: &jobs\n  build:\n    steps: *jobs\n")); err == nil {
+		t.Error("Parse succeeded, want an error for the recursive alias")
+	}
+}
+
+func TestParseDuplicateKeys(t *testing.T) {
+	w, err := Parse([]byte(`on: push
+jobs:
+  build:
+    runs-on: ubuntu-22.04
+    runs-on: ubuntu-24.04
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Duplicates) != 1 {
+		t.Fatalf("got %d duplicate keys, want 1", len(w.Duplicates))
+	}
+	duplicate := w.Duplicates[0]
+	if duplicate.Key.Value != "runs-on" || duplicate.Key.Line != 5 || duplicate.First.Line != 4 {
+		t.Errorf("duplicate %s at line %d, first at %d, want runs-on at 5, first at 4", duplicate.Key.Value, duplicate.Key.Line, duplicate.First.Line)
+	}
+	if w.Jobs["build"].RunsOn != "ubuntu-22.04" {
+		t.Errorf("runs-on = %v, want the first value", w.Jobs["build"].RunsOn)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// parallel calls f with each index from 0 to n-1 on a pool of at most
+// workers goroutines, or one per CPU when workers isn't positive, and
+// returns the values in the order of the indexes. All the calls are made
+// even if some fail, and the error of the lowest failing index is returned,
+// so the outcome doesn't depend on scheduling.
+func parallel[T any](n, workers int, f func(i int) (T, error)) ([]T, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	values := make([]T, n)
+	errs := make([]error, n)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				values[i], errs[i] = f(i)
+			}
+		}()
+	}
+	for i := range n {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
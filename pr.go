@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"ghactionscheck/pkg/checks"
+	"ghactionscheck/pkg/report"
+)
+
+// pullRequestPattern matches a pull request given as owner/repo#number.
+var pullRequestPattern = regexp.MustCompile(`^([\w.-]+/[\w.-]+)#(\d+)$`)
+
+// commentMarkerPattern matches the hidden marker identifying the finding of
+// a review comment posted by postReview.
+var commentMarkerPattern = regexp.MustCompile(`<!-- ghactionscheck:([0-9a-f]+) -->`)
+
+// postReview posts the results on the lines that pull request pr
+// (owner/repo#number) adds as review comments, for a checkout of its head.
+// Each comment carries a marker of its finding, so on later runs the
+// comments of findings posted before are updated rather than posted again.
+func postReview(pr string, results []report.Result) error {
+	match := pullRequestPattern.FindStringSubmatch(pr)
+	if match == nil {
+		return fmt.Errorf("invalid pull request %q, expected owner/repo#number", pr)
+	}
+	repo := match[1]
+	number, _ := strconv.Atoi(match[2])
+	if cli.GitHubToken == "" {
+		return fmt.Errorf("posting review comments needs a token with --github-token or $GITHUB_TOKEN")
+	}
+
+	github := githubClient()
+	commit, err := github.PullRequestHead(repo, number)
+	if err != nil {
+		return err
+	}
+	files, err := github.PullRequestFiles(repo, number)
+	if err != nil {
+		return fmt.Errorf("listing changed files: %v", err)
+	}
+	added := make(map[string]map[int]bool)
+	for _, file := range files {
+		added[file.Filename] = addedLines(file.Patch)
+	}
+	existing, err := github.ReviewComments(repo, number)
+	if err != nil {
+		return fmt.Errorf("listing review comments: %v", err)
+	}
+	posted := make(map[string]checks.ReviewComment)
+	for _, comment := range existing {
+		if match := commentMarkerPattern.FindStringSubmatch(comment.Body); match != nil {
+			posted[match[1]] = comment
+		}
+	}
+
+	var comments []checks.ReviewComment
+	updated := 0
+	occurrences := make(map[string]int)
+	for _, result := range results {
+		path := repositoryPath(result.File)
+		if !added[path][result.Line] {
+			continue
+		}
+		key := commentKey(path, result, occurrences)
+		body := commentBody(result, key)
+		if comment, ok := posted[key]; ok {
+			if comment.Body != body {
+				if err := github.UpdateReviewComment(repo, comment.ID, body); err != nil {
+					return fmt.Errorf("updating review comment: %v", err)
+				}
+				updated++
+			}
+			continue
+		}
+		comments = append(comments, checks.ReviewComment{Path: path, Line: result.Line, Side: "RIGHT", Body: body})
+	}
+	if len(comments) > 0 {
+		if err := github.CreateReview(repo, number, commit, comments); err != nil {
+			return fmt.Errorf("creating review: %v", err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Posted %d and updated %d review comments on %s\n", len(comments), updated, pr)
+	return nil
+}
+
+// addedLines returns the lines of the new file that a unified diff patch
+// adds.
+func addedLines(patch string) map[int]bool {
+	lines := make(map[int]bool)
+	line := 0
+	scanner := bufio.NewScanner(strings.NewReader(patch))
+	for scanner.Scan() {
+		text := scanner.Text()
+		if match := hunkHeaderPattern.FindStringSubmatch(text); match != nil {
+			line, _ = strconv.Atoi(match[1])
+			continue
+		}
+		switch {
+		case strings.HasPrefix(text, "+"):
+			lines[line] = true
+			line++
+		case strings.HasPrefix(text, " "):
+			line++
+		}
+	}
+	return lines
+}
+
+// repositoryPath returns the path of file in its repository, as the API
+// names it.
+func repositoryPath(file string) string {
+	rel, err := filepath.Rel(checks.FindRepoRoot(file), absPath(file))
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// commentKey identifies the finding of result in the file at path by its
+// check, job and untranslated message, ignoring its position like a
+// baseline, so the comment is found again when lines are added above it or
+// the language of messages changes. occurrences counts
+// the findings seen with each identity, to tell repeated ones apart.
+func commentKey(path string, result report.Result, occurrences map[string]int) string {
+	identity := strings.Join([]string{path, result.CheckID, result.JobName, result.UntranslatedMessage()}, "\x00")
+	occurrences[identity]++
+	sum := sha256.Sum256([]byte(identity + "\x00" + strconv.Itoa(occurrences[identity])))
+	return hex.EncodeToString(sum[:8])
+}
+
+// commentBody returns the markdown of the review comment of result, ending
+// with its marker.
+func commentBody(result report.Result, key string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** `%s`: %s\n", result.Severity, result.CheckID, result.Message)
+	if result.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", result.Description)
+	}
+	if result.URL != "" {
+		fmt.Fprintf(&b, "\n[Documentation](%s)\n", result.URL)
+	}
+	fmt.Fprintf(&b, "\n<!-- ghactionscheck:%s -->", key)
+	return b.String()
+}
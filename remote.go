@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"ghactionscheck/pkg/checks"
+	"ghactionscheck/pkg/report"
+)
+
+type remoteCmd struct {
+	Repository string `arg:"" name:"repository" help:"Repository to check, as owner/repo or owner/repo@ref"`
+	checkFlags
+}
+
+// Run fetches the workflow files of the repository with the GitHub contents
+// API and checks them. The checks config is the one for the current
+// directory, as the repository's own config is not trusted: its plugins
+// would run arbitrary commands.
+func (cmd *remoteCmd) Run() error {
+	repo, ref, _ := strings.Cut(cmd.Repository, "@")
+	if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid repository %q, expected owner/repo[@ref]", cmd.Repository)
+	}
+
+	if err := cmd.validateOutput(); err != nil {
+		return err
+	}
+	cmd.startRun()
+
+	checksConfig, err := loadConfig(".")
+	if err != nil {
+		return fmt.Errorf("loading checks config: %v", err)
+	}
+
+	github := githubClient()
+	if ref == "" {
+		ref, err = github.DefaultBranch(repo)
+		if err != nil {
+			return fmt.Errorf("getting default branch: %v", err)
+		}
+	}
+	files, results, err := cmd.checkRepository(checksConfig, github, repo, ref)
+	if err != nil {
+		return err
+	}
+
+	err = cmd.writeReport(func(out io.Writer) error {
+		return report.Write(out, cmd.Format, files, results, cmd.reportOptions(files))
+	})
+	if err != nil {
+		return fmt.Errorf("writing results: %v", err)
+	}
+	cmd.writeStepSummary(files, results)
+	if cmd.shouldFail(files, results) {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// checkRepository fetches the workflow files of repo at ref with github and
+// checks them, returning the names of the files as reported and the
+// results.
+func (flags *checkFlags) checkRepository(config *checks.Config, github *checks.GitHubClient, repo, ref string) ([]string, []report.Result, error) {
+	workflows, err := github.WorkflowFiles(repo, ref)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching workflow files: %w", err)
+	}
+
+	c, err := flags.checker(config, github, checks.WithRemoteRepository(github, repo, ref))
+	if err != nil {
+		return nil, nil, err
+	}
+	files := make([]string, len(workflows))
+	for i, workflow := range workflows {
+		files[i] = repo + "/" + workflow.Path
+	}
+	fileResults, err := parallel(len(workflows), flags.Jobs, func(i int) ([]report.Result, error) {
+		results, err := c.Check(files[i], workflows[i].Content)
+		if err != nil {
+			return nil, fmt.Errorf("checking %s: %v", files[i], err)
+		}
+		return results, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	flags.recordChecks(c)
+	results, err := flags.newFindings(slices.Concat(fileResults...))
+	return files, results, err
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"ghactionscheck/pkg/checks"
+	"github.com/olekukonko/tablewriter"
+)
+
+type rulesCmd struct {
+	Path   string `arg:"" optional:"" name:"path" default:"." help:"Directory whose checks config is listed"`
+	Format string `name:"format" enum:"table,json" default:"table" help:"Output format (table, json)"`
+}
+
+// rule is a check as listed by rules.
+type rule struct {
+	ID          string `json:"id"`
+	Severity    string `json:"severity"`
+	Category    string `json:"category,omitempty"`
+	Enabled     bool   `json:"enabled"`
+	Source      string `json:"source"`
+	Description string `json:"description"`
+	URL         string `json:"url,omitempty"`
+}
+
+// Run lists the built-in checks, with their state in the checks config for
+// the path, followed by the custom checks and plugins the config adds. A
+// built-in check missing from a config file doesn't run, so it is listed as
+// disabled. With --format json, the rules are written as a JSON object along
+// with the version and rule set, for tools generating documentation or
+// dashboards from them.
+func (cmd *rulesCmd) Run() error {
+	config, err := loadConfig(cmd.Path)
+	if err != nil {
+		return fmt.Errorf("loading checks config: %v", err)
+	}
+	rules, err := listRules(config)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(struct {
+			Version string `json:"version"`
+			RuleSet string `json:"rule_set"`
+			Rules   []rule `json:"rules"`
+		}{version, checks.RuleSetVersion(), rules})
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "Severity", "Category", "Enabled", "Source", "Description"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetCenterSeparator("|")
+	table.SetAutoWrapText(false)
+	for _, r := range rules {
+		enabled := "no"
+		if r.Enabled {
+			enabled = "yes"
+		}
+		table.Append([]string{r.ID, r.Severity, r.Category, enabled, r.Source, r.Description})
+	}
+	table.Render()
+	return nil
+}
+
+// listRules returns the checks of config: the built-in checks in their
+// default order, then the other checks and the plugins of config.
+func listRules(config *checks.Config) ([]rule, error) {
+	defaults, err := checks.LoadConfig("")
+	if err != nil {
+		return nil, err
+	}
+	builtin := make(map[string]bool)
+	var rules []rule
+	for _, check := range defaults.Checks {
+		builtin[check.ID] = true
+		r := rule{ID: check.ID, Severity: check.Severity, Category: check.Category, Source: "built-in", Description: check.Description, URL: check.URL}
+		for _, configured := range config.Checks {
+			if configured.ID == check.ID {
+				r.Severity = configured.Severity
+				r.Category = configured.Category
+				r.URL = configured.URL
+				r.Enabled = configured.Enabled == nil || *configured.Enabled
+			}
+		}
+		rules = append(rules, r)
+	}
+	for _, check := range config.Checks {
+		if builtin[check.ID] {
+			continue
+		}
+		rules = append(rules, rule{
+			ID:          check.ID,
+			Severity:    check.Severity,
+			Category:    check.Category,
+			Enabled:     config.Check(check.ID) != nil,
+			Source:      "config",
+			Description: check.Description,
+			URL:         check.URL,
+		})
+	}
+	for _, plugin := range config.Plugins {
+		rules = append(rules, rule{
+			ID:          plugin.Name,
+			Severity:    plugin.Severity,
+			Enabled:     plugin.Enabled == nil || *plugin.Enabled,
+			Source:      "plugin",
+			Description: "Findings of the plugin " + plugin.Name,
+		})
+	}
+	return rules, nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"ghactionscheck/pkg/report"
+)
+
+// writeStepSummary appends the results as a markdown report to the job
+// summary when running in GitHub Actions, so they are shown on the summary
+// page of the run. It is left out with --no-step-summary, or when the report
+// itself is written there. Failing to write it only prints a warning, as the
+// findings are reported anyway.
+func (flags *checkFlags) writeStepSummary(files []string, results []report.Result) {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" || flags.NoStepSummary || flags.Output != "" && absPath(flags.Output) == absPath(path) {
+		return
+	}
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err == nil {
+		err = report.WriteMarkdown(out, files, results)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not write the step summary: %v\n", err)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"os"
+	"strings"
+
+	"ghactionscheck/pkg/checks"
+	"ghactionscheck/pkg/report"
+)
+
+// uploadSARIF uploads the results of checking files as a SARIF log to the
+// code scanning API, for the repository and commit of the workflow run in
+// GitHub Actions, or of the clone of the first file otherwise. The paths of
+// the files are made relative to the root of their repository, as code
+// scanning expects.
+func uploadSARIF(files []string, results []report.Result) error {
+	if cli.GitHubToken == "" {
+		return fmt.Errorf("uploading needs a token with --github-token or $GITHUB_TOKEN")
+	}
+	root := checks.FindRepoRoot(files[0])
+	repo := cmp.Or(os.Getenv("GITHUB_REPOSITORY"), checks.OriginRepository(root, githubHost()))
+	if repo == "" {
+		return fmt.Errorf("could not find the GitHub repository of %s", root)
+	}
+	commit := os.Getenv("GITHUB_SHA")
+	ref := os.Getenv("GITHUB_REF")
+	if commit == "" || ref == "" {
+		head, err := git(root, "rev-parse", "HEAD")
+		if err != nil {
+			return err
+		}
+		branch, err := git(root, "symbolic-ref", "HEAD")
+		if err != nil {
+			return fmt.Errorf("could not find the branch to upload for, as HEAD is detached")
+		}
+		commit, ref = strings.TrimSpace(head), strings.TrimSpace(branch)
+	}
+
+	relative := make([]report.Result, len(results))
+	for i, result := range results {
+		relative[i] = result
+		relative[i].File = repositoryPath(result.File)
+	}
+	var sarif bytes.Buffer
+	if err := report.WriteSARIF(&sarif, relative); err != nil {
+		return err
+	}
+	id, err := githubClient().UploadSARIF(repo, commit, ref, sarif.Bytes())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Uploaded %d findings to code scanning for %s at %s (upload %s)\n", len(results), repo, commit, id)
+	return nil
+}
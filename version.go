@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"ghactionscheck/pkg/checks"
+)
+
+// The build metadata, set by the release build with
+//
+//	-ldflags "-X main.version=... -X main.commit=... -X main.date=..."
+//
+// When they aren't set, the commit and date are read from the VCS stamp of
+// the build.
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+type versionCmd struct{}
+
+// Run prints the version of ghactionscheck and its build metadata, as asked
+// for in bug reports.
+func (cmd *versionCmd) Run() error {
+	fmt.Println(versionInfo())
+	return nil
+}
+
+// versionInfo describes the build: its version, commit, date and the rule
+// set of the built-in checks.
+func versionInfo() string {
+	commit, date := commit, date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && commit == "":
+				commit = setting.Value
+			case setting.Key == "vcs.time" && date == "":
+				date = setting.Value
+			}
+		}
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+
+	rules := "unknown"
+	if config, err := checks.LoadConfig(""); err == nil {
+		rules = fmt.Sprintf("%d checks", len(config.Checks))
+	}
+	return fmt.Sprintf("ghactionscheck %s\ncommit: %s\nbuilt: %s\nrule set: %s (%s)\ngo: %s %s/%s",
+		version, commit, date, checks.RuleSetVersion(), rules, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
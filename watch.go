@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"ghactionscheck/pkg/checks"
+	"ghactionscheck/pkg/report"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDelay is how long to wait for more changes before checking again, as
+// editors often write a file in several steps.
+const watchDelay = 200 * time.Millisecond
+
+// watch checks the workflow files again as they are changed, added or
+// removed, printing the results of the changed files only. A change to the
+// checks config checks all of them again. It runs until interrupted.
+func (cmd *checkCmd) watch(files []string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("watching files: %v", err)
+	}
+	defer watcher.Close()
+
+	var dirs []string
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if slices.Contains(dirs, dir) {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("watching %s: %v", dir, err)
+		}
+		dirs = append(dirs, dir)
+	}
+	configPaths := make(map[string]bool)
+	for _, path := range cmd.configPaths(files[0]) {
+		configPaths[absPath(path)] = true
+		if _, err := os.Stat(filepath.Dir(path)); err != nil {
+			continue
+		}
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("watching %s: %v", path, err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Watching %s for changes...\n", strings.Join(dirs, ", "))
+
+	changed := make(map[string]bool)
+	configChanged := false
+	timer := time.NewTimer(watchDelay)
+	timer.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			changed[absPath(event.Name)] = true
+			configChanged = configChanged || configPaths[absPath(event.Name)]
+			timer.Reset(watchDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "Warning: watching files: %v\n", err)
+		case <-timer.C:
+			var err error
+			files, err = cmd.checkChanged(files, changed, configChanged)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+			changed = make(map[string]bool)
+			configChanged = false
+		}
+	}
+}
+
+// configPaths returns the paths of the checks configs that can apply to
+// file: the one given by --config, or the discovered one and those in the
+// repository that would take precedence once created.
+func (cmd *checkCmd) configPaths(file string) []string {
+	if cli.Config != "" {
+		return []string{cli.Config}
+	}
+	root := checks.FindRepoRoot(file)
+	paths := []string{
+		filepath.Join(root, checks.ConfigFileName),
+		filepath.Join(root, ".github", checks.ConfigFileName),
+	}
+	if path := checks.FindConfigFile(file); path != "" {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// checkChanged checks the workflow files among changed, or all of them when
+// the config changed, and reports the ones that were removed from watched.
+// It returns the workflow files found now.
+func (cmd *checkCmd) checkChanged(watched []string, changed map[string]bool, configChanged bool) ([]string, error) {
+	// The files are looked up again to find the added ones. Finding none is
+	// reported after the removed files.
+	files, findErr := expandPaths(cmd.Paths)
+	if findErr != nil {
+		findErr = fmt.Errorf("finding workflow files: %v", findErr)
+	}
+
+	current := make(map[string]bool)
+	var checked []string
+	for _, file := range files {
+		current[absPath(file)] = true
+		if configChanged || changed[absPath(file)] {
+			checked = append(checked, file)
+		}
+	}
+	var removed []string
+	for _, file := range watched {
+		if changed[absPath(file)] && !current[absPath(file)] {
+			removed = append(removed, file)
+		}
+	}
+	if len(checked) == 0 && len(removed) == 0 {
+		return files, findErr
+	}
+
+	fmt.Printf("\n[%s] ", time.Now().Format("15:04:05"))
+	if configChanged {
+		fmt.Println("Checks config changed")
+	} else {
+		fmt.Println("Workflow files changed")
+	}
+	sort.Strings(removed)
+	for _, file := range removed {
+		fmt.Printf("Removed %s\n", file)
+	}
+	if len(checked) == 0 {
+		return files, findErr
+	}
+
+	results, err := cmd.check(checked)
+	if err != nil {
+		return files, err
+	}
+	return files, report.Write(os.Stdout, cmd.Format, checked, results, cmd.reportOptions(checked))
+}
+
+// absPath returns the absolute form of path, or path itself if it can't be
+// determined, so paths from different sources can be compared.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}